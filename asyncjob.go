@@ -0,0 +1,155 @@
+package rchttp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrNoJobLocation is returned by DoAsyncJob for a 202 Accepted response without a Location.
+	ErrNoJobLocation = errors.New("rchttp: 202 Accepted response has no Location to poll")
+	// ErrAsyncJobTimeout is returned by DoAsyncJob when the job has not completed within the
+	// Timeout of its PollConfig.
+	ErrAsyncJobTimeout = errors.New("rchttp: async job did not complete in time")
+)
+
+// PollConfig configures how DoAsyncJob polls a job's status.
+type PollConfig struct {
+	// Interval is the delay before the first poll (1s if zero), doubling up to MaxInterval
+	// (30s if zero). A Retry-After header on a pending response takes precedence.
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// Timeout, if set, limits how long the job is polled for, in addition to any deadline
+	// of the context.
+	Timeout time.Duration
+	// Done, if set, reports whether a poll response is in a terminal state, e.g. from a
+	// status field in its body. By default any response other than 202 Accepted is terminal.
+	// Done must not close the response body.
+	Done func(resp *http.Response) (bool, error)
+}
+
+// DoAsyncJob submits req and, if it is accepted with 202 Accepted, polls the status URL in
+// its Location header until the job reaches a terminal state, returning that final
+// response (which the caller must close). Redirects from the status URL to the finished
+// resource (e.g. 303 See Other) are followed. Any other response to req is returned as is.
+func (c *Client) DoAsyncJob(ctx context.Context, req *http.Request, pollCfg PollConfig) (*http.Response, error) {
+	parent := ctx
+	if pollCfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pollCfg.Timeout)
+		defer cancel()
+	}
+	// jobError returns ErrAsyncJobTimeout if err is due to the job's own Timeout expiring.
+	jobError := func(err error) error {
+		if parent.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+			return ErrAsyncJobTimeout
+		}
+		return err
+	}
+	done := pollCfg.Done
+	if done == nil {
+		done = func(resp *http.Response) (bool, error) {
+			return resp.StatusCode != http.StatusAccepted, nil
+		}
+	}
+
+	// polls carry the caller's headers, not those Do adds to req (e.g. its correlation ID)
+	header := cloneHeader(req.Header)
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return resp, nil
+	}
+
+	location := req.URL
+	interval := pollCfg.interval()
+	for {
+		next, err := jobLocation(location, resp)
+		if err != nil {
+			drain(resp)
+			return nil, err
+		}
+		location = next
+
+		delay := interval
+		if after, ok := retryAfter(resp.Header); ok {
+			delay = after
+		} else if interval *= 2; interval > pollCfg.maxInterval() {
+			interval = pollCfg.maxInterval()
+		}
+		drain(resp)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, jobError(ctx.Err())
+		}
+
+		pollReq, err := http.NewRequest("GET", location.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			switch http.CanonicalHeaderKey(key) {
+			case "Content-Type", "Content-Length", "Content-Encoding":
+				continue
+			}
+			pollReq.Header[key] = append([]string(nil), values...)
+		}
+
+		resp, err = c.Do(ctx, pollReq)
+		if err != nil {
+			return nil, jobError(err)
+		}
+		finished, err := done(resp)
+		if err != nil {
+			drain(resp)
+			return nil, err
+		}
+		if finished {
+			return resp, nil
+		}
+	}
+}
+
+func (cfg PollConfig) interval() time.Duration {
+	if cfg.Interval > 0 {
+		return cfg.Interval
+	}
+	return time.Second
+}
+
+func (cfg PollConfig) maxInterval() time.Duration {
+	if cfg.MaxInterval > 0 {
+		return cfg.MaxInterval
+	}
+	return 30 * time.Second
+}
+
+// jobLocation returns the status URL to poll after resp, resolved against the URL it was
+// given by, keeping the current one if resp does not give a new Location.
+func jobLocation(current *url.URL, resp *http.Response) (*url.URL, error) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		if resp.Request != nil && resp.Request.Method == "GET" {
+			return current, nil
+		}
+		return nil, ErrNoJobLocation
+	}
+	return current.Parse(location)
+}
+
+func drain(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
@@ -0,0 +1,154 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientDoAsyncJob(t *testing.T) {
+	var polls int32
+	var pollHeaders atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jobs":
+			w.Header().Set("Location", "/jobs/1")
+			w.WriteHeader(http.StatusAccepted)
+		case "/jobs/1":
+			pollHeaders.Store(r.Header.Clone())
+			if atomic.AddInt32(&polls, 1) < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			http.Redirect(w, r, "/results/1", http.StatusSeeOther)
+		case "/results/1":
+			w.Write([]byte("done"))
+		case "/stuck":
+			w.Header().Set("Location", "/stuck")
+			w.WriteHeader(http.StatusAccepted)
+		case "/lost":
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client", t, func() {
+		atomic.StoreInt32(&polls, 0)
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		pollCfg := PollConfig{Interval: 10 * time.Millisecond}
+
+		Convey("When a job is accepted and completes after some polls", func() {
+			req, _ := http.NewRequest("POST", ts.URL+"/jobs", strings.NewReader("{}"))
+			req.Header.Set("Collection-Id", "c1")
+			resp, err := httpClient.DoAsyncJob(context.Background(), req, pollCfg)
+
+			Convey("Then each poll carries the caller's headers and one correlation ID", func() {
+				header := pollHeaders.Load().(http.Header)
+				So(header.Get("Collection-Id"), ShouldEqual, "c1")
+				So(header[common.RequestHeaderKey], ShouldHaveLength, 1)
+				So(req.Header[common.RequestHeaderKey], ShouldHaveLength, 1)
+			})
+
+			Convey("Then the finished resource is returned", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				So(string(body), ShouldEqual, "done")
+				So(atomic.LoadInt32(&polls), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When the request completes synchronously", func() {
+			req, _ := http.NewRequest("POST", ts.URL+"/sync", nil)
+			resp, err := httpClient.DoAsyncJob(context.Background(), req, pollCfg)
+
+			Convey("Then its response is returned without polling", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When a job does not complete within the timeout", func() {
+			pollCfg.Timeout = 50 * time.Millisecond
+			req, _ := http.NewRequest("POST", ts.URL+"/stuck", nil)
+			_, err := httpClient.DoAsyncJob(context.Background(), req, pollCfg)
+
+			Convey("Then ErrAsyncJobTimeout is returned", func() {
+				So(err, ShouldEqual, ErrAsyncJobTimeout)
+			})
+		})
+
+		Convey("When a job is accepted without a Location", func() {
+			req, _ := http.NewRequest("POST", ts.URL+"/lost", nil)
+			_, err := httpClient.DoAsyncJob(context.Background(), req, pollCfg)
+
+			Convey("Then ErrNoJobLocation is returned", func() {
+				So(err, ShouldEqual, ErrNoJobLocation)
+			})
+		})
+
+		Convey("When a Done function decides the terminal state", func() {
+			pollCfg.Done = func(resp *http.Response) (bool, error) {
+				return atomic.LoadInt32(&polls) >= 2, nil
+			}
+			req, _ := http.NewRequest("POST", ts.URL+"/jobs", nil)
+			resp, err := httpClient.DoAsyncJob(context.Background(), req, pollCfg)
+
+			Convey("Then polling stops as soon as it reports done", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusAccepted)
+				resp.Body.Close()
+				So(atomic.LoadInt32(&polls), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestClientDoAsyncJobWithJobServer(t *testing.T) {
+	Convey("Given a job server whose jobs complete after 3 pending polls", t, func() {
+		js := rchttptest.NewJobServer(3, `{"state":"completed"}`)
+		js.RetryAfter = "0"
+		defer js.Close()
+		httpClient := &Client{HTTPClient: &http.Client{}}
+
+		Convey("When a job is submitted with DoAsyncJob", func() {
+			req, _ := http.NewRequest("POST", js.URL+"/exports", strings.NewReader("{}"))
+			resp, err := httpClient.DoAsyncJob(context.Background(), req, PollConfig{Interval: time.Millisecond})
+
+			Convey("Then the job's result is returned after polling its status", func() {
+				So(err, ShouldBeNil)
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				So(string(body), ShouldEqual, `{"state":"completed"}`)
+				So(js.GetJobs(), ShouldEqual, 1)
+				So(js.GetPolls(1), ShouldEqual, 4)
+			})
+		})
+
+		Convey("When the job's result is a failure", func() {
+			js.ResultStatus = http.StatusUnprocessableEntity
+			req, _ := http.NewRequest("POST", js.URL+"/exports", nil)
+			resp, err := httpClient.DoAsyncJob(context.Background(), req, PollConfig{Interval: time.Millisecond})
+
+			Convey("Then the failed result is returned for the caller to handle", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusUnprocessableEntity)
+				resp.Body.Close()
+			})
+		})
+	})
+}
@@ -0,0 +1,169 @@
+package rchttp
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/go-ns/common"
+	"golang.org/x/net/context"
+)
+
+const (
+	basicAuthKey   = contextKey("rchttp-basic-auth")
+	tokenSourceKey = contextKey("rchttp-token-source")
+)
+
+// tokenExpiryDelta is how long before its expiry a cached token is considered expired,
+// to allow for clock skew and request latency.
+const tokenExpiryDelta = 10 * time.Second
+
+// BasicAuth holds credentials for HTTP basic authentication.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Token is a bearer token, e.g. from an OAuth2 client-credentials grant.
+type Token struct {
+	AccessToken string
+	// TokenType defaults to "Bearer" if empty.
+	TokenType string
+	// Expiry is the zero time if the token does not expire.
+	Expiry time.Time
+}
+
+// Valid reports whether t is non-nil, non-empty and not about to expire.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry)
+}
+
+// TokenSource supplies bearer tokens. It mirrors oauth2.TokenSource, which can be adapted
+// by converting the returned *oauth2.Token.
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+// CachingTokenSource is a TokenSource which reuses a token until it expires, or is
+// invalidated after being rejected, before asking the underlying source for a new one.
+type CachingTokenSource struct {
+	src   TokenSource
+	mutex sync.Mutex
+	token *Token
+}
+
+// ReuseTokenSource returns a CachingTokenSource wrapping src.
+func ReuseTokenSource(src TokenSource) *CachingTokenSource {
+	return &CachingTokenSource{src: src}
+}
+
+// Token returns the cached token if still valid, or a new token from the underlying source.
+func (ts *CachingTokenSource) Token() (*Token, error) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	if ts.token.Valid() {
+		return ts.token, nil
+	}
+	token, err := ts.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	ts.token = token
+	return token, nil
+}
+
+// Invalidate discards the cached token, so the next call to Token gets a new one.
+func (ts *CachingTokenSource) Invalidate() {
+	ts.mutex.Lock()
+	ts.token = nil
+	ts.mutex.Unlock()
+}
+
+// WithBasicAuth returns a context which makes requests made with it use basic
+// authentication with the given credentials, in preference to the client's BasicAuth.
+func WithBasicAuth(ctx context.Context, username, password string) context.Context {
+	return context.WithValue(ctx, basicAuthKey, &BasicAuth{Username: username, Password: password})
+}
+
+// WithTokenSource returns a context which makes requests made with it use bearer tokens
+// from src, in preference to the client's TokenSource.
+func WithTokenSource(ctx context.Context, src TokenSource) context.Context {
+	return context.WithValue(ctx, tokenSourceKey, src)
+}
+
+// basicAuth returns the basic auth credentials for a request made with ctx, if any.
+func (c *Client) basicAuth(ctx context.Context) *BasicAuth {
+	if auth, ok := ctx.Value(basicAuthKey).(*BasicAuth); ok {
+		return auth
+	}
+	return c.BasicAuth
+}
+
+// tokenSource returns the token source for a request made with ctx, if any.
+func (c *Client) tokenSource(ctx context.Context) TokenSource {
+	if src, ok := ctx.Value(tokenSourceKey).(TokenSource); ok {
+		return src
+	}
+	return c.TokenSource
+}
+
+// applyAuth sets the Authorization header of req from the token source or basic auth
+// credentials for ctx, preferring those set on the context.
+func (c *Client) applyAuth(ctx context.Context, req *http.Request) error {
+	_, ctxHasBasicAuth := ctx.Value(basicAuthKey).(*BasicAuth)
+	if src := c.tokenSource(ctx); src != nil && !ctxHasBasicAuth {
+		token, err := src.Token()
+		if err != nil {
+			return err
+		}
+		tokenType := token.TokenType
+		if tokenType == "" {
+			tokenType = "Bearer"
+		}
+		req.Header.Set("Authorization", tokenType+" "+token.AccessToken)
+		return nil
+	}
+	if auth := c.basicAuth(ctx); auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	return nil
+}
+
+// hasExplicitAuth reports whether req was made with credentials of its own.
+func hasExplicitAuth(req *http.Request) bool {
+	return req.Header.Get(common.AuthHeaderKey) != "" || req.Header.Get(common.FlorenceHeaderKey) != ""
+}
+
+// SetAuthRefresher sets a function to be called once when a request is rejected with a
+// 401, e.g. to rotate credentials, after which the request is tried again. If it returns
+// an error, the request fails with that error. Requests made with explicit credentials (an
+// Authorization or X-Florence-Token header), which it cannot change, are not refreshed.
+func (c *Client) SetAuthRefresher(refresher func(ctx context.Context) error) {
+	c.AuthRefresher = refresher
+}
+
+// refreshAuth is called when a request is rejected with a 401, and reports whether the
+// request should be tried again with refreshed credentials, which is never the case for
+// requests with explicit credentials as they would be sent again unchanged.
+func (c *Client) refreshAuth(ctx context.Context, explicitAuth bool) (bool, error) {
+	if explicitAuth {
+		return false, nil
+	}
+	retry := false
+	if src := c.tokenSource(ctx); src != nil {
+		if invalidator, ok := src.(interface{ Invalidate() }); ok {
+			invalidator.Invalidate()
+		}
+		retry = true
+	}
+	if c.AuthRefresher != nil {
+		if err := c.AuthRefresher(ctx); err != nil {
+			return false, err
+		}
+		retry = true
+	}
+	return retry, nil
+}
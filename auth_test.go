@@ -0,0 +1,199 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientBasicAuth(t *testing.T) {
+	var seen []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		seen = append(seen, user+":"+pass)
+		if len(seen) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with basic auth credentials", t, func() {
+		seen = nil
+		httpClient := &Client{
+			HTTPClient: &http.Client{Timeout: 5 * time.Second},
+			MaxRetries: 1,
+			RetryTime:  time.Millisecond,
+			BasicAuth:  &BasicAuth{Username: "client", Password: "secret"},
+		}
+
+		Convey("When Get() is called and the first attempt fails", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+			Convey("Then every attempt carries the client credentials", func() {
+				So(seen, ShouldResemble, []string{"client:secret", "client:secret"})
+			})
+		})
+
+		Convey("When Get() is called with credentials in the context", func() {
+			_, err := httpClient.Get(WithBasicAuth(context.Background(), "request", "pass"), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the context credentials are used", func() {
+				So(seen, ShouldResemble, []string{"request:pass", "request:pass"})
+			})
+		})
+
+		Convey("When Do() is called with an explicit Authorization header", func() {
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			req.SetBasicAuth("explicit", "auth")
+			_, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+
+			Convey("Then the explicit header is kept", func() {
+				So(seen, ShouldResemble, []string{"explicit:auth", "explicit:auth"})
+			})
+		})
+	})
+}
+
+type countingTokenSource struct {
+	calls  int
+	expiry time.Time
+}
+
+func (ts *countingTokenSource) Token() (*Token, error) {
+	ts.calls++
+	return &Token{AccessToken: "tok" + strconv.Itoa(ts.calls), Expiry: ts.expiry}, nil
+}
+
+func TestClientTokenSource(t *testing.T) {
+	var seen []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer tok2" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with no retries and a caching token source", t, func() {
+		seen = nil
+		src := &countingTokenSource{expiry: time.Now().Add(time.Hour)}
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}, TokenSource: ReuseTokenSource(src)}
+
+		Convey("When Get() is called and the first token is rejected", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the token is refreshed and the request tried once more", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(seen, ShouldResemble, []string{"Bearer tok1", "Bearer tok2"})
+				So(src.calls, ShouldEqual, 2)
+			})
+
+			Convey("And the refreshed token is reused on the next request", func() {
+				_, err := httpClient.Get(context.Background(), ts.URL)
+				So(err, ShouldBeNil)
+				So(seen, ShouldResemble, []string{"Bearer tok1", "Bearer tok2", "Bearer tok2"})
+				So(src.calls, ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with a token source in the context which is always rejected", t, func() {
+		seen = nil
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}}
+		ctx := WithTokenSource(context.Background(), &countingTokenSource{calls: 2})
+
+		Convey("When Get() is called", func() {
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the request is only tried again once", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusUnauthorized)
+				So(seen, ShouldResemble, []string{"Bearer tok3", "Bearer tok4"})
+			})
+		})
+	})
+}
+
+func TestTokenValid(t *testing.T) {
+	Convey("Tokens are valid until shortly before they expire", t, func() {
+		var nilToken *Token
+		So(nilToken.Valid(), ShouldBeFalse)
+		So((&Token{}).Valid(), ShouldBeFalse)
+		So((&Token{AccessToken: "a"}).Valid(), ShouldBeTrue)
+		So((&Token{AccessToken: "a", Expiry: time.Now().Add(time.Minute)}).Valid(), ShouldBeTrue)
+		So((&Token{AccessToken: "a", Expiry: time.Now().Add(time.Second)}).Valid(), ShouldBeFalse)
+	})
+}
+
+func TestClientAuthRefresher(t *testing.T) {
+	var seen []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("X-Florence-Token"))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with an auth refresher", t, func() {
+		seen = nil
+		refreshes := 0
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}}
+		httpClient.SetAuthRefresher(func(ctx context.Context) error {
+			refreshes++
+			return nil
+		})
+
+		Convey("When a request is always rejected with a 401", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the refresher is called once and the request tried again once", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusUnauthorized)
+				So(refreshes, ShouldEqual, 1)
+				So(seen, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When a request with explicit credentials is rejected with a 401", func() {
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			req.Header.Set("X-Florence-Token", "stale")
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is not tried again with the same credentials", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusUnauthorized)
+				So(refreshes, ShouldEqual, 0)
+				So(seen, ShouldResemble, []string{"stale"})
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with an auth refresher which fails", t, func() {
+		seen = nil
+		refreshErr := errors.New("refresh failed")
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}}
+		httpClient.SetAuthRefresher(func(ctx context.Context) error {
+			return refreshErr
+		})
+
+		Convey("When a request is rejected with a 401", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the refresher error is returned without trying again", func() {
+				So(resp, ShouldBeNil)
+				So(err, ShouldEqual, refreshErr)
+				So(seen, ShouldHaveLength, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,110 @@
+package rchttp
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// BaggageHeaderKey is the W3C Baggage header.
+const BaggageHeaderKey = "Baggage"
+
+// Limits on the baggage header from the W3C Baggage specification.
+const (
+	DefaultMaxBaggageSize = 8192
+	maxBaggageMembers     = 64
+)
+
+const baggageKey = contextKey("rchttp-baggage")
+
+// WithBaggage returns a context carrying the members of a W3C baggage header (e.g.
+// "tenant=ons,experiment=b") to be forwarded on outbound requests made with it. Only
+// members named in the client's PropagatedBaggage are sent.
+func WithBaggage(ctx context.Context, baggage string) context.Context {
+	members := append([]string(nil), baggageFromContext(ctx)...)
+	for _, member := range strings.Split(baggage, ",") {
+		if member = strings.TrimSpace(member); member != "" {
+			members = append(members, member)
+		}
+	}
+	return context.WithValue(ctx, baggageKey, members)
+}
+
+// WithBaggageFromRequest returns a context carrying the baggage of an inbound request, to
+// be forwarded on outbound requests made with it (subject to the client's PropagatedBaggage).
+func WithBaggageFromRequest(ctx context.Context, r *http.Request) context.Context {
+	return WithBaggage(ctx, strings.Join(r.Header[BaggageHeaderKey], ","))
+}
+
+func baggageFromContext(ctx context.Context) []string {
+	members, _ := ctx.Value(baggageKey).([]string)
+	return members
+}
+
+// baggageMemberKey returns the key of a baggage member "key=value;properties".
+func baggageMemberKey(member string) string {
+	if i := strings.IndexAny(member, "=;"); i >= 0 {
+		member = member[:i]
+	}
+	return strings.TrimSpace(member)
+}
+
+// addBaggage adds the allowlisted baggage members in the context to the baggage header of
+// req, after any members already set on the request (which take precedence), dropping
+// members which would take the header over the client's MaxBaggageSize.
+func (c *Client) addBaggage(ctx context.Context, req *http.Request) {
+	if len(c.PropagatedBaggage) == 0 {
+		return
+	}
+	maxSize := c.MaxBaggageSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBaggageSize
+	}
+
+	var members []string
+	seen := make(map[string]bool)
+	size := 0
+	add := func(member string) {
+		key := baggageMemberKey(member)
+		if seen[key] || len(members) == maxBaggageMembers {
+			return
+		}
+		added := len(member)
+		if len(members) > 0 {
+			added++
+		}
+		if size+added > maxSize {
+			return
+		}
+		seen[key] = true
+		members = append(members, member)
+		size += added
+	}
+
+	for _, value := range req.Header[BaggageHeaderKey] {
+		for _, member := range strings.Split(value, ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				add(member)
+			}
+		}
+	}
+	existing := len(members)
+	for _, member := range baggageFromContext(ctx) {
+		if c.isPropagatedBaggage(baggageMemberKey(member)) {
+			add(member)
+		}
+	}
+	if len(members) > existing {
+		req.Header.Set(BaggageHeaderKey, strings.Join(members, ","))
+	}
+}
+
+func (c *Client) isPropagatedBaggage(key string) bool {
+	for _, allowed := range c.PropagatedBaggage {
+		if key == allowed {
+			return true
+		}
+	}
+	return false
+}
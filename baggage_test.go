@@ -0,0 +1,82 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientPropagatesBaggage(t *testing.T) {
+	ts := rchttptest.NewTestServer(200)
+	defer ts.Close()
+
+	Convey("Given an rchttp client which propagates the tenant and experiment baggage", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, PropagatedBaggage: []string{"tenant", "experiment"}}
+
+		inbound, err := http.NewRequest("GET", "http://localhost", nil)
+		So(err, ShouldBeNil)
+		inbound.Header.Set("Baggage", "tenant=ons;origin=web, user=private,experiment=b")
+		ctx := WithBaggageFromRequest(context.Background(), inbound)
+
+		Convey("When Get() is called with baggage in the context", func() {
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then only the allowlisted members are sent, with their properties", func() {
+				So(call.Headers["Baggage"], ShouldResemble, []string{"tenant=ons;origin=web,experiment=b"})
+			})
+		})
+
+		Convey("When the request already has baggage for a key", func() {
+			req, err := http.NewRequest("GET", ts.URL, nil)
+			So(err, ShouldBeNil)
+			req.Header.Set("Baggage", "tenant=explicit")
+
+			resp, err := httpClient.Do(ctx, req)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the explicit member is not overridden", func() {
+				So(call.Headers["Baggage"], ShouldResemble, []string{"tenant=explicit,experiment=b"})
+			})
+		})
+
+		Convey("When the baggage would exceed the size limit", func() {
+			httpClient.MaxBaggageSize = 20
+			resp, err := httpClient.Get(WithBaggage(context.Background(), "tenant=ons,experiment="+strings.Repeat("x", 20)), ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then members which do not fit are dropped", func() {
+				So(call.Headers["Baggage"], ShouldResemble, []string{"tenant=ons"})
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with no propagated baggage", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("When Get() is called with baggage in the context", func() {
+			resp, err := httpClient.Get(WithBaggage(context.Background(), "tenant=ons"), ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then no baggage is sent", func() {
+				So(call.Headers["Baggage"], ShouldBeNil)
+			})
+		})
+	})
+}
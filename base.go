@@ -0,0 +1,12 @@
+package rchttp
+
+// BaseClienter is the method set expected of an HTTP client by the health-checked clients
+// in dp-api-clients-go. It is Clienter, so *Client and ClienterMock can be passed to them
+// directly rather than maintaining a separate implementation there.
+type BaseClienter = Clienter
+
+// NewBaseClient returns a new client, as NewClient, as a BaseClienter for use with the
+// health-checked clients in dp-api-clients-go.
+func NewBaseClient() BaseClienter {
+	return NewClient()
+}
@@ -0,0 +1,156 @@
+package rchttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// DefaultMaxBufferedBodySize is the most bytes of a request body buffered so that it can
+// be retried, when the client's MaxBufferedBodySize is zero.
+const DefaultMaxBufferedBodySize = 1 << 20
+
+// DefaultRetryDrainLimit is the most bytes of a response body read before it is closed
+// for a retry, when the client's RetryDrainLimit is zero.
+const DefaultRetryDrainLimit = 64 << 10
+
+// NonReplayableBodyError is returned by Do when a request should be retried but its
+// body cannot be re-sent, e.g. a streamed body of unknown length. Err holds the error
+// (if any) from the attempt that was made.
+type NonReplayableBodyError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *NonReplayableBodyError) Error() string {
+	msg := fmt.Sprintf("rchttp: not retrying %s %s: request body cannot be replayed", e.Method, e.URL)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the error from the attempt that was made.
+func (e *NonReplayableBodyError) Unwrap() error {
+	return e.Err
+}
+
+// BodyTooLargeError is returned by Do when a request should be retried but its body could
+// not be replayed, and was larger than the client's MaxBufferedBodySize so was not buffered
+// to make it so. Err holds the error (if any) from the attempt that was made.
+type BodyTooLargeError struct {
+	Method string
+	URL    string
+	Limit  int64
+	Err    error
+}
+
+func (e *BodyTooLargeError) Error() string {
+	msg := fmt.Sprintf("rchttp: not retrying %s %s: request body is larger than the %d byte buffer limit", e.Method, e.URL, e.Limit)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the error from the attempt that was made.
+func (e *BodyTooLargeError) Unwrap() error {
+	return e.Err
+}
+
+// isReplayable reports whether the body of req can be re-sent on a retry.
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// prepareBody makes the body of req replayable by buffering it in memory, if it may be
+// retried and is no larger than the client's MaxBufferedBodySize (or whatever its size if
+// BufferRequestBodies is set), and reports whether the body can be re-sent on a retry.
+// A body which is too large is sent as it is, and a *BodyTooLargeError returned from send
+// if it needs to be retried.
+func (c *Client) prepareBody(req *http.Request, retry bool) (bool, error) {
+	if isReplayable(req) {
+		return true, nil
+	}
+	if c.BufferRequestBodies {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return false, err
+		}
+		setBufferedBody(req, b)
+		return true, nil
+	}
+	limit := c.maxBufferedBodySize()
+	if !retry || limit < 0 {
+		return false, nil
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		req.Body.Close()
+		return false, err
+	}
+	if int64(len(b)) > limit {
+		// send what has been read followed by the rest of the body, without retrying it
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), req.Body), req.Body}
+		return false, nil
+	}
+	req.Body.Close()
+	setBufferedBody(req, b)
+	return true, nil
+}
+
+func (c *Client) maxBufferedBodySize() int64 {
+	if c.MaxBufferedBodySize == 0 {
+		return DefaultMaxBufferedBodySize
+	}
+	return c.MaxBufferedBodySize
+}
+
+// setBufferedBody replaces the body of req with b.
+func setBufferedBody(req *http.Request, b []byte) {
+	req.ContentLength = int64(len(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+	req.Body, _ = req.GetBody()
+}
+
+// DefaultErrorBodyLimit is the most bytes of the body of a response returned by Do with an
+// error which are kept for the caller to read.
+const DefaultErrorBodyLimit = 64 << 10
+
+// closeErrorBody replaces the body of a response being returned with an error with up to
+// DefaultErrorBodyLimit bytes of it held in memory, and closes the original.
+func closeErrorBody(resp *http.Response) {
+	if resp.Body == nil {
+		return
+	}
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, DefaultErrorBodyLimit))
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+}
+
+// drainForRetry reads and closes the body of a response which is being retried, so that
+// its connection can be reused, unless the body is larger than the client's RetryDrainLimit
+// (when the connection is closed instead).
+func (c *Client) drainForRetry(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	limit := c.RetryDrainLimit
+	if limit == 0 {
+		limit = DefaultRetryDrainLimit
+	}
+	if limit > 0 {
+		io.CopyN(ioutil.Discard, resp.Body, limit)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,214 @@
+package rchttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// streamReader hides the concrete reader type so that http.NewRequest cannot make the body replayable.
+type streamReader struct {
+	io.Reader
+}
+
+func TestClientNonReplayableBody(t *testing.T) {
+	Convey("Given a server which always fails and a request with a streamed body", t, func() {
+		ts := rchttptest.NewTestServer(500)
+		defer ts.Close()
+
+		req, err := http.NewRequest("POST", ts.URL, streamReader{strings.NewReader(`{"a":"b"}`)})
+		So(err, ShouldBeNil)
+		So(isReplayable(req), ShouldBeFalse)
+
+		Convey("When the client does not buffer request bodies", func() {
+			httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}, MaxRetries: 2, RetryTime: time.Millisecond, NonIdempotentRetries: true, MaxBufferedBodySize: -1}
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then the request is not retried and a NonReplayableBodyError is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &NonReplayableBodyError{})
+				So(err.Error(), ShouldContainSubstring, "request body cannot be replayed")
+				So(resp, ShouldNotBeNil)
+				So(resp.StatusCode, ShouldEqual, 500)
+				resp.Body.Close()
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the client buffers request bodies", func() {
+			httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}, MaxRetries: 2, RetryTime: time.Millisecond, BufferRequestBodies: true, NonIdempotentRetries: true}
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then every attempt sends the full body", func() {
+				So(ts.GetCalls(0), ShouldEqual, 3)
+				So(call.Body, ShouldEqual, `{"a":"b"}`)
+				So(ts.CheckReplays(), ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestClientBuffersBodiesUpToLimit(t *testing.T) {
+	Convey("Given a server which always fails and an rchttp client with a buffer limit", t, func() {
+		ts := rchttptest.NewTestServer(500)
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}, MaxRetries: 2, RetryTime: time.Millisecond, MaxBufferedBodySize: 16}
+
+		Convey("When a streamed body within the limit is sent", func() {
+			req, _ := http.NewRequest("PUT", ts.URL, streamReader{strings.NewReader(`{"a":"b"}`)})
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is buffered and every attempt sends the full body", func() {
+				So(ts.GetCalls(0), ShouldEqual, 3)
+				So(call.Body, ShouldEqual, `{"a":"b"}`)
+				So(ts.CheckReplays(), ShouldBeNil)
+			})
+		})
+
+		Convey("When a streamed body over the limit is sent", func() {
+			body := `{"dimension":"aggregate"}`
+			req, _ := http.NewRequest("PUT", ts.URL, streamReader{strings.NewReader(body)})
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then the full body is sent once and a BodyTooLargeError is returned", func() {
+				var tooLarge *BodyTooLargeError
+				So(errors.As(err, &tooLarge), ShouldBeTrue)
+				So(tooLarge.Limit, ShouldEqual, 16)
+				So(err.Error(), ShouldContainSubstring, "larger than the 16 byte buffer limit")
+
+				call, err := unmarshallResp(resp)
+				So(err, ShouldBeNil)
+				So(call.Body, ShouldEqual, body)
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestTestServerCheckReplays(t *testing.T) {
+	Convey("Given a test server", t, func() {
+		ts := rchttptest.NewTestServer(500)
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}, MaxRetries: 2, RetryTime: time.Millisecond}
+
+		Convey("When a request with an idempotency key is retried", func() {
+			req, _ := http.NewRequest("POST", ts.URL+"/filters", strings.NewReader(`{"a":"b"}`))
+			req.Header.Set(rchttptest.IdempotencyKeyHeader, "key-1")
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then every call is recorded with the same body hash and key", func() {
+				calls := ts.GetRecordedCalls()
+				So(calls, ShouldHaveLength, 3)
+				So(calls[2].BodyHash, ShouldEqual, calls[0].BodyHash)
+				So(calls[2].BodyLength, ShouldEqual, 9)
+				So(calls[2].IdempotencyKey, ShouldEqual, "key-1")
+				So(ts.CheckReplays(), ShouldBeNil)
+			})
+		})
+
+		Convey("When calls differ in their bodies", func() {
+			for _, body := range []string{`{"a":"b"}`, `{"a":"c"}`} {
+				resp, err := http.Post(ts.URL, rchttptest.JsonContentType, strings.NewReader(body))
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then CheckReplays reports the difference", func() {
+				err := ts.CheckReplays()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "call 2 had a different body")
+			})
+		})
+
+		Convey("When calls differ in their idempotency keys", func() {
+			for _, key := range []string{"key-1", "key-2"} {
+				req, _ := http.NewRequest("PUT", ts.URL, nil)
+				req.Header.Set(rchttptest.IdempotencyKeyHeader, key)
+				resp, err := http.DefaultClient.Do(req)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then CheckReplays reports the difference", func() {
+				So(ts.CheckReplays().Error(), ShouldEqual, `call 2 had idempotency key "key-2", not "key-1"`)
+			})
+		})
+	})
+}
+
+func TestClientDrainsBodiesBetweenRetries(t *testing.T) {
+	var connections int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size := 1024
+		if r.URL.Path == "/large" {
+			size = 1 << 20
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(bytes.Repeat([]byte("x"), size))
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	Convey("Given an rchttp client retrying a server which fails with a body", t, func() {
+		atomic.StoreInt32(&connections, 0)
+		httpClient := &Client{HTTPClient: &http.Client{Transport: &http.Transport{}}, MaxRetries: 2, RetryTime: time.Millisecond}
+
+		Convey("When the bodies are within the drain limit", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then every attempt reuses the same connection", func() {
+				So(atomic.LoadInt32(&connections), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the bodies are larger than the drain limit", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL+"/large")
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then each retry is made on a new connection", func() {
+				So(atomic.LoadInt32(&connections), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When the last response is returned", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then its body has not been drained", func() {
+				b, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				So(b, ShouldHaveLength, 1024)
+			})
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package rchttp
+
+import "sync"
+
+// Defaults for a RetryBudget with a zero Ratio or MaxTokens.
+const (
+	DefaultRetryBudgetRatio     = 0.2
+	DefaultRetryBudgetMaxTokens = 10
+)
+
+// RetryBudget limits the retries made through a client to a share of the requests made
+// through it, so that retries are shed rather than multiplying the load on a downstream
+// during a widespread outage. It is a token bucket: each request adds Ratio tokens, up to
+// MaxTokens, and each retry takes one, so that over time retries are at most Ratio of
+// requests. A request is not retried (its last response or error is returned) once the
+// bucket is empty.
+type RetryBudget struct {
+	// Ratio is the tokens added by each request, DefaultRetryBudgetRatio if zero.
+	Ratio float64
+	// MaxTokens is the most tokens the bucket holds, and so the most retries which may be
+	// made in a burst, DefaultRetryBudgetMaxTokens if zero. The bucket starts full.
+	MaxTokens float64
+
+	mutex   sync.Mutex
+	tokens  float64
+	started bool
+}
+
+// NewRetryBudget returns a RetryBudget allowing retries of up to ratio of requests, and
+// up to maxTokens retries in a burst.
+func NewRetryBudget(ratio, maxTokens float64) *RetryBudget {
+	return &RetryBudget{Ratio: ratio, MaxTokens: maxTokens}
+}
+
+// Tokens returns the number of tokens in the bucket, i.e. the retries which may be made
+// before more requests are.
+func (b *RetryBudget) Tokens() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.start()
+	return b.tokens
+}
+
+// deposit adds the tokens for a request.
+func (b *RetryBudget) deposit() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.start()
+	b.tokens += b.ratio()
+	if b.tokens > b.maxTokens() {
+		b.tokens = b.maxTokens()
+	}
+}
+
+// withdraw takes the token for a retry, reporting false if there is none.
+func (b *RetryBudget) withdraw() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.start()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// start fills the bucket on first use.
+func (b *RetryBudget) start() {
+	if !b.started {
+		b.tokens, b.started = b.maxTokens(), true
+	}
+}
+
+func (b *RetryBudget) ratio() float64 {
+	if b.Ratio <= 0 {
+		return DefaultRetryBudgetRatio
+	}
+	return b.Ratio
+}
+
+func (b *RetryBudget) maxTokens() float64 {
+	if b.MaxTokens <= 0 {
+		return DefaultRetryBudgetMaxTokens
+	}
+	return b.MaxTokens
+}
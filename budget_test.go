@@ -0,0 +1,47 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientRetryBudget(t *testing.T) {
+	Convey("Given an rchttp client with a retry budget and a failing server", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusServiceUnavailable)
+		defer ts.Close()
+		budget := NewRetryBudget(0.5, 4)
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 3, RetryTime: time.Millisecond, RetryBudget: budget}
+
+		get := func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			resp.Body.Close()
+		}
+
+		Convey("When requests keep failing", func() {
+			get()
+			get()
+
+			Convey("Then retries are made until the budget is spent", func() {
+				// the full bucket allows the first request 3 retries, leaving 1 token,
+				// and the second request 1, leaving 0.5
+				So(ts.GetCalls(0), ShouldEqual, 4+2)
+				So(budget.Tokens(), ShouldEqual, 0.5)
+			})
+
+			Convey("And each later request earns a share of a retry", func() {
+				calls := ts.GetCalls(0)
+				get()
+				get()
+				So(ts.GetCalls(0)-calls, ShouldEqual, 2+1)
+				So(budget.Tokens(), ShouldEqual, 0.5)
+			})
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package rchttp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ErrConcurrencyLimit is wrapped by the *ConcurrencyLimitError returned for requests which
+// could not be made within the queue timeout because the client's concurrency limit was
+// reached.
+var ErrConcurrencyLimit = errors.New("rchttp: too many concurrent requests")
+
+// ConcurrencyLimitError is returned by Do, without the request being made, when a request
+// has waited QueueTimeout for one of the client's MaxConcurrentRequests to finish.
+type ConcurrencyLimitError struct {
+	Limit  int
+	Waited time.Duration
+}
+
+func (e *ConcurrencyLimitError) Error() string {
+	return fmt.Sprintf("rchttp: gave up after %s waiting for one of %d concurrent requests to finish", e.Waited, e.Limit)
+}
+
+// Unwrap returns ErrConcurrencyLimit.
+func (e *ConcurrencyLimitError) Unwrap() error {
+	return ErrConcurrencyLimit
+}
+
+// bulkhead bounds the requests in flight through a client.
+type bulkhead struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+// SetMaxConcurrentRequests bounds the requests in flight through the client, from when
+// Do is called until the response body is closed, to max, so that one slow downstream
+// cannot absorb all of a service's goroutines and memory. Further requests wait for one to
+// finish, for up to queueTimeout if it is positive (failing with a *ConcurrencyLimitError),
+// or until their context is done. A non-positive max removes the bound. It should be
+// called before the client is used.
+func (c *Client) SetMaxConcurrentRequests(max int, queueTimeout time.Duration) {
+	if max <= 0 {
+		c.bulkhead = nil
+		return
+	}
+	c.bulkhead = &bulkhead{slots: make(chan struct{}, max), queueTimeout: queueTimeout}
+}
+
+// InFlightRequests returns the number of requests in flight through the client. It is
+// always zero unless SetMaxConcurrentRequests has been called.
+func (c *Client) InFlightRequests() int {
+	if c.bulkhead == nil {
+		return 0
+	}
+	return len(c.bulkhead.slots)
+}
+
+// acquire waits for a slot for a request, returning the function which releases it.
+func (b *bulkhead) acquire(ctx context.Context) (func(), error) {
+	release := func() func() {
+		var once sync.Once
+		return func() {
+			once.Do(func() { <-b.slots })
+		}
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return release(), nil
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if b.queueTimeout > 0 {
+		timer := time.NewTimer(b.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return release(), nil
+	case <-timeout:
+		return nil, &ConcurrencyLimitError{Limit: cap(b.slots), Waited: b.queueTimeout}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,103 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientMaxConcurrentRequests(t *testing.T) {
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-unblock
+		}
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	Convey("Given an rchttp client bounded to two concurrent requests", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		httpClient.SetMaxConcurrentRequests(2, 50*time.Millisecond)
+
+		Convey("When a request completes", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is in flight until its body is closed", func() {
+				So(httpClient.InFlightRequests(), ShouldEqual, 1)
+				resp.Body.Close()
+				resp.Body.Close()
+				So(httpClient.InFlightRequests(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When two slow requests are in flight", func() {
+			for i := 0; i < 2; i++ {
+				go httpClient.Get(context.Background(), ts.URL+"/slow")
+			}
+			for i := 0; i < 100 && httpClient.InFlightRequests() < 2; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			So(httpClient.InFlightRequests(), ShouldEqual, 2)
+
+			Convey("Then another fails once the queue timeout is up", func() {
+				start := time.Now()
+				_, err := httpClient.Get(context.Background(), ts.URL)
+				var limitErr *ConcurrencyLimitError
+				So(errors.As(err, &limitErr), ShouldBeTrue)
+				So(errors.Is(err, ErrConcurrencyLimit), ShouldBeTrue)
+				So(limitErr.Limit, ShouldEqual, 2)
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 50*time.Millisecond)
+			})
+
+			Convey("Then another waits for its context if it is done first", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				_, err := httpClient.Get(ctx, ts.URL)
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestClientMaxConcurrentRequestsReleasedOnError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"errors":["dataset not found"]}`))
+	}))
+	defer ts.Close()
+
+	Convey("Given a bounded rchttp client which returns failed responses with an error", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, ErrorBodySnapshotSize: 100}
+		httpClient.SetMaxConcurrentRequests(1, 200*time.Millisecond)
+
+		Convey("When a request fails and the caller drops the response", func() {
+			_, err := httpClient.Capabilities(context.Background(), ts.URL)
+			So(err, ShouldNotBeNil)
+
+			Convey("Then its slot is released", func() {
+				So(httpClient.InFlightRequests(), ShouldEqual, 0)
+				_, err := httpClient.Get(context.Background(), ts.URL)
+				So(errors.Is(err, ErrConcurrencyLimit), ShouldBeFalse)
+			})
+		})
+
+		Convey("When a request fails", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the error payload can still be read", func() {
+				So(err, ShouldNotBeNil)
+				body, _ := ioutil.ReadAll(resp.Body)
+				So(string(body), ShouldEqual, `{"errors":["dataset not found"]}`)
+				So(httpClient.InFlightRequests(), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,351 @@
+package rchttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/go-ns/common"
+	"golang.org/x/net/context"
+)
+
+// credentialHeaders are the request headers identifying who a request is made by, which
+// DefaultCacheKey includes so that responses are not shared between callers.
+var credentialHeaders = []string{common.AuthHeaderKey, common.FlorenceHeaderKey, "Cookie", common.UserHeaderKey, OnBehalfOfHeaderKey}
+
+// CachedResponse is a response held in a CacheStore.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Expires    time.Time
+	// Vary is a digest of the request headers named by the response's Vary header, which
+	// a request must match to be served the response.
+	Vary string
+}
+
+// CacheStore holds cached responses by key.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+	// DeletePrefix removes all responses with keys starting with prefix.
+	DeletePrefix(prefix string)
+}
+
+const cacheBypassKey = contextKey("rchttp-cache-bypass")
+
+// Cache caches successful (200) GET responses, for the max-age in their Cache-Control
+// header or TTL otherwise. Responses marked no-store, no-cache or private, or varying on
+// all headers (Vary: *), are not cached, nor are requests marked no-store or made with a
+// context from WithCacheBypass. Requests marked no-cache are not served from the cache, but
+// their responses replace those cached. Cached responses are only served to requests with
+// the same credentials (see DefaultCacheKey) and headers named by their Vary header.
+type Cache struct {
+	Store CacheStore
+	TTL   time.Duration
+	// KeyFunc returns the key a request is cached under, DefaultCacheKey if nil.
+	KeyFunc func(req *http.Request) string
+	// SingleFlight makes concurrent requests for the same uncached key wait for the first
+	// to complete and then use its cached response, protecting downstreams from stampedes.
+	SingleFlight bool
+
+	inflightMutex sync.Mutex
+	inflight      map[string]chan struct{}
+}
+
+// NewCache returns a Cache holding responses in memory for ttl by default.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{Store: NewMemoryCacheStore(), TTL: ttl}
+}
+
+// DefaultCacheKey returns the URL of req, followed by a digest of its credentials
+// (Authorization, X-Florence-Token, Cookie, User-Identity and X-On-Behalf-Of headers) if it
+// has any, so that responses are only shared between requests made with the same
+// credentials. The client's cache sees the Authorization header a request will be sent
+// with, including credentials from its context (WithBasicAuth, WithTokenSource) or the
+// client (BasicAuth, TokenSource). Custom keys should also
+// start with DefaultCacheKey, so that cached responses can be invalidated by URL prefix.
+func DefaultCacheKey(req *http.Request) string {
+	key := req.URL.String()
+	for _, h := range credentialHeaders {
+		if len(req.Header[h]) > 0 {
+			return key + "\n" + headerDigest(req.Header, credentialHeaders)
+		}
+	}
+	return key
+}
+
+// CacheKeyWithHeaders returns a cache key function which adds the values of the given
+// request headers to DefaultCacheKey, so that e.g. responses for different collections
+// (Collection-Id) are cached separately.
+func CacheKeyWithHeaders(headers ...string) func(req *http.Request) string {
+	return func(req *http.Request) string {
+		key := DefaultCacheKey(req)
+		for _, h := range headers {
+			key += "\n" + http.CanonicalHeaderKey(h) + ": " + strings.Join(req.Header[http.CanonicalHeaderKey(h)], ",")
+		}
+		return key
+	}
+}
+
+// Invalidate removes all cached responses with keys (by default, URLs) starting with prefix.
+func (cache *Cache) Invalidate(prefix string) {
+	cache.Store.DeletePrefix(prefix)
+}
+
+// Subscribe invalidates each prefix received from events (e.g. the URLs of resources
+// affected by a publishing event) until events is closed or ctx is done.
+func (cache *Cache) Subscribe(ctx context.Context, events <-chan string) {
+	go func() {
+		for {
+			select {
+			case prefix, ok := <-events:
+				if !ok {
+					return
+				}
+				cache.Invalidate(prefix)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// WithCacheBypass returns a context which makes requests made with it skip the client's
+// cache altogether, neither being served from it nor having their responses cached, e.g.
+// for preview-mode traffic which must not see or affect cached published content.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey, true)
+}
+
+// InvalidateCache removes all cached responses with keys (by default, URLs) starting with
+// prefix. It does nothing if the client has no cache.
+func (c *Client) InvalidateCache(prefix string) {
+	if c.Cache != nil {
+		c.Cache.Invalidate(prefix)
+	}
+}
+
+// withResolvedAuth returns req, or a copy of it with the Authorization header it is to be
+// sent with if that comes from ctx or the client, for the cache to key it on.
+func (c *Client) withResolvedAuth(ctx context.Context, req *http.Request) (*http.Request, error) {
+	if req.Header.Get(common.AuthHeaderKey) != "" {
+		return req, nil
+	}
+	authReq := req.Clone(ctx)
+	if err := c.applyAuth(ctx, authReq); err != nil {
+		return nil, err
+	}
+	return authReq, nil
+}
+
+func (cache *Cache) key(req *http.Request) string {
+	if cache.KeyFunc != nil {
+		return cache.KeyFunc(req)
+	}
+	return DefaultCacheKey(req)
+}
+
+// do returns the cached response to req if there is one, or else the response from send,
+// caching it if possible.
+func (cache *Cache) do(ctx context.Context, req *http.Request, send func() (*http.Response, error)) (*http.Response, error) {
+	if bypass, _ := ctx.Value(cacheBypassKey).(bool); bypass || req.Method != "GET" || hasCacheDirective(req.Header, "no-store") {
+		return send()
+	}
+
+	key := cache.key(req)
+	revalidate := hasCacheDirective(req.Header, "no-cache") || containsToken(headerTokens(req.Header, "Pragma"), "no-cache")
+	if !revalidate {
+		if resp, ok := cache.get(key, req); ok {
+			return resp, nil
+		}
+	}
+
+	if cache.SingleFlight && !revalidate {
+		done, leader := cache.join(key)
+		if !leader {
+			select {
+			case <-done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if resp, ok := cache.get(key, req); ok {
+				return resp, nil
+			}
+			return send()
+		}
+		defer cache.leave(key, done)
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	ttl, ok := cache.ttl(resp.Header)
+	vary := headerTokens(resp.Header, "Vary")
+	if !ok || containsToken(vary, "*") {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	cache.Store.Set(key, &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     cloneHeader(resp.Header),
+		Body:       body,
+		Expires:    time.Now().Add(ttl),
+		Vary:       headerDigest(req.Header, vary),
+	})
+	return resp, nil
+}
+
+// get returns the unexpired response cached under key, if any, which varies on headers
+// matching those of req.
+func (cache *Cache) get(key string, req *http.Request) (*http.Response, bool) {
+	cached, ok := cache.Store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(cached.Expires) {
+		cache.Store.Delete(key)
+		return nil, false
+	}
+	if cached.Vary != headerDigest(req.Header, headerTokens(cached.Header, "Vary")) {
+		return nil, false
+	}
+	return cached.response(req), true
+}
+
+// join returns a channel which is closed when the request in flight for key completes,
+// and whether the caller is the leader which must make the request and then call leave.
+func (cache *Cache) join(key string) (chan struct{}, bool) {
+	cache.inflightMutex.Lock()
+	defer cache.inflightMutex.Unlock()
+	if done, ok := cache.inflight[key]; ok {
+		return done, false
+	}
+	if cache.inflight == nil {
+		cache.inflight = make(map[string]chan struct{})
+	}
+	done := make(chan struct{})
+	cache.inflight[key] = done
+	return done, true
+}
+
+func (cache *Cache) leave(key string, done chan struct{}) {
+	cache.inflightMutex.Lock()
+	delete(cache.inflight, key)
+	cache.inflightMutex.Unlock()
+	close(done)
+}
+
+// ttl returns how long a response with header h may be cached for, if at all.
+func (cache *Cache) ttl(h http.Header) (time.Duration, bool) {
+	if hasCacheDirective(h, "no-store") || hasCacheDirective(h, "no-cache") || hasCacheDirective(h, "private") {
+		return 0, false
+	}
+	for _, directive := range headerTokens(h, "Cache-Control") {
+		if strings.HasPrefix(strings.ToLower(directive), "max-age=") {
+			if maxAge, err := strconv.Atoi(directive[len("max-age="):]); err == nil {
+				return time.Duration(maxAge) * time.Second, maxAge > 0
+			}
+		}
+	}
+	return cache.TTL, cache.TTL > 0
+}
+
+// headerDigest returns a digest of the values of the named headers of h, or "" if there
+// are none, so that credentials are not held in cache keys or stores.
+func headerDigest(h http.Header, names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	digest := sha256.New()
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		digest.Write([]byte(name + ": " + strings.Join(h[name], ",") + "\n"))
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+func hasCacheDirective(h http.Header, directive string) bool {
+	return containsToken(headerTokens(h, "Cache-Control"), directive)
+}
+
+func (cached *CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(cached.StatusCode) + " " + http.StatusText(cached.StatusCode),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cloneHeader(cached.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
+
+func cloneHeader(h http.Header) http.Header {
+	clone := make(http.Header, len(h))
+	for k, v := range h {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
+
+// MemoryCacheStore is a CacheStore holding responses in memory.
+type MemoryCacheStore struct {
+	mutex     sync.RWMutex
+	responses map[string]*CachedResponse
+}
+
+// NewMemoryCacheStore returns an empty MemoryCacheStore.
+func NewMemoryCacheStore() *MemoryCacheStore {
+	return &MemoryCacheStore{responses: make(map[string]*CachedResponse)}
+}
+
+// Get returns the response cached under key.
+func (store *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	resp, ok := store.responses[key]
+	return resp, ok
+}
+
+// Set caches resp under key.
+func (store *MemoryCacheStore) Set(key string, resp *CachedResponse) {
+	store.mutex.Lock()
+	store.responses[key] = resp
+	store.mutex.Unlock()
+}
+
+// Delete removes the response cached under key.
+func (store *MemoryCacheStore) Delete(key string) {
+	store.mutex.Lock()
+	delete(store.responses, key)
+	store.mutex.Unlock()
+}
+
+// DeletePrefix removes all responses with keys starting with prefix.
+func (store *MemoryCacheStore) DeletePrefix(prefix string) {
+	store.mutex.Lock()
+	for key := range store.responses {
+		if strings.HasPrefix(key, prefix) {
+			delete(store.responses, key)
+		}
+	}
+	store.mutex.Unlock()
+}
@@ -0,0 +1,225 @@
+package rchttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const diskCacheFileSuffix = ".rchttp-cache"
+
+var errCorruptCacheRecord = errors.New("rchttp: corrupt cache record")
+
+// DiskCacheStore is a CacheStore holding responses in files in a directory, so that they
+// persist across runs. Each file is checksummed, and corrupt files are discarded. When the
+// total size of the files exceeds MaxSize, the least recently used are removed.
+type DiskCacheStore struct {
+	dir     string
+	maxSize int64
+
+	mutex   sync.Mutex
+	entries map[string]*diskCacheEntry
+	size    int64
+}
+
+type diskCacheEntry struct {
+	file     string
+	size     int64
+	lastUsed time.Time
+}
+
+type diskCacheRecord struct {
+	Key      string          `json:"key"`
+	Response *CachedResponse `json:"response"`
+	Checksum string          `json:"checksum"`
+}
+
+// NewDiskCacheStore returns a DiskCacheStore using dir (created if necessary), holding at
+// most maxSize bytes of responses (unbounded if maxSize is zero or less). Responses
+// already in dir are loaded.
+func NewDiskCacheStore(dir string, maxSize int64) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	store := &DiskCacheStore{dir: dir, maxSize: maxSize, entries: make(map[string]*diskCacheEntry)}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range files {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), diskCacheFileSuffix) {
+			continue
+		}
+		file := filepath.Join(dir, info.Name())
+		record, err := readDiskCacheRecord(file)
+		if err != nil {
+			os.Remove(file)
+			continue
+		}
+		store.entries[record.Key] = &diskCacheEntry{file: file, size: info.Size(), lastUsed: info.ModTime()}
+		store.size += info.Size()
+	}
+	store.evict()
+	return store, nil
+}
+
+// Get returns the response cached under key, if its file is intact.
+func (store *DiskCacheStore) Get(key string) (*CachedResponse, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		return nil, false
+	}
+	record, err := readDiskCacheRecord(entry.file)
+	if err != nil || record.Key != key {
+		store.remove(key)
+		return nil, false
+	}
+	entry.lastUsed = time.Now()
+	os.Chtimes(entry.file, entry.lastUsed, entry.lastUsed)
+	return record.Response, true
+}
+
+// Set caches resp under key, removing the least recently used responses if MaxSize is exceeded.
+func (store *DiskCacheStore) Set(key string, resp *CachedResponse) {
+	record := diskCacheRecord{Key: key, Response: resp}
+	checksum, err := diskCacheChecksum(resp)
+	if err != nil {
+		return
+	}
+	record.Checksum = checksum
+	b, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	file := filepath.Join(store.dir, diskCacheFileName(key))
+	tmp, err := ioutil.TempFile(store.dir, "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	if old, ok := store.entries[key]; ok {
+		store.size -= old.size
+	}
+	store.entries[key] = &diskCacheEntry{file: file, size: int64(len(b)), lastUsed: time.Now()}
+	store.size += int64(len(b))
+	store.evict()
+}
+
+// Delete removes the response cached under key.
+func (store *DiskCacheStore) Delete(key string) {
+	store.mutex.Lock()
+	store.remove(key)
+	store.mutex.Unlock()
+}
+
+// DeletePrefix removes all responses with keys starting with prefix.
+func (store *DiskCacheStore) DeletePrefix(prefix string) {
+	store.mutex.Lock()
+	for key := range store.entries {
+		if strings.HasPrefix(key, prefix) {
+			store.remove(key)
+		}
+	}
+	store.mutex.Unlock()
+}
+
+// Size returns the total size in bytes of the cached responses.
+func (store *DiskCacheStore) Size() int64 {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.size
+}
+
+// remove deletes the entry for key and its file. The mutex must be held.
+func (store *DiskCacheStore) remove(key string) {
+	if entry, ok := store.entries[key]; ok {
+		os.Remove(entry.file)
+		store.size -= entry.size
+		delete(store.entries, key)
+	}
+}
+
+// evict removes the least recently used entries until the store is within its maximum
+// size. The mutex must be held.
+func (store *DiskCacheStore) evict() {
+	if store.maxSize <= 0 || store.size <= store.maxSize {
+		return
+	}
+	keys := make([]string, 0, len(store.entries))
+	for key := range store.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return store.entries[keys[i]].lastUsed.Before(store.entries[keys[j]].lastUsed)
+	})
+	for _, key := range keys {
+		if store.size <= store.maxSize {
+			return
+		}
+		store.remove(key)
+	}
+}
+
+func diskCacheFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + diskCacheFileSuffix
+}
+
+func diskCacheChecksum(resp *CachedResponse) (string, error) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readDiskCacheRecord reads the record in file, returning an error if it is corrupt.
+func readDiskCacheRecord(file string) (*diskCacheRecord, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	record := &diskCacheRecord{}
+	if err := json.Unmarshal(b, record); err != nil {
+		return nil, err
+	}
+	if record.Response == nil {
+		return nil, errCorruptCacheRecord
+	}
+	checksum, err := diskCacheChecksum(record.Response)
+	if err != nil {
+		return nil, err
+	}
+	if checksum != record.Checksum {
+		return nil, errCorruptCacheRecord
+	}
+	return record, nil
+}
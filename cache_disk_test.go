@@ -0,0 +1,118 @@
+package rchttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiskCacheStore(t *testing.T) {
+	Convey("Given a disk cache store in a temporary directory", t, func() {
+		dir, err := ioutil.TempDir("", "rchttp-cache")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		store, err := NewDiskCacheStore(dir, 0)
+		So(err, ShouldBeNil)
+
+		resp := &CachedResponse{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       []byte(`{"id":"cpih"}`),
+			Expires:    time.Now().Add(time.Minute).Round(0),
+		}
+		store.Set("http://localhost/datasets/cpih", resp)
+
+		Convey("When the response is read back", func() {
+			cached, ok := store.Get("http://localhost/datasets/cpih")
+
+			Convey("Then it is the same response", func() {
+				So(ok, ShouldBeTrue)
+				So(cached.StatusCode, ShouldEqual, 200)
+				So(cached.Header, ShouldResemble, resp.Header)
+				So(string(cached.Body), ShouldEqual, `{"id":"cpih"}`)
+				So(cached.Expires.Equal(resp.Expires), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the store is reopened", func() {
+			reopened, err := NewDiskCacheStore(dir, 0)
+			So(err, ShouldBeNil)
+
+			Convey("Then the response persists", func() {
+				cached, ok := reopened.Get("http://localhost/datasets/cpih")
+				So(ok, ShouldBeTrue)
+				So(string(cached.Body), ShouldEqual, `{"id":"cpih"}`)
+			})
+		})
+
+		Convey("When the file is corrupted", func() {
+			file := filepath.Join(dir, diskCacheFileName("http://localhost/datasets/cpih"))
+			b, err := ioutil.ReadFile(file)
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(file, []byte(strings.Replace(string(b), "200", "500", 1)), 0600), ShouldBeNil)
+
+			Convey("Then the response is discarded", func() {
+				_, ok := store.Get("http://localhost/datasets/cpih")
+				So(ok, ShouldBeFalse)
+				_, err := os.Stat(file)
+				So(os.IsNotExist(err), ShouldBeTrue)
+				So(store.Size(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When responses are deleted by prefix", func() {
+			store.Set("http://localhost/datasets/wellbeing", resp)
+			store.DeletePrefix("http://localhost/datasets/c")
+
+			Convey("Then only matching responses are removed", func() {
+				_, ok := store.Get("http://localhost/datasets/cpih")
+				So(ok, ShouldBeFalse)
+				_, ok = store.Get("http://localhost/datasets/wellbeing")
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a size-bounded disk cache store", t, func() {
+		dir, err := ioutil.TempDir("", "rchttp-cache")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		resp := &CachedResponse{StatusCode: 200, Body: []byte(strings.Repeat("x", 100))}
+		unbounded, err := NewDiskCacheStore(dir, 0)
+		So(err, ShouldBeNil)
+		unbounded.Set("probe", resp)
+		entrySize := unbounded.Size()
+		unbounded.Delete("probe")
+
+		store, err := NewDiskCacheStore(dir, 2*entrySize)
+		So(err, ShouldBeNil)
+
+		Convey("When more responses are cached than fit", func() {
+			store.Set("a", resp)
+			time.Sleep(10 * time.Millisecond)
+			store.Set("b", resp)
+			time.Sleep(10 * time.Millisecond)
+			store.Get("a")
+			time.Sleep(10 * time.Millisecond)
+			store.Set("c", resp)
+
+			Convey("Then the least recently used response is evicted", func() {
+				So(store.Size(), ShouldBeLessThanOrEqualTo, 2*entrySize)
+				_, ok := store.Get("b")
+				So(ok, ShouldBeFalse)
+				_, ok = store.Get("a")
+				So(ok, ShouldBeTrue)
+				_, ok = store.Get("c")
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,132 @@
+package rchttp
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ErrRedisNil is returned by RedisClient.Get when a key does not exist.
+var ErrRedisNil = errors.New("rchttp: redis key does not exist")
+
+// RedisClient is the subset of Redis commands used by RedisCacheStore. A thin adapter is
+// needed around e.g. a go-redis client, returning ErrRedisNil for missing keys.
+type RedisClient interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, expiration time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	// Scan returns all keys matching the glob-style pattern.
+	Scan(ctx context.Context, pattern string) ([]string, error)
+}
+
+// RedisCacheStore is a CacheStore holding responses in Redis, so that they are shared by
+// all replicas of a service. Keys are namespaced with Prefix. Combine with
+// Cache.SingleFlight to protect downstreams from stampedes within each replica.
+type RedisCacheStore struct {
+	Client RedisClient
+	Prefix string
+	// Timeout bounds each Redis command, defaulting to one second.
+	Timeout time.Duration
+	// OnError, if set, is called with errors from Redis, which are otherwise treated as cache misses.
+	OnError func(err error)
+}
+
+// NewRedisCacheStore returns a RedisCacheStore using client, with keys namespaced with prefix.
+func NewRedisCacheStore(client RedisClient, prefix string) *RedisCacheStore {
+	return &RedisCacheStore{Client: client, Prefix: prefix}
+}
+
+// Get returns the response cached under key.
+func (store *RedisCacheStore) Get(key string) (*CachedResponse, bool) {
+	ctx, cancel := store.context()
+	defer cancel()
+
+	b, err := store.Client.Get(ctx, store.Prefix+key)
+	if err != nil {
+		if err != ErrRedisNil {
+			store.error(err)
+		}
+		return nil, false
+	}
+	resp := &CachedResponse{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		store.error(err)
+		return nil, false
+	}
+	return resp, true
+}
+
+// Set caches resp under key, expiring it from Redis when the response expires.
+func (store *RedisCacheStore) Set(key string, resp *CachedResponse) {
+	ttl := time.Until(resp.Expires)
+	if ttl <= 0 {
+		return
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		store.error(err)
+		return
+	}
+
+	ctx, cancel := store.context()
+	defer cancel()
+	if err := store.Client.Set(ctx, store.Prefix+key, b, ttl); err != nil {
+		store.error(err)
+	}
+}
+
+// Delete removes the response cached under key.
+func (store *RedisCacheStore) Delete(key string) {
+	ctx, cancel := store.context()
+	defer cancel()
+	if err := store.Client.Del(ctx, store.Prefix+key); err != nil {
+		store.error(err)
+	}
+}
+
+// DeletePrefix removes all responses with keys starting with prefix.
+func (store *RedisCacheStore) DeletePrefix(prefix string) {
+	ctx, cancel := store.context()
+	defer cancel()
+
+	keys, err := store.Client.Scan(ctx, escapeRedisPattern(store.Prefix+prefix)+"*")
+	if err != nil {
+		store.error(err)
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := store.Client.Del(ctx, keys...); err != nil {
+		store.error(err)
+	}
+}
+
+func (store *RedisCacheStore) context() (context.Context, context.CancelFunc) {
+	timeout := store.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func (store *RedisCacheStore) error(err error) {
+	if store.OnError != nil {
+		store.OnError(err)
+	}
+}
+
+// escapeRedisPattern escapes the glob characters in s for use in a Redis SCAN pattern.
+func escapeRedisPattern(s string) string {
+	escaped := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, r)
+	}
+	return string(escaped)
+}
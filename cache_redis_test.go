@@ -0,0 +1,98 @@
+package rchttp
+
+import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeRedis is an in-memory RedisClient, ignoring expiry.
+type fakeRedis struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+	ttls   map[string]time.Duration
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string][]byte), ttls: make(map[string]time.Duration)}
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		return nil, ErrRedisNil
+	}
+	return value, nil
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.values[key] = value
+	r.ttls[key] = expiration
+	return nil
+}
+
+func (r *fakeRedis) Del(ctx context.Context, keys ...string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, key := range keys {
+		delete(r.values, key)
+	}
+	return nil
+}
+
+func (r *fakeRedis) Scan(ctx context.Context, pattern string) (keys []string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key := range r.values {
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func TestRedisCacheStore(t *testing.T) {
+	Convey("Given a Redis cache store", t, func() {
+		redis := newFakeRedis()
+		store := NewRedisCacheStore(redis, "frontend:")
+		resp := &CachedResponse{StatusCode: 200, Body: []byte("hello"), Expires: time.Now().Add(time.Minute)}
+
+		Convey("When a response is cached", func() {
+			store.Set("http://localhost/a*b", resp)
+
+			Convey("Then it is stored under the prefixed key and expires with the response", func() {
+				So(redis.values, ShouldContainKey, "frontend:http://localhost/a*b")
+				So(redis.ttls["frontend:http://localhost/a*b"], ShouldBeBetweenOrEqual, 59*time.Second, time.Minute)
+
+				cached, ok := store.Get("http://localhost/a*b")
+				So(ok, ShouldBeTrue)
+				So(string(cached.Body), ShouldEqual, "hello")
+			})
+
+			Convey("And it can be deleted by prefix", func() {
+				store.Set("http://localhost/abc", resp)
+				store.DeletePrefix("http://localhost/a*")
+				_, ok := store.Get("http://localhost/a*b")
+				So(ok, ShouldBeFalse)
+				_, ok = store.Get("http://localhost/abc")
+				So(ok, ShouldBeTrue)
+			})
+		})
+
+		Convey("When an expired response is cached", func() {
+			store.Set("expired", &CachedResponse{Expires: time.Now().Add(-time.Second)})
+
+			Convey("Then it is not stored", func() {
+				So(redis.values, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,337 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientCache(t *testing.T) {
+	Convey("Given an rchttp client with a cache", t, func() {
+		ts := rchttptest.NewTestServer(200)
+		defer ts.Close()
+
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, Cache: NewCache(time.Minute)}
+
+		Convey("When Get() is called twice on a URL", func() {
+			first, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			firstBody, _ := ioutil.ReadAll(first.Body)
+
+			second, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			secondBody, _ := ioutil.ReadAll(second.Body)
+
+			Convey("Then the server sees one call and the cached response is returned", func() {
+				So(ts.GetCalls(0), ShouldEqual, 1)
+				So(second.StatusCode, ShouldEqual, 200)
+				So(string(secondBody), ShouldEqual, string(firstBody))
+				So(second.Header.Get(rchttptest.ContentTypeHeader), ShouldEqual, first.Header.Get(rchttptest.ContentTypeHeader))
+			})
+		})
+
+		Convey("When the request asks for no-store", func() {
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest("GET", ts.URL, nil)
+				req.Header.Set("Cache-Control", "no-store")
+				resp, err := httpClient.Do(context.Background(), req)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then the cache is bypassed", func() {
+				So(ts.GetCalls(0), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a request asks for no-cache after a response is cached", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			req.Header.Set("Cache-Control", "no-cache")
+			resp, err = httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			resp, err = httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is not served from the cache, but its response is cached", func() {
+				So(ts.GetCalls(0), ShouldEqual, 2)
+				So(call.CallCount, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When requests bypass the cache", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			ctx := WithCacheBypass(context.Background())
+			for i := 0; i < 2; i++ {
+				resp, err := httpClient.Get(ctx, ts.URL)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			resp, err = httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then they are neither served from nor stored in the cache", func() {
+				So(ts.GetCalls(0), ShouldEqual, 3)
+				So(call.CallCount, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When requests differ only by a header included in the cache key", func() {
+			httpClient.Cache.KeyFunc = CacheKeyWithHeaders("Collection-Id")
+			for _, collection := range []string{"preview", "preview", ""} {
+				req, _ := http.NewRequest("GET", ts.URL, nil)
+				if collection != "" {
+					req.Header.Set("Collection-Id", collection)
+				}
+				resp, err := httpClient.Do(context.Background(), req)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then each variant is cached separately", func() {
+				So(ts.GetCalls(0), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When requests are made with different credentials", func() {
+			for _, token := range []string{"Bearer a", "Bearer a", "Bearer b", ""} {
+				req, _ := http.NewRequest("GET", ts.URL, nil)
+				if token != "" {
+					req.Header.Set("Authorization", token)
+				}
+				resp, err := httpClient.Do(context.Background(), req)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then responses are only shared between requests with the same credentials", func() {
+				So(ts.GetCalls(0), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When two users share the client with credentials from their contexts", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				username, _, _ := r.BasicAuth()
+				w.Write([]byte("secret-of-" + username))
+			}))
+			defer server.Close()
+			get := func(ctx context.Context) string {
+				resp, err := httpClient.Get(ctx, server.URL)
+				So(err, ShouldBeNil)
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				return string(body)
+			}
+
+			alice := get(WithBasicAuth(context.Background(), "alice", "a"))
+			bob := get(WithBasicAuth(context.Background(), "bob", "b"))
+
+			Convey("Then each is only served their own response", func() {
+				So(alice, ShouldEqual, "secret-of-alice")
+				So(bob, ShouldEqual, "secret-of-bob")
+				So(get(WithBasicAuth(context.Background(), "alice", "a")), ShouldEqual, "secret-of-alice")
+			})
+		})
+
+		Convey("When Post() is called", func() {
+			for i := 0; i < 2; i++ {
+				resp, err := httpClient.Post(context.Background(), ts.URL, "text/plain", nil)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then the response is not cached", func() {
+				So(ts.GetCalls(0), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestCacheVary(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/any" {
+			w.Header().Set("Vary", "*")
+		} else {
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with a cache", t, func() {
+		atomic.StoreInt32(&calls, 0)
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, Cache: NewCache(time.Minute)}
+		get := func(url, language string) string {
+			req, _ := http.NewRequest("GET", url, nil)
+			req.Header.Set("Accept-Language", language)
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			return string(body)
+		}
+
+		Convey("When responses vary on a header the requests differ by", func() {
+			bodies := []string{get(ts.URL, "en"), get(ts.URL, "cy"), get(ts.URL, "cy")}
+
+			Convey("Then a response is only served to requests matching it", func() {
+				So(bodies, ShouldResemble, []string{"en", "cy", "cy"})
+				So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When responses vary on all headers", func() {
+			get(ts.URL+"/any", "en")
+			get(ts.URL+"/any", "en")
+
+			Convey("Then they are not cached", func() {
+				So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestCacheTTL(t *testing.T) {
+	Convey("Given a cache with a default TTL", t, func() {
+		cache := NewCache(time.Minute)
+
+		Convey("Then Cache-Control directives determine the TTL", func() {
+			ttl, ok := cache.ttl(http.Header{})
+			So(ok, ShouldBeTrue)
+			So(ttl, ShouldEqual, time.Minute)
+
+			ttl, ok = cache.ttl(http.Header{"Cache-Control": {"public, max-age=5"}})
+			So(ok, ShouldBeTrue)
+			So(ttl, ShouldEqual, 5*time.Second)
+
+			_, ok = cache.ttl(http.Header{"Cache-Control": {"max-age=0"}})
+			So(ok, ShouldBeFalse)
+			_, ok = cache.ttl(http.Header{"Cache-Control": {"private"}})
+			So(ok, ShouldBeFalse)
+			_, ok = cache.ttl(http.Header{"Cache-Control": {"no-store"}})
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestClientInvalidateCache(t *testing.T) {
+	Convey("Given an rchttp client with cached responses for two datasets", t, func() {
+		ts := rchttptest.NewTestServer(200)
+		defer ts.Close()
+
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, Cache: NewCache(time.Minute)}
+		get := func(path string) {
+			resp, err := httpClient.Get(context.Background(), ts.URL+path)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+		}
+		get("/datasets/cpih")
+		get("/datasets/cpih/editions")
+		get("/datasets/wellbeing")
+		So(ts.GetCalls(0), ShouldEqual, 3)
+
+		Convey("When the cache is invalidated for one dataset", func() {
+			httpClient.InvalidateCache(ts.URL + "/datasets/cpih")
+			get("/datasets/cpih")
+			get("/datasets/cpih/editions")
+			get("/datasets/wellbeing")
+
+			Convey("Then only that dataset's responses are fetched again", func() {
+				So(ts.GetCalls(0), ShouldEqual, 5)
+			})
+		})
+
+		Convey("When an invalidation event is received by a subscribed cache", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events := make(chan string)
+			httpClient.Cache.Subscribe(ctx, events)
+			events <- ts.URL + "/datasets/wellbeing"
+			close(events)
+
+			Convey("Then that dataset's response is evicted", func() {
+				So(waitFor(func() bool {
+					_, ok := httpClient.Cache.Store.Get(ts.URL + "/datasets/wellbeing")
+					return !ok
+				}), ShouldBeTrue)
+				get("/datasets/cpih")
+				So(ts.GetCalls(0), ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+// waitFor polls condition for up to a second, reporting whether it became true.
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestCacheSingleFlight(t *testing.T) {
+	Convey("Given an rchttp client with a single-flight cache and a slow server", t, func() {
+		var mutex sync.Mutex
+		calls := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			calls++
+			mutex.Unlock()
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte("slow"))
+		}))
+		defer ts.Close()
+
+		cache := NewCache(time.Minute)
+		cache.SingleFlight = true
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, Cache: cache}
+
+		Convey("When Get() is called concurrently on a URL", func() {
+			var wg sync.WaitGroup
+			bodies := make([]string, 5)
+			for i := range bodies {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					resp, err := httpClient.Get(context.Background(), ts.URL)
+					if err == nil {
+						b, _ := ioutil.ReadAll(resp.Body)
+						bodies[i] = string(b)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("Then the server sees one call and every caller gets the response", func() {
+				So(calls, ShouldEqual, 1)
+				So(bodies, ShouldResemble, []string{"slow", "slow", "slow", "slow", "slow"})
+			})
+		})
+	})
+}
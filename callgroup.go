@@ -0,0 +1,230 @@
+package rchttp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// CallOutcome is the outcome of one call in a CallGroup.
+type CallOutcome struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+	// Cancelled is true if the call failed after the group cancelled its remaining calls.
+	Cancelled bool
+}
+
+// ErrQuorumNotMet is returned by Wait under a Quorum policy when fewer calls were started
+// than are needed to succeed.
+var ErrQuorumNotMet = errors.New("rchttp: quorum of successful calls not met")
+
+type policyMode int
+
+const (
+	failFast policyMode = iota
+	collectAll
+	quorum
+)
+
+// CallPolicy decides when a CallGroup cancels its remaining calls and what error Wait returns.
+type CallPolicy struct {
+	mode   policyMode
+	quorum int
+}
+
+var (
+	// FailFast cancels the remaining calls as soon as one fails, and Wait returns that
+	// call's error. It is the default policy.
+	FailFast = CallPolicy{mode: failFast}
+	// CollectAll lets every call complete, and Wait returns a *MultiError of all the
+	// calls which failed.
+	CollectAll = CallPolicy{mode: collectAll}
+)
+
+// Quorum returns a policy which needs n calls to succeed: the remaining calls are
+// cancelled as soon as n have succeeded, or once Wait is called and too many have failed
+// for n to succeed, in which case Wait returns a *MultiError of the calls which failed.
+func Quorum(n int) CallPolicy {
+	return CallPolicy{mode: quorum, quorum: n}
+}
+
+// CallError is the error of one call in a CallGroup.
+type CallError struct {
+	Name string
+	Err  error
+}
+
+func (e *CallError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the call's error.
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError holds the errors of the calls which failed in a CallGroup, each keeping its
+// own (typed) error.
+type MultiError struct {
+	Errors []*CallError
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("rchttp: %d calls failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the calls, so that errors.Is and errors.As match any of them.
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// CallGroup runs a set of parallel downstream calls which share the deadline of the
+// group's context, aggregating their errors according to its Policy (FailFast by default).
+type CallGroup struct {
+	// Client makes the calls started with GetJSON.
+	Client *Client
+	// Policy must be set before any calls are started.
+	Policy CallPolicy
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mutex     sync.Mutex
+	outcomes  []*CallOutcome
+	err       error
+	succeeded int
+	failed    int
+	waiting   bool
+	cancelled bool
+}
+
+// NewCallGroup returns a CallGroup whose calls share ctx (and so its deadline), for use
+// with client.
+func NewCallGroup(ctx context.Context, client *Client) *CallGroup {
+	ctx, cancel := context.WithCancel(ctx)
+	return &CallGroup{Client: client, ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a new goroutine with the group's context, which is cancelled when the
+// group's Policy decides the remaining calls are no longer needed. All calls must be
+// started before Wait is called; Go panics if called once Wait has begun.
+func (g *CallGroup) Go(name string, fn func(ctx context.Context) error) {
+	outcome := &CallOutcome{Name: name}
+	g.mutex.Lock()
+	if g.waiting {
+		g.mutex.Unlock()
+		panic("rchttp: CallGroup.Go called after Wait")
+	}
+	g.outcomes = append(g.outcomes, outcome)
+	g.wg.Add(1)
+	g.mutex.Unlock()
+
+	go func() {
+		defer g.wg.Done()
+		start := time.Now()
+		err := fn(g.ctx)
+
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		outcome.Err = err
+		outcome.Duration = time.Since(start)
+		if err == nil {
+			g.succeeded++
+		} else if g.cancelled {
+			outcome.Cancelled = true
+		} else {
+			g.failed++
+			if g.err == nil {
+				g.err = err
+			}
+		}
+		g.settle()
+	}()
+}
+
+// GetJSON runs a call, as Go, which gets url with the group's Client and decodes the JSON
+// response body into v.
+func (g *CallGroup) GetJSON(name, url string, v interface{}) {
+	g.Go(name, func(ctx context.Context) error {
+		_, err := g.Client.GetJSON(ctx, url, v)
+		return err
+	})
+}
+
+// settle cancels the remaining calls if the policy no longer needs them. It must be
+// called with the mutex held.
+func (g *CallGroup) settle() {
+	if g.cancelled {
+		return
+	}
+	switch g.Policy.mode {
+	case failFast:
+		g.cancelled = g.failed > 0
+	case quorum:
+		g.cancelled = g.succeeded >= g.Policy.quorum || (g.waiting && len(g.outcomes)-g.failed < g.Policy.quorum)
+	}
+	if g.cancelled {
+		g.cancel()
+	}
+}
+
+// Wait waits for all calls to return, and returns their outcomes (in the order the calls
+// were started) and the error decided by the group's Policy.
+func (g *CallGroup) Wait() ([]CallOutcome, error) {
+	g.mutex.Lock()
+	g.waiting = true
+	g.settle()
+	g.mutex.Unlock()
+
+	g.wg.Wait()
+	g.cancel()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	outcomes := make([]CallOutcome, len(g.outcomes))
+	for i, outcome := range g.outcomes {
+		outcomes[i] = *outcome
+	}
+
+	switch g.Policy.mode {
+	case failFast:
+		return outcomes, g.err
+	case quorum:
+		if g.succeeded >= g.Policy.quorum {
+			return outcomes, nil
+		}
+		if err := multiError(outcomes); err != nil {
+			return outcomes, err
+		}
+		return outcomes, ErrQuorumNotMet
+	}
+	return outcomes, multiError(outcomes)
+}
+
+// multiError returns a *MultiError of the failed (but not cancelled) calls, or nil.
+func multiError(outcomes []CallOutcome) error {
+	var errs []*CallError
+	for _, outcome := range outcomes {
+		if outcome.Err != nil && !outcome.Cancelled {
+			errs = append(errs, &CallError{Name: outcome.Name, Err: outcome.Err})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &MultiError{Errors: errs}
+}
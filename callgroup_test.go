@@ -0,0 +1,169 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCallGroup(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+			}
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	Convey("Given a call group", t, func() {
+		group := NewCallGroup(context.Background(), &Client{HTTPClient: DefaultClient.HTTPClient})
+
+		Convey("When all calls succeed", func() {
+			group.GetJSON("header", ts.URL+"/header", nil)
+			group.GetJSON("footer", ts.URL+"/footer", nil)
+			outcomes, err := group.Wait()
+
+			Convey("Then each outcome is reported in order", func() {
+				So(err, ShouldBeNil)
+				So(outcomes, ShouldHaveLength, 2)
+				So(outcomes[0].Name, ShouldEqual, "header")
+				So(outcomes[0].Err, ShouldBeNil)
+				So(outcomes[1].Name, ShouldEqual, "footer")
+			})
+		})
+
+		Convey("When one call fails", func() {
+			errFatal := errors.New("dataset unavailable")
+			start := time.Now()
+			group.GetJSON("slow", ts.URL+"/slow", nil)
+			group.Go("dataset", func(ctx context.Context) error {
+				time.Sleep(20 * time.Millisecond)
+				return errFatal
+			})
+			outcomes, err := group.Wait()
+
+			Convey("Then its siblings are cancelled and its error is returned", func() {
+				So(err, ShouldEqual, errFatal)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+				So(outcomes[0].Err, ShouldNotBeNil)
+				So(outcomes[0].Cancelled, ShouldBeTrue)
+				So(outcomes[1].Err, ShouldEqual, errFatal)
+				So(outcomes[1].Cancelled, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a call group with a deadline", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		group := NewCallGroup(ctx, &Client{HTTPClient: DefaultClient.HTTPClient})
+
+		Convey("When a call outlasts it", func() {
+			group.GetJSON("slow", ts.URL+"/slow", nil)
+			_, err := group.Wait()
+
+			Convey("Then the call fails with the shared deadline", func() {
+				So(errors.Is(err, ErrCallerDeadline), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestCallGroupPolicies(t *testing.T) {
+	errA := errors.New("a failed")
+	errB := &StatusError{Method: "GET", URL: "http://b", StatusCode: 500}
+
+	Convey("Given a call group collecting all errors", t, func() {
+		group := NewCallGroup(context.Background(), nil)
+		group.Policy = CollectAll
+
+		Convey("When several calls fail", func() {
+			group.Go("a", func(ctx context.Context) error { return errA })
+			group.Go("b", func(ctx context.Context) error { return errB })
+			group.Go("c", func(ctx context.Context) error {
+				time.Sleep(20 * time.Millisecond)
+				return ctx.Err()
+			})
+			outcomes, err := group.Wait()
+
+			Convey("Then no call is cancelled and every error is kept with its type", func() {
+				So(outcomes[2].Err, ShouldBeNil)
+				multi, ok := err.(*MultiError)
+				So(ok, ShouldBeTrue)
+				So(multi.Errors, ShouldHaveLength, 2)
+				So(errors.Is(err, errA), ShouldBeTrue)
+				var statusErr *StatusError
+				So(errors.As(err, &statusErr), ShouldBeTrue)
+				So(statusErr.URL, ShouldEqual, "http://b")
+			})
+
+			Convey("And starting another call afterwards panics", func() {
+				So(func() {
+					group.Go("d", func(ctx context.Context) error { return nil })
+				}, ShouldPanic)
+			})
+		})
+	})
+
+	Convey("Given a call group needing a quorum of 2", t, func() {
+		group := NewCallGroup(context.Background(), nil)
+		group.Policy = Quorum(2)
+
+		Convey("When two calls succeed", func() {
+			group.Go("a", func(ctx context.Context) error { return nil })
+			group.Go("b", func(ctx context.Context) error { return errA })
+			group.Go("c", func(ctx context.Context) error { return nil })
+			group.Go("slow", func(ctx context.Context) error {
+				select {
+				case <-time.After(5 * time.Second):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			outcomes, err := group.Wait()
+
+			Convey("Then the remaining call is cancelled and there is no error", func() {
+				So(err, ShouldBeNil)
+				So(outcomes[3].Cancelled, ShouldBeTrue)
+			})
+		})
+
+		Convey("When too many calls fail for the quorum", func() {
+			group.Go("a", func(ctx context.Context) error { return errA })
+			group.Go("b", func(ctx context.Context) error { return errB })
+			group.Go("slow", func(ctx context.Context) error {
+				select {
+				case <-time.After(5 * time.Second):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			start := time.Now()
+			_, err := group.Wait()
+
+			Convey("Then the group stops early with the failures", func() {
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+				So(err.(*MultiError).Errors, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When fewer calls are started than the quorum", func() {
+			group.Go("a", func(ctx context.Context) error { return nil })
+			_, err := group.Wait()
+
+			Convey("Then ErrQuorumNotMet is returned", func() {
+				So(err, ShouldEqual, ErrQuorumNotMet)
+			})
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package rchttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Capabilities describes what a server advertises in response to an OPTIONS request,
+// from the Allow header and any CORS (Access-Control-*) headers.
+type Capabilities struct {
+	StatusCode       int
+	Allow            []string
+	AllowOrigin      string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// Allows reports whether method is listed in the Allow header.
+func (caps *Capabilities) Allows(method string) bool {
+	return containsToken(caps.Allow, method)
+}
+
+// AllowsCORSMethod reports whether method is listed in the Access-Control-Allow-Methods header.
+func (caps *Capabilities) AllowsCORSMethod(method string) bool {
+	return containsToken(caps.AllowMethods, method)
+}
+
+// Capabilities calls Do with an OPTIONS and parses the Allow and CORS headers of the response.
+func (c *Client) Capabilities(ctx context.Context, url string) (*Capabilities, error) {
+	req, err := http.NewRequest("OPTIONS", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return parseCapabilities(resp), nil
+}
+
+func parseCapabilities(resp *http.Response) *Capabilities {
+	caps := &Capabilities{
+		StatusCode:    resp.StatusCode,
+		Allow:         headerTokens(resp.Header, "Allow"),
+		AllowOrigin:   resp.Header.Get("Access-Control-Allow-Origin"),
+		AllowMethods:  headerTokens(resp.Header, "Access-Control-Allow-Methods"),
+		AllowHeaders:  headerTokens(resp.Header, "Access-Control-Allow-Headers"),
+		ExposeHeaders: headerTokens(resp.Header, "Access-Control-Expose-Headers"),
+	}
+	caps.AllowCredentials = strings.EqualFold(resp.Header.Get("Access-Control-Allow-Credentials"), "true")
+	if maxAge, err := strconv.Atoi(resp.Header.Get("Access-Control-Max-Age")); err == nil && maxAge > 0 {
+		caps.MaxAge = time.Duration(maxAge) * time.Second
+	}
+	return caps
+}
+
+// headerTokens splits all values of the comma-separated header key into trimmed, non-empty tokens.
+func headerTokens(h http.Header, key string) (tokens []string) {
+	for _, value := range h[http.CanonicalHeaderKey(key)] {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+func containsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
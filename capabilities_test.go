@@ -0,0 +1,52 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCapabilities(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "OPTIONS" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Allow", "GET, HEAD,OPTIONS")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Add("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Add("Access-Control-Allow-Methods", "PUT")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Florence-Token")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	Convey("Given a default rchttp client", t, func() {
+		httpClient := NewClient().(*Client)
+
+		Convey("When Capabilities() is called on a URL", func() {
+			caps, err := httpClient.Capabilities(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the Allow and CORS headers are parsed", func() {
+				So(caps.StatusCode, ShouldEqual, http.StatusNoContent)
+				So(caps.Allow, ShouldResemble, []string{"GET", "HEAD", "OPTIONS"})
+				So(caps.Allows("head"), ShouldBeTrue)
+				So(caps.Allows("DELETE"), ShouldBeFalse)
+				So(caps.AllowOrigin, ShouldEqual, "*")
+				So(caps.AllowMethods, ShouldResemble, []string{"GET", "POST", "PUT"})
+				So(caps.AllowsCORSMethod("PUT"), ShouldBeTrue)
+				So(caps.AllowHeaders, ShouldResemble, []string{"Content-Type", "X-Florence-Token"})
+				So(caps.ExposeHeaders, ShouldBeNil)
+				So(caps.AllowCredentials, ShouldBeTrue)
+				So(caps.MaxAge, ShouldEqual, 10*time.Minute)
+			})
+		})
+	})
+}
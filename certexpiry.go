@@ -0,0 +1,98 @@
+package rchttp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCertExpiryWarning is how long before a certificate expires that CheckCertExpiry
+// warns about it, if EnableCertExpiryMonitoring is given zero.
+const DefaultCertExpiryWarning = 14 * 24 * time.Hour
+
+// CertExpiry is the expiry of the leaf certificate last presented by a host.
+type CertExpiry struct {
+	Host     string
+	Subject  string
+	NotAfter time.Time
+}
+
+// ExpiresWithin reports whether the certificate expires within d from now.
+func (e CertExpiry) ExpiresWithin(d time.Duration) bool {
+	return time.Until(e.NotAfter) < d
+}
+
+// CertExpiryError is returned by CheckCertExpiry when certificates are close to expiry.
+type CertExpiryError struct {
+	Expiring []CertExpiry
+}
+
+func (e *CertExpiryError) Error() string {
+	hosts := make([]string, len(e.Expiring))
+	for i, expiry := range e.Expiring {
+		hosts[i] = fmt.Sprintf("%s (%s)", expiry.Host, expiry.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return "rchttp: certificates close to expiry: " + strings.Join(hosts, ", ")
+}
+
+// certMonitor records the leaf certificate expiry of each host.
+type certMonitor struct {
+	mutex      sync.Mutex
+	warnWithin time.Duration
+	expiries   map[string]CertExpiry
+}
+
+// EnableCertExpiryMonitoring makes the client record the expiry of the leaf certificate
+// presented by each host it calls over TLS, reported by Stats and CheckCertExpiry, which
+// warns about certificates expiring within warnWithin (DefaultCertExpiryWarning if zero).
+func (c *Client) EnableCertExpiryMonitoring(warnWithin time.Duration) {
+	if warnWithin <= 0 {
+		warnWithin = DefaultCertExpiryWarning
+	}
+	c.certMonitor = &certMonitor{warnWithin: warnWithin, expiries: make(map[string]CertExpiry)}
+}
+
+// CheckCertExpiry returns a *CertExpiryError if any host called has presented a certificate
+// expiring within the warning period, e.g. to report as a health check warning.
+func (c *Client) CheckCertExpiry() error {
+	if c.certMonitor == nil {
+		return nil
+	}
+	var expiring []CertExpiry
+	for _, expiry := range c.certMonitor.list() {
+		if expiry.ExpiresWithin(c.certMonitor.warnWithin) {
+			expiring = append(expiring, expiry)
+		}
+	}
+	if len(expiring) == 0 {
+		return nil
+	}
+	return &CertExpiryError{Expiring: expiring}
+}
+
+func (m *certMonitor) record(host string, state *tls.ConnectionState) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return
+	}
+	leaf := state.PeerCertificates[0]
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.expiries[host] = CertExpiry{Host: host, Subject: leaf.Subject.CommonName, NotAfter: leaf.NotAfter}
+}
+
+// list returns the recorded expiries, soonest first.
+func (m *certMonitor) list() []CertExpiry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	expiries := make([]CertExpiry, 0, len(m.expiries))
+	for _, expiry := range m.expiries {
+		expiries = append(expiries, expiry)
+	}
+	sort.Slice(expiries, func(i, j int) bool {
+		return expiries[i].NotAfter.Before(expiries[j].NotAfter)
+	})
+	return expiries
+}
@@ -0,0 +1,59 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientCertExpiryMonitoring(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+	host := strings.TrimPrefix(ts.URL, "https://")
+
+	Convey("Given an rchttp client with cert expiry monitoring enabled", t, func() {
+		httpClient := &Client{HTTPClient: ts.Client()}
+		So(httpClient.Stats().CertExpiries, ShouldBeNil)
+		httpClient.EnableCertExpiryMonitoring(0)
+
+		Convey("When it calls hosts over TLS and plain HTTP", func() {
+			for _, url := range []string{ts.URL, plain.URL} {
+				resp, err := httpClient.Get(context.Background(), url)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then the expiry of the TLS host's certificate is recorded", func() {
+				expiries := httpClient.Stats().CertExpiries
+				So(expiries, ShouldHaveLength, 1)
+				So(expiries[0].Host, ShouldEqual, host)
+				So(expiries[0].NotAfter, ShouldEqual, ts.Certificate().NotAfter)
+			})
+
+			Convey("And no warning is given for a certificate far from expiry", func() {
+				So(httpClient.CheckCertExpiry(), ShouldBeNil)
+			})
+		})
+
+		Convey("When the warning period covers the certificate's expiry", func() {
+			httpClient.EnableCertExpiryMonitoring(time.Until(ts.Certificate().NotAfter) + time.Hour)
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then CheckCertExpiry warns about it", func() {
+				err := httpClient.CheckCertExpiry()
+				So(err, ShouldHaveSameTypeAs, &CertExpiryError{})
+				So(err.(*CertExpiryError).Expiring[0].Host, ShouldEqual, host)
+				So(err.Error(), ShouldContainSubstring, host)
+			})
+		})
+	})
+}
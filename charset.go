@@ -0,0 +1,120 @@
+package rchttp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// UnsupportedCharsetError is returned by the JSON/text helpers when the charset of a
+// response body is not one that can be transcoded to UTF-8.
+type UnsupportedCharsetError struct {
+	Charset string
+}
+
+func (e *UnsupportedCharsetError) Error() string {
+	return fmt.Sprintf("rchttp: unsupported response charset %q", e.Charset)
+}
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from ISO-8859-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// charsetDecoders map a byte of a single-byte charset to its rune, by charset name.
+var charsetDecoders = map[string]func(b byte) rune{
+	"iso-8859-1": latin1Rune,
+	"latin1":     latin1Rune,
+	"l1":         latin1Rune,
+	"windows-1252": func(b byte) rune {
+		if b >= 0x80 && b <= 0x9f {
+			return windows1252[b-0x80]
+		}
+		return rune(b)
+	},
+}
+
+func latin1Rune(b byte) rune {
+	return rune(b)
+}
+
+// responseCharset returns the lower-cased charset parameter of contentType, if any.
+func responseCharset(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(params["charset"]))
+}
+
+// charsetDecoder returns the decoder for the charset in contentType, nil if the body is
+// already UTF-8 (no charset, UTF-8 or US-ASCII), or an *UnsupportedCharsetError.
+func charsetDecoder(contentType string) (func(b byte) rune, error) {
+	charset := responseCharset(contentType)
+	switch charset {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return nil, nil
+	}
+	decode, ok := charsetDecoders[charset]
+	if !ok {
+		return nil, &UnsupportedCharsetError{Charset: charset}
+	}
+	return decode, nil
+}
+
+// utf8Reader returns a reader of body transcoded to UTF-8 from the charset in contentType.
+func utf8Reader(contentType string, body io.Reader) (io.Reader, error) {
+	decode, err := charsetDecoder(contentType)
+	if err != nil || decode == nil {
+		return body, err
+	}
+	return &singleByteReader{r: bufio.NewReader(body), decode: decode}, nil
+}
+
+// toUTF8 transcodes body to UTF-8 from the charset in contentType.
+func toUTF8(contentType string, body []byte) ([]byte, error) {
+	decode, err := charsetDecoder(contentType)
+	if err != nil || decode == nil {
+		return body, err
+	}
+	transcoded := make([]byte, 0, len(body))
+	for _, b := range body {
+		transcoded = append(transcoded, string(decode(b))...)
+	}
+	return transcoded, nil
+}
+
+// singleByteReader transcodes a single-byte charset to UTF-8.
+type singleByteReader struct {
+	r      io.ByteReader
+	decode func(b byte) rune
+	buf    [utf8.UTFMax]byte
+	n, off int
+}
+
+func (r *singleByteReader) Read(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		if r.off == r.n {
+			b, err := r.r.ReadByte()
+			if err != nil {
+				if written > 0 {
+					return written, nil
+				}
+				return 0, err
+			}
+			r.n, r.off = utf8.EncodeRune(r.buf[:], r.decode(b)), 0
+		}
+		c := copy(p[written:], r.buf[r.off:r.n])
+		r.off += c
+		written += c
+	}
+	return written, nil
+}
@@ -0,0 +1,81 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCharsetTranscoding(t *testing.T) {
+	Convey("Given bodies in single-byte charsets", t, func() {
+		Convey("Then ISO-8859-1 is transcoded to UTF-8", func() {
+			b, err := toUTF8("text/plain; charset=ISO-8859-1", []byte("caf\xe9 \xa3"))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "café £")
+		})
+
+		Convey("Then Windows-1252 is transcoded to UTF-8", func() {
+			r, err := utf8Reader("text/csv; charset=windows-1252", strings.NewReader("\x80100 \x93q\x94"))
+			So(err, ShouldBeNil)
+			b, _ := ioutil.ReadAll(r)
+			So(string(b), ShouldEqual, "€100 “q”")
+		})
+
+		Convey("Then UTF-8 and unlabelled bodies are unchanged", func() {
+			b, err := toUTF8("application/json; charset=utf-8", []byte("café"))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "café")
+			b, err = toUTF8("", []byte("café"))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "café")
+		})
+
+		Convey("Then unsupported charsets return an UnsupportedCharsetError", func() {
+			_, err := toUTF8("text/plain; charset=Shift_JIS", []byte("x"))
+			So(err, ShouldResemble, &UnsupportedCharsetError{Charset: "shift_jis"})
+		})
+	})
+}
+
+func TestClientGetJSONCharset(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=iso-8859-1")
+		w.Write([]byte("{\"name\":\"Caf\xe9\"}"))
+	}))
+	defer ts.Close()
+
+	Convey("Given a JSON response in ISO-8859-1", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("Then GetJSON decodes it transcoded to UTF-8", func() {
+			var v struct{ Name string }
+			_, err := httpClient.GetJSON(context.Background(), ts.URL, &v)
+			So(err, ShouldBeNil)
+			So(v.Name, ShouldEqual, "Café")
+		})
+
+		Convey("Then GetBytes returns it transcoded to UTF-8", func() {
+			b, _, err := httpClient.GetBytes(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, `{"name":"Café"}`)
+		})
+
+		Convey("Then response transformers see it transcoded, and it is decoded only once", func() {
+			var seen string
+			httpClient.ResponseTransformers = []ResponseTransformer{func(resp *http.Response, body []byte) ([]byte, error) {
+				seen = string(body)
+				return []byte(strings.Replace(seen, "Café", "Crème", 1)), nil
+			}}
+			var v struct{ Name string }
+			_, err := httpClient.GetJSON(context.Background(), ts.URL, &v)
+			So(err, ShouldBeNil)
+			So(seen, ShouldEqual, `{"name":"Café"}`)
+			So(v.Name, ShouldEqual, "Crème")
+		})
+	})
+}
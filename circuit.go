@@ -0,0 +1,173 @@
+package rchttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Defaults for a CircuitBreaker with a zero Threshold or CoolDown.
+const (
+	DefaultCircuitThreshold = 5
+	DefaultCircuitCoolDown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is wrapped by the *CircuitOpenError returned for requests to a host whose
+// circuit is open.
+var ErrCircuitOpen = errors.New("rchttp: circuit open")
+
+// CircuitOpenError is returned, without a request being made, for requests to a host
+// whose circuit is open. Such requests are not retried.
+type CircuitOpenError struct {
+	Host string
+	// RetryAt is when the circuit half-opens to let a request through.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("rchttp: circuit open for %s until %s", e.Host, e.RetryAt.Format(time.RFC3339))
+}
+
+// Unwrap returns ErrCircuitOpen.
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// CircuitState is the state of the circuit for a host.
+type CircuitState int
+
+// The states of a circuit: closed circuits let requests through, open circuits fail them
+// without making them, and half-open circuits let one request through to probe the host.
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitBreaker stops requests being made to a host, by host, once Threshold attempts
+// in a row have failed with an error or a 5xx response, so that a dead dependency is not
+// hammered with retries. While a host's circuit is open its requests fail at once with a
+// *CircuitOpenError; after CoolDown it half-opens and lets one request through, closing
+// again if that succeeds or reopening if it fails.
+type CircuitBreaker struct {
+	// Threshold is the number of failed attempts in a row which opens the circuit,
+	// DefaultCircuitThreshold if zero.
+	Threshold int
+	// CoolDown is how long the circuit stays open, DefaultCircuitCoolDown if zero.
+	CoolDown time.Duration
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker returns a CircuitBreaker which opens a host's circuit after threshold
+// failures in a row, for coolDown.
+func NewCircuitBreaker(threshold int, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, CoolDown: coolDown}
+}
+
+// State returns the state of the circuit for host ("host:port" for URLs with a port).
+func (b *CircuitBreaker) State(host string) CircuitState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if cb, ok := b.circuits[host]; ok {
+		if cb.state == CircuitOpen && !time.Now().Before(cb.openedAt.Add(b.coolDown())) {
+			return CircuitHalfOpen
+		}
+		return cb.state
+	}
+	return CircuitClosed
+}
+
+// allow returns a *CircuitOpenError if an attempt may not be made to host, half-opening
+// the circuit if its cool-down is over. It returns the states the circuit moved between,
+// which are the same if it did not change.
+func (b *CircuitBreaker) allow(host string) (from, to CircuitState, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	cb, ok := b.circuits[host]
+	if !ok {
+		return CircuitClosed, CircuitClosed, nil
+	}
+	from = cb.state
+	switch cb.state {
+	case CircuitOpen:
+		retryAt := cb.openedAt.Add(b.coolDown())
+		if time.Now().Before(retryAt) {
+			return from, cb.state, &CircuitOpenError{Host: host, RetryAt: retryAt}
+		}
+		cb.state, cb.probing = CircuitHalfOpen, true
+	case CircuitHalfOpen:
+		if cb.probing {
+			return from, cb.state, &CircuitOpenError{Host: host, RetryAt: time.Now()}
+		}
+		cb.probing = true
+	}
+	return from, cb.state, nil
+}
+
+// record updates the circuit for host with the outcome of an attempt, returning the states
+// it moved between as allow. Attempts abandoned because ctx is done count as neither
+// success nor failure.
+func (b *CircuitBreaker) record(ctx context.Context, host string, resp *http.Response, err error) (from, to CircuitState) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	cb, ok := b.circuits[host]
+	if !ok {
+		cb = &circuit{}
+		b.circuits[host] = cb
+	}
+
+	from = cb.state
+	if ctx.Err() != nil {
+		cb.probing = false
+		return from, cb.state
+	}
+	if err == nil && resp.StatusCode < 500 {
+		delete(b.circuits, host)
+		return from, CircuitClosed
+	}
+	cb.failures++
+	if cb.state == CircuitHalfOpen || cb.failures >= b.threshold() {
+		cb.state, cb.openedAt, cb.probing = CircuitOpen, time.Now(), false
+	}
+	return from, cb.state
+}
+
+func (b *CircuitBreaker) threshold() int {
+	if b.Threshold <= 0 {
+		return DefaultCircuitThreshold
+	}
+	return b.Threshold
+}
+
+func (b *CircuitBreaker) coolDown() time.Duration {
+	if b.CoolDown <= 0 {
+		return DefaultCircuitCoolDown
+	}
+	return b.CoolDown
+}
@@ -0,0 +1,109 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var status, calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+	host := ts.Listener.Addr().String()
+
+	Convey("Given an rchttp client with a circuit breaker and a failing server", t, func() {
+		atomic.StoreInt32(&status, http.StatusInternalServerError)
+		atomic.StoreInt32(&calls, 0)
+		breaker := NewCircuitBreaker(3, 50*time.Millisecond)
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 10, RetryTime: time.Millisecond, Jitter: JitterNone, CircuitBreaker: breaker}
+
+		Convey("When a request keeps failing", func() {
+			_, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the circuit opens and the request stops being retried", func() {
+				var open *CircuitOpenError
+				So(errors.As(err, &open), ShouldBeTrue)
+				So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+				So(open.Host, ShouldEqual, host)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+				So(breaker.State(host), ShouldEqual, CircuitOpen)
+			})
+
+			Convey("And further requests fail without being made", func() {
+				_, err := httpClient.Get(context.Background(), ts.URL)
+				So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+			})
+
+			Convey("And after the cool-down a successful request closes the circuit", func() {
+				time.Sleep(60 * time.Millisecond)
+				So(breaker.State(host), ShouldEqual, CircuitHalfOpen)
+				atomic.StoreInt32(&status, http.StatusOK)
+				resp, err := httpClient.Get(context.Background(), ts.URL)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(breaker.State(host), ShouldEqual, CircuitClosed)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 4)
+			})
+
+			Convey("And after the cool-down a failed request reopens the circuit", func() {
+				time.Sleep(60 * time.Millisecond)
+				_, err := httpClient.Get(context.Background(), ts.URL)
+				So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+				So(breaker.State(host), ShouldEqual, CircuitOpen)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 4)
+			})
+		})
+
+		Convey("When events are enabled and the circuit opens, half-opens and closes", func() {
+			httpClient.EnableEvents(20)
+			httpClient.Get(context.Background(), ts.URL)
+			time.Sleep(60 * time.Millisecond)
+			atomic.StoreInt32(&status, http.StatusOK)
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then an event is emitted for each transition", func() {
+				var transitions [][2]CircuitState
+				for len(httpClient.Events()) > 0 {
+					if event := <-httpClient.Events(); event.Type == EventCircuitTransition {
+						So(event.URL, ShouldEqual, ts.URL)
+						transitions = append(transitions, [2]CircuitState{event.From, event.To})
+					}
+				}
+				So(transitions, ShouldResemble, [][2]CircuitState{
+					{CircuitClosed, CircuitOpen},
+					{CircuitOpen, CircuitHalfOpen},
+					{CircuitHalfOpen, CircuitClosed},
+				})
+			})
+		})
+
+		Convey("When requests fail fewer times in a row than the threshold", func() {
+			httpClient.MaxRetries = 1
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			atomic.StoreInt32(&status, http.StatusOK)
+			resp, err = httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the circuit stays closed", func() {
+				So(breaker.State(host), ShouldEqual, CircuitClosed)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+			})
+		})
+	})
+}
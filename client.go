@@ -3,6 +3,7 @@ package rchttp
 //go:generate moq -out mock_client.go . Clienter
 
 import (
+	"errors"
 	"io"
 	"math"
 	"math/rand"
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ONSdigital/go-ns/common"
@@ -24,6 +26,234 @@ type Client struct {
 	RetryTime          time.Duration
 	PathsWithNoRetries map[string]bool
 	HTTPClient         *http.Client
+
+	// MaxCorrelationIDSegments and MaxCorrelationIDLength bound the chained correlation ID
+	// header ("id1,id2,...") sent downstream; zero means unbounded.
+	MaxCorrelationIDSegments int
+	MaxCorrelationIDLength   int
+
+	// MaxHeaderBytes, if positive, makes Do reject requests whose headers (before any
+	// credentials are added) are larger than this, with a *HeaderTooLargeError, rather than
+	// sending them to proxies which would reject them with an opaque 431 or 400.
+	MaxHeaderBytes int
+
+	// ExactCaseHeaders are header names which are sent with exactly the casing given,
+	// rather than canonicalised by net/http (e.g. "x-legacy-TOKEN"), for downstreams which
+	// require it. Headers should be set as usual (e.g. with Header.Set) on requests. The
+	// casing is lost over HTTP/2, where header names are always lower case.
+	ExactCaseHeaders []string
+
+	// BufferRequestBodies makes Do read request bodies that cannot otherwise be
+	// replayed (e.g. streams of unknown length) into memory, so that they can be retried,
+	// whatever their size.
+	BufferRequestBodies bool
+
+	// MaxBufferedBodySize is the most bytes of a request body that cannot otherwise be
+	// replayed which Do reads into memory, when the request may be retried, so that it can
+	// be, DefaultMaxBufferedBodySize if zero. Larger bodies are streamed and the request is
+	// not retried, failing with a *BodyTooLargeError instead. If negative, bodies are only
+	// buffered when BufferRequestBodies is set.
+	MaxBufferedBodySize int64
+
+	// ChunkedUploader, if set, is called with a PUT or POST request (with a fresh copy of
+	// its body) which was rejected with 413 Payload Too Large, to send its body in parts
+	// that the downstream accepts, and its response is returned instead of the 413. If it
+	// fails, a *PayloadTooLargeError is returned. Requests whose bodies cannot be replayed
+	// are not handed to it.
+	ChunkedUploader func(ctx context.Context, req *http.Request) (*http.Response, error)
+
+	// RequestTransformers are applied in order to the body of every request before it is
+	// sent, so that changes between API versions can be shimmed in one place.
+	RequestTransformers []RequestTransformer
+	// ResponseTransformers are applied in order to the body of every successful response
+	// read by the helper methods, before it is decoded.
+	ResponseTransformers []ResponseTransformer
+
+	// SendDeadlineHeader adds the X-Deadline header, holding the context deadline, to requests.
+	SendDeadlineHeader bool
+
+	// ErrorBodySnapshotSize, if set, makes Do return a *RequestError holding up to this
+	// many bytes of the request and response bodies when a request fails permanently.
+	// The snapshots are redacted by ErrorBodyRedactor, or RedactBody if that is nil.
+	ErrorBodySnapshotSize int
+	ErrorBodyRedactor     func([]byte) []byte
+
+	// PropagatedCookies is the allowlist of cookie names which are copied from the
+	// context (see WithCookies) onto outbound requests.
+	PropagatedCookies []string
+
+	// PropagatedBaggage is the allowlist of W3C baggage keys which are forwarded from the
+	// context (see WithBaggage) onto outbound requests, in a header of at most MaxBaggageSize
+	// bytes (DefaultMaxBaggageSize if zero).
+	PropagatedBaggage []string
+	MaxBaggageSize    int
+
+	// HostHeader, if set, is presented as the Host header of requests (see also WithHostHeader).
+	HostHeader string
+
+	// BasicAuth, if set, is used to authenticate requests which do not already have an
+	// Authorization header (see also WithBasicAuth).
+	BasicAuth *BasicAuth
+
+	// TokenSource, if set, supplies a bearer token for every attempt of requests which do
+	// not already have an Authorization header (see also WithTokenSource). Wrap it with
+	// ReuseTokenSource to cache tokens until they expire.
+	TokenSource TokenSource
+
+	// AuthRefresher, if set, is called once when a request is rejected with a 401, after
+	// which the request is tried again (see SetAuthRefresher).
+	AuthRefresher func(ctx context.Context) error
+
+	// OnBehalfOfSigningKey, if set, makes requests carry the identity they are made on
+	// behalf of (the user from the context, or see WithOnBehalfOf) in the X-On-Behalf-Of
+	// header, signed with this key (see VerifyOnBehalfOf).
+	OnBehalfOfSigningKey []byte
+
+	// Cache, if set, caches successful GET responses.
+	Cache *Cache
+
+	// CallInfoHeaders is the allowlist of response headers returned in CallInfo by the
+	// JSON/bytes helpers, DefaultCallInfoHeaders if nil.
+	CallInfoHeaders []string
+
+	// Decoders are the decoders used by GetNegotiated, keyed by media type, DefaultDecoders if nil.
+	Decoders map[string]Decoder
+
+	// Codec encodes and decodes bodies for GetAs and PostAs, JSONCodec if nil.
+	Codec Codec
+
+	// Presets are named sets of request options, used by requests made with WithPreset
+	// (see RegisterPreset).
+	Presets map[string]Preset
+
+	// ProgressInterval is the least time between calls to a progress callback set with
+	// WithProgress, DefaultProgressInterval if zero.
+	ProgressInterval time.Duration
+
+	// SpoolThreshold is the size above which Download spools a response body to a
+	// temporary file rather than holding it in memory, DefaultSpoolThreshold if zero.
+	SpoolThreshold int64
+
+	// ContextDecorator, if set, is applied to the context of every call before anything
+	// else (see SetContextDecorator).
+	ContextDecorator func(ctx context.Context) context.Context
+
+	// MaxRetryTime, if set, caps each sleep between retries, which otherwise doubles with
+	// each retry. It does not shorten sleeps requested by Retry-After (see HonorRetryAfter).
+	MaxRetryTime time.Duration
+
+	// RetryDrainLimit is the most bytes of a response body read, so that its connection
+	// can be reused, before it is closed for a retry, DefaultRetryDrainLimit if zero. If
+	// negative, bodies are closed without being read.
+	RetryDrainLimit int64
+
+	// Jitter selects how the sleep between retries is randomised, JitterFixed by default.
+	Jitter JitterMode
+
+	// BackoffFunc, if set, returns the sleep before each retry (the first being attempt 1),
+	// replacing the exponential backoff of RetryTime and Jitter. Sleeps are still capped by
+	// MaxRetryTime and replaced by Retry-After if HonorRetryAfter is set.
+	BackoffFunc func(attempt int) time.Duration
+
+	// MaxElapsedTime, if set, limits the total time spent on a request including its
+	// retries and the sleeps between them: no retry is started which would begin after it,
+	// and the last response or error is returned instead.
+	MaxElapsedTime time.Duration
+
+	// ErrorOnRetriesExhausted makes Do return an *ErrMaxRetriesExceeded when a request still
+	// gets a retryable response (e.g. a 503) after all of its retries, as it does when the
+	// last attempt fails with an error. The last response is returned with it either way,
+	// so that the caller can read the server's error payload (up to DefaultErrorBodyLimit
+	// bytes of it; responses returned with an error are already closed).
+	ErrorOnRetriesExhausted bool
+
+	// NonIdempotentRetries makes requests with methods which are not idempotent, e.g. POST,
+	// be retried like any other. See AllowNonIdempotentRetries.
+	NonIdempotentRetries bool
+
+	// MinAttemptTime is the least time an attempt is expected to take,
+	// DefaultMinAttemptTime if zero. A retry is not started if the context's deadline
+	// would pass before its sleep and MinAttemptTime are over; the call fails at once
+	// with a *TimeoutError matching ErrCallerDeadline, and the last response, instead.
+	MinAttemptTime time.Duration
+
+	// AttemptTimeoutEscalation, if greater than 1, multiplies the timeout of the attempts
+	// made after each attempt which times out, up to MaxAttemptTimeout (if set), for
+	// downstreams which are slow on cold caches rather than broken.
+	AttemptTimeoutEscalation float64
+	MaxAttemptTimeout        time.Duration
+
+	// RetryPolicy decides which errors and responses are retried, DefaultRetryPolicy if nil.
+	RetryPolicy RetryPolicy
+	// RetryableStatusCodes, if not nil, replaces the status codes retried under the default
+	// RetryPolicy (5xx and 409), e.g. []int{429, 460, 500, 502, 503, 504}. Errors are still retried.
+	RetryableStatusCodes []int
+	// HonorRetryAfter makes the client wait for the time given by the Retry-After header of
+	// a 429 or 503 response before retrying, instead of the exponential backoff, and retry
+	// 429 responses with a Retry-After header under the default RetryPolicy.
+	HonorRetryAfter bool
+
+	// ContractRecorder, if set, writes every request and its response to a file, e.g. to
+	// generate contracts from integration tests.
+	ContractRecorder *ContractRecorder
+
+	// OpenAPIValidator, if set, validates every request and its response against the
+	// downstream's OpenAPI spec, failing with an *OpenAPIViolationError if they do not match.
+	// It is intended to catch drift in non-production environments.
+	OpenAPIValidator *OpenAPIValidator
+
+	// TenancyGuard, if set, requires requests to its hosts to carry a tenant (see WithTenant).
+	TenancyGuard *TenancyGuard
+
+	// HashRing, if set, routes requests for relative URLs (with no host) across several
+	// base URLs by consistent hashing (see WithRoutingKey).
+	HashRing *HashRing
+
+	// CircuitBreaker, if set, fails requests to hosts which keep failing at once, without
+	// making or retrying them, until they have had time to recover.
+	CircuitBreaker *CircuitBreaker
+
+	// RetryBudget, if set, limits the retries made through the client to a share of its
+	// requests, shedding retries under a widespread outage.
+	RetryBudget *RetryBudget
+
+	// Hedging, if set, sends a second copy of GET requests which are slow to respond, and
+	// uses whichever response comes first.
+	Hedging *HedgePolicy
+
+	// ShouldRetryFeature, if set, is consulted before retrying each request and can veto
+	// retries to a host, e.g. from a feature flag so that operators can stop retries to a
+	// struggling downstream during an incident. It takes precedence over WithBackoff.
+	ShouldRetryFeature func(ctx context.Context, host string) bool
+
+	// OnRetry, if set, is called before the sleep ahead of each retry, e.g. to log or count
+	// the retries to each downstream, with the number of the retry about to be made
+	// (starting at 1) and the response or error of the attempt being retried. The
+	// response's body is drained and closed after OnRetry returns.
+	OnRetry func(ctx context.Context, req *http.Request, attempt int, resp *http.Response, err error)
+
+	// MaxDeferredRequests, if positive, is the most deferred requests (see Defer) which may
+	// be pending at once, beyond which Defer returns ErrDeferredQueueFull.
+	MaxDeferredRequests int
+	// OnDeferredError, if set, is called with the error of each deferred request which
+	// fails, which is a *StatusError for one with a non-2xx response.
+	OnDeferredError func(req *http.Request, err error)
+
+	tlsServerNames map[string]string
+	transport      *http.Transport
+	events         *eventBus
+	rateLimitPacer *rateLimitPacer
+	hostRateLimits *hostRateLimiter
+	deferred       *deferredQueue
+	connGauge      *connGauge
+	connHooks      *ConnectionHooks
+	dial           func(ctx context.Context, network, addr string) (net.Conn, error)
+	resolver       *cachingResolver
+	hostDialers    map[string]func(ctx context.Context, network, addr string) (net.Conn, error)
+	certMonitor    *certMonitor
+	shutdown       *shutdownTracker
+	bulkhead       *bulkhead
+	config         atomic.Value
 }
 
 // DefaultClient is a go-ns specific http client with sensible timeouts,
@@ -87,23 +317,41 @@ func ClientWithListOfNonRetriablePaths(c Clienter, paths []string) Clienter {
 	return c
 }
 
-// SetTimeout sets HTTP request timeout.
+// SetTimeout sets HTTP request timeout. Once a config has been applied (see ApplyConfig)
+// it updates the config, and may be called while the client is in use; otherwise it gives
+// the client its own copy of HTTPClient with the timeout, leaving any shared one unchanged.
 func (c *Client) SetTimeout(timeout time.Duration) {
-	c.HTTPClient.Timeout = timeout
+	if c.updateConfig(func(cfg *Config) { cfg.Timeout = timeout }) {
+		return
+	}
+	httpClient := *c.HTTPClient
+	httpClient.Timeout = timeout
+	c.HTTPClient = &httpClient
 }
 
 // GetMaxRetries gets the HTTP request maximum number of retries.
 func (c *Client) GetMaxRetries() int {
+	if live := c.liveConfig(); live != nil {
+		return live.maxRetries
+	}
 	return c.MaxRetries
 }
 
 // SetMaxRetries sets HTTP request maximum number of retries.
 func (c *Client) SetMaxRetries(maxRetries int) {
-	c.MaxRetries = maxRetries
+	if !c.updateConfig(func(cfg *Config) { cfg.MaxRetries = maxRetries }) {
+		c.MaxRetries = maxRetries
+	}
 }
 
 // GetPathsWithNoRetries gets a list of paths that will HTTP request will not retry on error.
 func (c *Client) GetPathsWithNoRetries() (paths []string) {
+	if live := c.liveConfig(); live != nil {
+		for path := range live.pathsWithNoRetries {
+			paths = append(paths, path)
+		}
+		return paths
+	}
 	for path, _ := range c.PathsWithNoRetries {
 		paths = append(paths, path)
 	}
@@ -116,11 +364,22 @@ func (c *Client) SetPathsWithNoRetries(paths []string) {
 	for _, path := range paths {
 		mapPath[path] = true
 	}
-	c.PathsWithNoRetries = mapPath
+	if !c.updateConfig(func(cfg *Config) { cfg.PathsWithNoRetries = paths }) {
+		c.PathsWithNoRetries = mapPath
+	}
 }
 
 // Do calls ctxhttp.Do with the addition of retries with exponential backoff
 func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.ContextDecorator != nil {
+		ctx = c.ContextDecorator(ctx)
+	}
+	c.route(ctx, req)
+	if err := c.applyPresetHeaders(ctx, req); err != nil {
+		return nil, err
+	}
+	ctx = traceInformational(ctx)
+	ctx = c.traceConnectionReuse(ctx, req)
 
 	// TODO: Remove this once user token (Florence token) is propegated throughout apps
 	// Used for audit purposes
@@ -131,43 +390,249 @@ func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, err
 		}
 	}
 
-	// get any existing correlation-id (might be "id1,id2"), append a new one, add to headers
-	upstreamCorrelationIDs := common.GetRequestId(ctx)
-	addedIDLen := 20
-	if upstreamCorrelationIDs != "" {
-		// get length of (first of) IDs (e.g. "id1" is 3), new ID will be half that size
-		addedIDLen = len(upstreamCorrelationIDs) / 2
-		if commaPosition := strings.Index(upstreamCorrelationIDs, ","); commaPosition > 1 {
-			addedIDLen = commaPosition / 2
+	c.addCorrelationID(ctx, req)
+	c.addCookies(ctx, req)
+	c.addBaggage(ctx, req)
+	c.applyHostHeader(ctx, req)
+	if c.SendDeadlineHeader {
+		addDeadlineHeader(ctx, req)
+	}
+	if len(c.OnBehalfOfSigningKey) > 0 {
+		addOnBehalfOf(ctx, req, c.OnBehalfOfSigningKey)
+	}
+
+	if err := c.enforceTenancy(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := c.checkHeaderSize(req); err != nil {
+		return nil, err
+	}
+	if c.OpenAPIValidator != nil {
+		if err := c.OpenAPIValidator.ValidateRequest(req); err != nil {
+			return nil, err
 		}
-		upstreamCorrelationIDs += ","
 	}
-	common.AddRequestIdHeader(req, upstreamCorrelationIDs+common.NewRequestID(addedIDLen))
 
-	doer := func(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
-		if req.ContentLength > 0 {
+	var finish func()
+	if c.shutdown != nil {
+		var err error
+		if ctx, finish, err = c.shutdown.track(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if c.bulkhead != nil {
+		release, err := c.bulkhead.acquire(ctx)
+		if err != nil {
+			if finish != nil {
+				finish()
+			}
+			return nil, err
+		}
+		if untrack := finish; untrack != nil {
+			finish = func() {
+				release()
+				untrack()
+			}
+		} else {
+			finish = release
+		}
+	}
+
+	countFanOut(ctx, req)
+
+	call := callName(ctx)
+	c.emit(EventRequestStart, req, func(event *Event) {
+		event.Call = call
+	})
+	start := time.Now()
+
+	var resp *http.Response
+	var err error
+	if c.Cache != nil {
+		var cacheReq *http.Request
+		if cacheReq, err = c.withResolvedAuth(ctx, req); err == nil {
+			resp, err = c.Cache.do(ctx, cacheReq, func() (*http.Response, error) {
+				return c.send(ctx, req)
+			})
+		}
+	} else {
+		resp, err = c.send(ctx, req)
+	}
+
+	err = classifyTimeout(ctx, err)
+
+	c.emit(EventRequestFinish, req, func(event *Event) {
+		outcome(resp, err)(event)
+		event.Call = call
+		event.Duration = time.Since(start)
+	})
+	if c.ContractRecorder != nil && resp != nil {
+		c.ContractRecorder.record(req, resp)
+	}
+	if c.OpenAPIValidator != nil && err == nil {
+		err = c.OpenAPIValidator.ValidateResponse(req, resp)
+	}
+	if resp != nil && err != nil {
+		// callers rarely close the body of a response returned with an error, so it is
+		// closed here, releasing its connection and any slot held for the request
+		closeErrorBody(resp)
+	} else if resp != nil {
+		resp.Body = c.reportProgress(ctx, resp.Body, resp.ContentLength)
+	}
+	if finish != nil {
+		if resp != nil && err == nil {
+			resp.Body = &finishOnClose{ReadCloser: resp.Body, finish: finish}
+		} else {
+			finish()
+		}
+	}
+	return resp, err
+}
+
+// send makes the request, with any retries, once its headers have been set up by Do.
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := c.transformRequestBody(req); err != nil {
+		return nil, err
+	}
+	if c.RetryBudget != nil {
+		c.RetryBudget.deposit()
+	}
+	retry := c.retriesEnabled(ctx, req)
+	if !retry && c.canSendDirectly(ctx) {
+		return c.sendDirectly(ctx, req)
+	}
+	replayable, err := c.prepareBody(req, retry)
+	if err != nil {
+		return nil, err
+	}
+
+	explicitAuth := req.Header.Get("Authorization") != ""
+	timeouts := 0
+	attempt := func(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+		// each attempt is made with a fresh copy of the request, so that changes made to it
+		// by one attempt (e.g. auth headers) do not carry over to the next
+		req = req.Clone(ctx)
+		if !explicitAuth {
+			if err := c.applyAuth(ctx, req); err != nil {
+				return nil, err
+			}
+		}
+		if req.GetBody != nil {
 			var err error
 			req.Body, err = req.GetBody()
 			if err != nil {
 				return nil, err
 			}
 		}
-		return ctxhttp.Do(ctx, client, req)
+		req.Body = c.reportProgress(ctx, req.Body, req.ContentLength)
+		client = c.escalateTimeout(c.attemptClient(ctx, client), timeouts)
+		if c.rateLimitPacer != nil {
+			if err := c.rateLimitPacer.wait(ctx, req.URL.Host); err != nil {
+				return nil, err
+			}
+		}
+		if limiter := c.rateLimiter(); limiter != nil {
+			if err := limiter.wait(ctx, req.URL.Host); err != nil {
+				return nil, err
+			}
+		}
+		c.applyHeaderCasing(req)
+		if c.CircuitBreaker != nil {
+			from, to, err := c.CircuitBreaker.allow(req.URL.Host)
+			c.emitCircuitTransition(req, from, to)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var resp *http.Response
+		var err error
+		if c.Hedging != nil && hedgeable(req) {
+			resp, err = c.Hedging.do(ctx, client, req)
+		} else {
+			resp, err = ctxhttp.Do(ctx, client, req)
+		}
+		if c.CircuitBreaker != nil {
+			from, to := c.CircuitBreaker.record(ctx, req.URL.Host, resp, err)
+			c.emitCircuitTransition(req, from, to)
+		}
+		if isAttemptTimeout(ctx, err) {
+			timeouts++
+		}
+		if err == nil {
+			if c.rateLimitPacer != nil {
+				c.rateLimitPacer.update(req.URL.Host, resp)
+			}
+			if c.certMonitor != nil {
+				c.certMonitor.record(req.URL.Host, resp.TLS)
+			}
+		}
+		return resp, resourceExhausted(err)
 	}
 
-	path := req.URL.Path
+	// on the first 401, refresh credentials and try again once
+	refreshedAuth := false
+	doer := func(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+		resp, err := attempt(ctx, client, req)
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && !refreshedAuth && replayable {
+			refreshedAuth = true
+			retry, refreshErr := c.refreshAuth(ctx, hasExplicitAuth(req))
+			if refreshErr != nil || retry {
+				resp.Body.Close()
+			}
+			if refreshErr != nil {
+				return nil, refreshErr
+			}
+			if retry {
+				resp, err = attempt(ctx, client, req)
+			}
+		}
+		if err == nil {
+			err = possiblyCreated(req, resp)
+		}
+		return resp, err
+	}
 
+	start := time.Now()
 	resp, err := doer(ctx, c.HTTPClient, req)
-	if !c.PathsWithNoRetries[path] && c.GetMaxRetries() > 0 && wantRetry(err, resp) {
-		return c.backoff(ctx, doer, c.HTTPClient, req)
+	if retry && c.shouldRetry(ctx, resp, err, 1) {
+		if !replayable && c.maxBufferedBodySize() >= 0 {
+			return resp, &BodyTooLargeError{Method: req.Method, URL: req.URL.String(), Limit: c.maxBufferedBodySize(), Err: err}
+		}
+		if !replayable {
+			return resp, &NonReplayableBodyError{Method: req.Method, URL: req.URL.String(), Err: err}
+		}
+		resp, err = c.backoff(ctx, doer, c.HTTPClient, req, resp, err, start)
+	}
+	if c.ChunkedUploader != nil && replayable {
+		resp, err = c.uploadInChunks(ctx, req, resp, err)
 	}
 
+	if c.ErrorBodySnapshotSize > 0 && wantRetry(err, resp) {
+		return c.snapshotFailure(req, resp, err)
+	}
 	return resp, err
 }
 
+// retriesEnabled reports whether req may be retried, taking into account its method, the
+// ShouldRetryFeature hook and any per-request override in the context.
+func (c *Client) retriesEnabled(ctx context.Context, req *http.Request) bool {
+	if c.maxRetries(ctx) <= 0 || !c.retryableMethod(ctx, req) {
+		return false
+	}
+	u := req.URL
+	if c.ShouldRetryFeature != nil && !c.ShouldRetryFeature(ctx, u.Host) {
+		return false
+	}
+	if enabled, ok := backoffOverride(ctx); ok {
+		return enabled
+	}
+	return !c.isPathWithNoRetries(u.Path)
+}
+
 func wantRetry(err error, resp *http.Response) bool {
 	if err != nil {
-		return true
+		var exhausted *ResourceExhaustedError
+		return !errors.As(err, &exhausted)
 	}
 	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusConflict {
 		return true
@@ -222,40 +687,90 @@ func (c *Client) PostForm(ctx context.Context, uri string, data url.Values) (*ht
 	return c.Post(ctx, uri, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
 }
 
+// Doer makes one attempt at a request with the given context and HTTP client.
 type Doer = func(context.Context, *http.Client, *http.Request) (*http.Response, error)
 
+// backoff retries req with doer, after the first attempt (made at start) gave resp and
+// err, sleeping between attempts, until an attempt should not be retried or the retries
+// run out, and returns the outcome of the last attempt.
 func (c *Client) backoff(
 	ctx context.Context,
 	doer Doer,
 	client *http.Client,
 	req *http.Request,
-) (resp *http.Response, err error) {
-
-	for retries := 1; retries <= c.GetMaxRetries(); retries++ {
-		pingChan := make(chan struct{}, 0)
-		go func() {
-			time.Sleep(getSleepTime(retries, c.RetryTime))
-			close(pingChan)
-		}()
+	resp *http.Response,
+	err error,
+	start time.Time,
+) (*http.Response, error) {
+	maxRetries, retryTime := c.maxRetries(ctx), c.retryTime(ctx)
+	prevSleep := retryTime
+	lastStatusCode := 0
+	if resp != nil {
+		lastStatusCode = resp.StatusCode
+	}
+	for retries := 1; retries <= maxRetries; retries++ {
+		sleepTime := c.sleepTime(ctx, retries, retryTime, prevSleep)
+		prevSleep = sleepTime
+		if after, ok := c.retryAfterDelay(resp); ok {
+			sleepTime = after
+		}
+		if c.MaxElapsedTime > 0 && time.Since(start)+sleepTime > c.MaxElapsedTime {
+			return resp, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < sleepTime+c.minAttemptTime() {
+			return resp, &TimeoutError{Reason: ErrCallerDeadline, Err: context.DeadlineExceeded}
+		}
+		if c.RetryBudget != nil && !c.RetryBudget.withdraw() {
+			return resp, err
+		}
+
+		c.emit(EventRetry, req, func(event *Event) {
+			outcome(resp, err)(event)
+			event.Attempt = retries
+			event.Reason = retryReason(resp, err)
+			event.Call = callName(ctx)
+		})
+		if c.OnRetry != nil {
+			c.OnRetry(ctx, req, retries, resp, err)
+		}
+		c.drainForRetry(resp)
+
 		// check for first of: context cancellation or sleep ends
+		timer := time.NewTimer(sleepTime)
 		select {
-		case <-pingChan:
+		case <-timer.C:
 		case <-ctx.Done():
-			err = ctx.Err()
-			return
+			timer.Stop()
+			return nil, ctx.Err()
 		}
 
 		resp, err = doer(ctx, client, req)
+		if resp != nil {
+			lastStatusCode = resp.StatusCode
+		}
 		// prioritise any context cancellation
 		if ctx.Err() != nil {
-			err = ctx.Err()
-			return
+			return resp, ctx.Err()
 		}
-		if !wantRetry(err, resp) {
-			return
+		if !c.shouldRetry(ctx, resp, err, retries+1) {
+			return resp, err
 		}
 	}
-	return
+	if err != nil || c.ErrorOnRetriesExhausted {
+		lastErr := err
+		if lastErr == nil {
+			lastErr = &StatusError{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode}
+		}
+		err = &ErrMaxRetriesExceeded{
+			Method:         req.Method,
+			URL:            req.URL.String(),
+			Attempts:       maxRetries + 1,
+			Elapsed:        time.Since(start),
+			LastStatusCode: lastStatusCode,
+			Err:            lastErr,
+		}
+	}
+	return resp, err
 }
 
 // getSleepTime will return a sleep time based on the attempt and initial retry time.
@@ -105,7 +105,8 @@ func TestClientDoesRetry(t *testing.T) {
 
 	Convey("Given an rchttp client with small client timeout", t, func() {
 		// force client to abandon requests before the requested one second delay on the (next) server response
-		httpClient := ClientWithTimeout(nil, 100*time.Millisecond)
+		httpClient := ClientWithTimeout(nil, 100*time.Millisecond).(*Client)
+		httpClient.AllowNonIdempotentRetries(true)
 
 		Convey("When Post() is called on a URL with a delay on the first response", func() {
 			delayByOneSecondOnNext := delayByOneSecondOn(expectedCallCount + 1)
@@ -160,6 +161,30 @@ func TestClientDoesRetryAndContextCancellation(t *testing.T) {
 	})
 }
 
+func TestClientContextCancelledDuringBackoff(t *testing.T) {
+	ts := rchttptest.NewTestServer(http.StatusInternalServerError)
+	defer ts.Close()
+
+	Convey("Given an rchttp client sleeping for seconds between retries", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 1, RetryTime: 5 * time.Second, Jitter: JitterNone}
+
+		Convey("When the context is cancelled during the sleep", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			start := time.Now()
+			resp, err := httpClient.Get(ctx, ts.URL)
+
+			Convey("Then the call returns immediately with the context's error", func() {
+				So(resp, ShouldBeNil)
+				So(err, ShouldEqual, context.Canceled)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
 func TestClientDoesRetryAndContextTimeout(t *testing.T) {
 	ts := rchttptest.NewTestServer(200)
 	defer ts.Close()
@@ -167,12 +192,14 @@ func TestClientDoesRetryAndContextTimeout(t *testing.T) {
 
 	Convey("Given an rchttp client with small client timeout", t, func() {
 		// force client to abandon requests before the requested one second delay on the (next) server response
-		httpClient := ClientWithTimeout(nil, 500*time.Millisecond)
+		httpClient := ClientWithTimeout(nil, 500*time.Millisecond).(*Client)
+		httpClient.AllowNonIdempotentRetries(true)
 		Convey("When Post() is called on a URL with a delay on the first response", func() {
 			delayByOneSecondOnNext := delayByOneSecondOn(expectedCallCount + 1)
 			expectedCallCount++
 
-			ctx, _ := context.WithTimeout(context.Background(), time.Duration(200*time.Millisecond))
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(200*time.Millisecond))
+			defer cancel()
 
 			resp, err := httpClient.Post(ctx, ts.URL, rchttptest.JsonContentType, strings.NewReader(delayByOneSecondOnNext))
 			So(err, ShouldNotBeNil)
@@ -389,3 +416,39 @@ func unmarshallResp(resp *http.Response) (*rchttptest.Responder, error) {
 	}
 	return responder, err
 }
+
+func TestClientClonesRequestPerAttempt(t *testing.T) {
+	Convey("Given an rchttp client with a token source retrying a failing transport", t, func() {
+		var attempts []*http.Request
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts = append(attempts, req)
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody, Request: req}, nil
+		})
+		httpClient := &Client{
+			HTTPClient:  &http.Client{Transport: transport},
+			MaxRetries:  2,
+			RetryTime:   time.Millisecond,
+			TokenSource: &countingTokenSource{},
+		}
+
+		Convey("When a request is made", func() {
+			req, _ := http.NewRequest("PUT", "http://localhost/datasets", strings.NewReader(`{}`))
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then each attempt is made with a fresh copy of the request", func() {
+				So(attempts, ShouldHaveLength, 3)
+				So(attempts[0] != req, ShouldBeTrue)
+				So(attempts[1] != attempts[0], ShouldBeTrue)
+				So(attempts[0].Header["Authorization"], ShouldResemble, []string{"Bearer tok1"})
+				So(attempts[2].Header["Authorization"], ShouldResemble, []string{"Bearer tok3"})
+				So(attempts[2].Header[common.RequestHeaderKey], ShouldHaveLength, 1)
+			})
+
+			Convey("And the caller's request is not changed by the attempts", func() {
+				So(req.Header.Get("Authorization"), ShouldEqual, "")
+			})
+		})
+	})
+}
@@ -0,0 +1,100 @@
+package rchttp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Codec encodes request bodies and decodes response bodies in one media type.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Built-in codecs, which are registered by default.
+var (
+	JSONCodec Codec = jsonCodec{}
+	XMLCodec  Codec = xmlCodec{}
+)
+
+var (
+	codecsMutex sync.RWMutex
+	codecs      = map[string]Codec{
+		JSONCodec.ContentType(): JSONCodec,
+		XMLCodec.ContentType():  XMLCodec,
+	}
+)
+
+// RegisterCodec registers codec (e.g. for protobuf or msgpack) for its content type, so
+// that GetAs and PostAs can decode responses of that type.
+func RegisterCodec(codec Codec) {
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	codecs[codec.ContentType()] = codec
+}
+
+// CodecFor returns the codec registered for the media type of contentType, if any.
+func CodecFor(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+	codec, ok := codecs[mediaType]
+	return codec, ok
+}
+
+// GetAs calls Get, accepting the client's Codec (JSONCodec if nil), and decodes the
+// response body into v with the codec registered for its Content-Type, or the client's
+// Codec if there is none.
+func (c *Client) GetAs(ctx context.Context, url string, v interface{}) (*CallInfo, error) {
+	return c.doCodec(ctx, "GET", url, c.codec(), true, nil, v)
+}
+
+// PostAs calls Post with body encoded with the client's Codec (JSONCodec if nil), and
+// decodes the response body into v (unless v is nil) as GetAs. As PostJSON, it is not
+// retried unless non-idempotent retries are allowed.
+func (c *Client) PostAs(ctx context.Context, url string, body, v interface{}) (*CallInfo, error) {
+	return c.doCodec(ctx, "POST", url, c.codec(), true, body, v)
+}
+
+func (c *Client) codec() Codec {
+	if c.Codec != nil {
+		return c.Codec
+	}
+	return JSONCodec
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string {
+	return "application/xml"
+}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
@@ -0,0 +1,86 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// csvLineCodec is a test codec encoding a []string as one comma-separated line.
+type csvLineCodec struct{}
+
+func (csvLineCodec) ContentType() string { return "application/x-csv-line" }
+func (csvLineCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.Join(v.([]string), ",")), nil
+}
+func (csvLineCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*[]string) = strings.Split(string(data), ",")
+	return nil
+}
+
+func TestClientCodecs(t *testing.T) {
+	var accept, contentType, received string
+	var respContentType, respBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept, contentType = r.Header.Get("Accept"), r.Header.Get("Content-Type")
+		b, _ := ioutil.ReadAll(r.Body)
+		received = string(b)
+		w.Header().Set("Content-Type", respContentType)
+		w.Write([]byte(respBody))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with the default codec", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("When GetAs receives JSON", func() {
+			respContentType, respBody = "application/json", `{"id":"cpih"}`
+			var v struct {
+				ID string `json:"id" xml:"id"`
+			}
+			_, err := httpClient.GetAs(context.Background(), ts.URL, &v)
+
+			Convey("Then JSON is requested and decoded", func() {
+				So(err, ShouldBeNil)
+				So(accept, ShouldEqual, "application/json")
+				So(v.ID, ShouldEqual, "cpih")
+			})
+		})
+
+		Convey("When GetAs receives XML", func() {
+			respContentType, respBody = "application/xml", `<dataset><id>cpih</id></dataset>`
+			var v struct {
+				ID string `json:"id" xml:"id"`
+			}
+			_, err := httpClient.GetAs(context.Background(), ts.URL, &v)
+
+			Convey("Then the registered XML codec decodes it", func() {
+				So(err, ShouldBeNil)
+				So(v.ID, ShouldEqual, "cpih")
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with a registered custom codec", t, func() {
+		RegisterCodec(csvLineCodec{})
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, Codec: csvLineCodec{}}
+
+		Convey("When PostAs is called", func() {
+			respContentType, respBody = "application/x-csv-line", "c,d"
+			var v []string
+			_, err := httpClient.PostAs(context.Background(), ts.URL, []string{"a", "b"}, &v)
+
+			Convey("Then the body is encoded and decoded with the codec", func() {
+				So(err, ShouldBeNil)
+				So(contentType, ShouldEqual, "application/x-csv-line")
+				So(received, ShouldEqual, "a,b")
+				So(v, ShouldResemble, []string{"c", "d"})
+			})
+		})
+	})
+}
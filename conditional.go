@@ -0,0 +1,52 @@
+package rchttp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// FetchIfChanged fetches the body of url only if its ETag is no longer lastETag, returning
+// the body and the new ETag with changed set, or just lastETag if it is unchanged. It first
+// makes a cheap HEAD request, then a GET with If-None-Match, so that it works both with
+// servers which only support one of them. A blank lastETag always fetches the body.
+func (c *Client) FetchIfChanged(ctx context.Context, url, lastETag string) (body []byte, newETag string, changed bool, err error) {
+	if lastETag != "" {
+		req, err := http.NewRequest("HEAD", url, nil)
+		if err != nil {
+			return nil, "", false, err
+		}
+		resp, err := c.Do(ctx, req)
+		if err != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, "", false, err
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 && resp.Header.Get("ETag") == lastETag {
+			return nil, lastETag, false, nil
+		}
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if lastETag != "" {
+		req.Header.Set("If-None-Match", lastETag)
+	}
+	body, info, err := c.doBytes(ctx, req)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotModified {
+			return nil, lastETag, false, nil
+		}
+		return nil, "", false, err
+	}
+	return body, info.etag, true, nil
+}
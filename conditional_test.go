@@ -0,0 +1,95 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientFetchIfChanged(t *testing.T) {
+	etag := `"v1"`
+	var methods []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("ETag", etag)
+		if r.Method == "GET" && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("config " + etag))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client polling a config document", t, func() {
+		methods = nil
+		etag = `"v1"`
+		httpClient := &Client{HTTPClient: &http.Client{}, CallInfoHeaders: []string{}}
+
+		Convey("When it has no previous ETag", func() {
+			body, newETag, changed, err := httpClient.FetchIfChanged(context.Background(), ts.URL, "")
+
+			Convey("Then the body is fetched with a single GET", func() {
+				So(err, ShouldBeNil)
+				So(changed, ShouldBeTrue)
+				So(string(body), ShouldEqual, `config "v1"`)
+				So(newETag, ShouldEqual, `"v1"`)
+				So(methods, ShouldResemble, []string{"GET"})
+			})
+		})
+
+		Convey("When the document has not changed", func() {
+			body, newETag, changed, err := httpClient.FetchIfChanged(context.Background(), ts.URL, `"v1"`)
+
+			Convey("Then only a HEAD request is made", func() {
+				So(err, ShouldBeNil)
+				So(changed, ShouldBeFalse)
+				So(body, ShouldBeNil)
+				So(newETag, ShouldEqual, `"v1"`)
+				So(methods, ShouldResemble, []string{"HEAD"})
+			})
+		})
+
+		Convey("When the document has changed", func() {
+			etag = `"v2"`
+			body, newETag, changed, err := httpClient.FetchIfChanged(context.Background(), ts.URL, `"v1"`)
+
+			Convey("Then the new body and ETag are returned", func() {
+				So(err, ShouldBeNil)
+				So(changed, ShouldBeTrue)
+				So(string(body), ShouldEqual, `config "v2"`)
+				So(newETag, ShouldEqual, `"v2"`)
+				So(methods, ShouldResemble, []string{"HEAD", "GET"})
+			})
+		})
+	})
+
+	Convey("Given a server which answers HEAD without an ETag but supports conditional GETs", t, func() {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "HEAD" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Write([]byte("config"))
+		}))
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{}}
+
+		Convey("When the document has not changed", func() {
+			body, newETag, changed, err := httpClient.FetchIfChanged(context.Background(), ts.URL, `"v1"`)
+
+			Convey("Then the 304 from the conditional GET reports it unchanged", func() {
+				So(err, ShouldBeNil)
+				So(changed, ShouldBeFalse)
+				So(body, ShouldBeNil)
+				So(newETag, ShouldEqual, `"v1"`)
+			})
+		})
+	})
+}
@@ -0,0 +1,175 @@
+package rchttp
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Config holds the settings of a Client which ApplyConfig can change while it is in use.
+type Config struct {
+	// Timeout is the timeout of each attempt, as HTTPClient.Timeout.
+	Timeout            time.Duration
+	MaxRetries         int
+	RetryTime          time.Duration
+	PathsWithNoRetries []string
+	// HostRateLimits, if not nil, replaces the limits set with SetHostRateLimit, by host.
+	HostRateLimits map[string]HostRateLimit
+}
+
+// HostRateLimit is the rate limit of requests to a host (see SetHostRateLimit).
+type HostRateLimit struct {
+	RPS   float64
+	Burst int
+}
+
+// liveConfig is an applied Config, which is never modified once stored.
+type liveConfig struct {
+	timeout            time.Duration
+	maxRetries         int
+	retryTime          time.Duration
+	pathsWithNoRetries map[string]bool
+	hostRateLimits     *hostRateLimiter
+}
+
+// ApplyConfig atomically replaces the timeout, retry settings and host rate limits of a
+// client which may be in use, e.g. from a feature-flag or configuration service, without a
+// restart. Once a config has been applied it takes precedence over the MaxRetries,
+// RetryTime and PathsWithNoRetries fields and HTTPClient.Timeout, and the Set methods
+// update it. Host rate limits are only replaced if cfg.HostRateLimits is not nil, and
+// start with a full burst.
+func (c *Client) ApplyConfig(cfg Config) {
+	for {
+		prev := c.liveConfig()
+		if c.swapConfig(prev, c.newLiveConfig(cfg, prev)) {
+			return
+		}
+	}
+}
+
+// newLiveConfig returns cfg as a liveConfig, keeping the host rate limits of prev (or of
+// the client) unless cfg replaces them.
+func (c *Client) newLiveConfig(cfg Config, prev *liveConfig) *liveConfig {
+	live := &liveConfig{
+		timeout:            cfg.Timeout,
+		maxRetries:         cfg.MaxRetries,
+		retryTime:          cfg.RetryTime,
+		pathsWithNoRetries: make(map[string]bool),
+		hostRateLimits:     c.hostRateLimits,
+	}
+	for _, path := range cfg.PathsWithNoRetries {
+		live.pathsWithNoRetries[path] = true
+	}
+	if prev != nil {
+		live.hostRateLimits = prev.hostRateLimits
+	}
+	if cfg.HostRateLimits != nil {
+		live.hostRateLimits = newHostRateLimiter()
+		for host, limit := range cfg.HostRateLimits {
+			live.hostRateLimits.set(host, limit.RPS, limit.Burst)
+		}
+	}
+	return live
+}
+
+// swapConfig stores next as the applied config if prev still is, reporting whether it did.
+func (c *Client) swapConfig(prev, next *liveConfig) bool {
+	if prev == nil {
+		return c.config.CompareAndSwap(nil, next)
+	}
+	return c.config.CompareAndSwap(prev, next)
+}
+
+// Config returns the client's current settings.
+func (c *Client) Config() Config {
+	var cfg Config
+	if live := c.liveConfig(); live != nil {
+		cfg = live.config()
+	} else {
+		cfg = Config{
+			Timeout:            c.HTTPClient.Timeout,
+			MaxRetries:         c.MaxRetries,
+			RetryTime:          c.RetryTime,
+			PathsWithNoRetries: c.GetPathsWithNoRetries(),
+		}
+	}
+	if limiter := c.rateLimiter(); limiter != nil {
+		cfg.HostRateLimits = limiter.limits()
+	}
+	return cfg
+}
+
+func (live *liveConfig) config() Config {
+	cfg := Config{Timeout: live.timeout, MaxRetries: live.maxRetries, RetryTime: live.retryTime}
+	for path := range live.pathsWithNoRetries {
+		cfg.PathsWithNoRetries = append(cfg.PathsWithNoRetries, path)
+	}
+	return cfg
+}
+
+// updateConfig applies a modified copy of the current config, reporting false if no
+// config has been applied. Concurrent updates are retried rather than lost.
+func (c *Client) updateConfig(update func(cfg *Config)) bool {
+	for {
+		prev := c.liveConfig()
+		if prev == nil {
+			return false
+		}
+		cfg := prev.config()
+		update(&cfg)
+		if c.swapConfig(prev, c.newLiveConfig(cfg, prev)) {
+			return true
+		}
+	}
+}
+
+func (c *Client) liveConfig() *liveConfig {
+	live, _ := c.config.Load().(*liveConfig)
+	return live
+}
+
+// retryTime returns the retry time for a request made with ctx.
+// rateLimiter returns the host rate limits of the applied config or else the client.
+func (c *Client) rateLimiter() *hostRateLimiter {
+	if live := c.liveConfig(); live != nil && live.hostRateLimits != nil {
+		return live.hostRateLimits
+	}
+	return c.hostRateLimits
+}
+
+func (c *Client) retryTime(ctx context.Context) time.Duration {
+	if opts := callOptions(ctx); opts != nil && opts.RetryTime > 0 {
+		return opts.RetryTime
+	}
+	if live := c.liveConfig(); live != nil {
+		return live.retryTime
+	}
+	return c.RetryTime
+}
+
+func (c *Client) isPathWithNoRetries(path string) bool {
+	if live := c.liveConfig(); live != nil {
+		return live.pathsWithNoRetries[path]
+	}
+	return c.PathsWithNoRetries[path]
+}
+
+// attemptClient returns client with the timeout from the preset or options in the context
+// or the applied config, if any.
+func (c *Client) attemptClient(ctx context.Context, client *http.Client) *http.Client {
+	timeout := client.Timeout
+	if preset, _ := c.preset(ctx); preset != nil && preset.Timeout > 0 {
+		timeout = preset.Timeout
+	} else if opts := callOptions(ctx); opts != nil && opts.Timeout > 0 {
+		timeout = opts.Timeout
+	} else if live := c.liveConfig(); live != nil {
+		timeout = live.timeout
+	}
+	if timeout == client.Timeout {
+		return client
+	}
+	withTimeout := *client
+	withTimeout.Timeout = timeout
+	return &withTimeout
+}
@@ -0,0 +1,157 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientApplyConfig(t *testing.T) {
+	Convey("Given an rchttp client with retries and a server which always fails", t, func() {
+		ts := rchttptest.NewTestServer(500)
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 1, RetryTime: time.Millisecond}
+
+		Convey("When a config with more retries is applied", func() {
+			httpClient.ApplyConfig(Config{MaxRetries: 3, RetryTime: time.Millisecond})
+			_, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then requests use the new retry settings", func() {
+				So(ts.GetCalls(0), ShouldEqual, 4)
+				So(httpClient.GetMaxRetries(), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When a config with no retries for a path is applied", func() {
+			httpClient.ApplyConfig(Config{MaxRetries: 3, PathsWithNoRetries: []string{"/health"}})
+			_, err := httpClient.Get(context.Background(), ts.URL+"/health")
+			So(err, ShouldBeNil)
+
+			Convey("Then the path is not retried", func() {
+				So(ts.GetCalls(0), ShouldEqual, 1)
+				So(httpClient.Config().PathsWithNoRetries, ShouldResemble, []string{"/health"})
+			})
+		})
+
+		Convey("When a Set method is called after a config is applied", func() {
+			httpClient.ApplyConfig(Config{MaxRetries: 3})
+			httpClient.SetMaxRetries(0)
+
+			Convey("Then the applied config is updated", func() {
+				So(httpClient.Config().MaxRetries, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When Set methods are called concurrently after a config is applied", func() {
+			httpClient.ApplyConfig(Config{MaxRetries: 3})
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					httpClient.SetTimeout(time.Duration(i+1) * time.Millisecond)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					httpClient.SetMaxRetries(i + 1)
+				}
+			}()
+			wg.Wait()
+
+			Convey("Then no update is lost", func() {
+				So(httpClient.Config().Timeout, ShouldEqual, 100*time.Millisecond)
+				So(httpClient.Config().MaxRetries, ShouldEqual, 100)
+			})
+		})
+
+		Convey("When a config with host rate limits is applied", func() {
+			tsURL, _ := url.Parse(ts.URL)
+			host := tsURL.Host
+			httpClient.ApplyConfig(Config{HostRateLimits: map[string]HostRateLimit{host: {RPS: 20, Burst: 1}}})
+			start := time.Now()
+			for i := 0; i < 3; i++ {
+				httpClient.Get(WithBackoff(context.Background(), false), ts.URL)
+			}
+
+			Convey("Then requests to the host are limited", func() {
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 90*time.Millisecond)
+				So(httpClient.Config().HostRateLimits, ShouldResemble, map[string]HostRateLimit{host: {RPS: 20, Burst: 1}})
+			})
+
+			Convey("And a later config without limits keeps them", func() {
+				httpClient.ApplyConfig(Config{MaxRetries: 1})
+				So(httpClient.Config().HostRateLimits, ShouldResemble, map[string]HostRateLimit{host: {RPS: 20, Burst: 1}})
+			})
+
+			Convey("And a later config with empty limits removes them", func() {
+				httpClient.ApplyConfig(Config{HostRateLimits: map[string]HostRateLimit{}})
+				So(httpClient.Config().HostRateLimits, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given an rchttp client and a slow server", t, func() {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer ts.Close()
+		shared := &http.Client{}
+		httpClient := &Client{HTTPClient: shared}
+
+		Convey("When a config with a short timeout is applied", func() {
+			httpClient.ApplyConfig(Config{Timeout: 50 * time.Millisecond})
+			_, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the request times out without modifying the HTTP client", func() {
+				So(err, ShouldNotBeNil)
+				So(shared.Timeout, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the timeout is set without a config applied", func() {
+			httpClient.SetTimeout(50 * time.Millisecond)
+			_, err := httpClient.Get(WithBackoff(context.Background(), false), ts.URL)
+
+			Convey("Then the request times out without modifying the shared HTTP client", func() {
+				So(err, ShouldNotBeNil)
+				So(shared.Timeout, ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given a client in use", t, func() {
+		ts := rchttptest.NewTestServer(200)
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{}}
+
+		Convey("Then configs can be applied concurrently with requests", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(2)
+				go func(i int) {
+					defer wg.Done()
+					httpClient.ApplyConfig(Config{MaxRetries: i, Timeout: time.Second})
+				}(i)
+				go func() {
+					defer wg.Done()
+					resp, err := httpClient.Get(context.Background(), ts.URL)
+					if err == nil {
+						resp.Body.Close()
+					}
+				}()
+			}
+			wg.Wait()
+			So(ts.GetCalls(0), ShouldEqual, 10)
+		})
+	})
+}
@@ -0,0 +1,98 @@
+package rchttp
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ConnectionEvent describes a connection made by the client's transport.
+type ConnectionEvent struct {
+	// Host is the "host:port" address the connection was made to.
+	Host       string
+	LocalAddr  string
+	RemoteAddr string
+	// IdleTime is how long a reused connection had been idle (Reused only).
+	IdleTime time.Duration
+	// Lifetime is how long the connection had been open (Closed only).
+	Lifetime time.Duration
+}
+
+// ConnectionHooks are called as the client's transport establishes, reuses and closes
+// connections, so that tools can observe connection behaviour per host. Any hook may be nil.
+// Hooks are called synchronously, so must not block.
+type ConnectionHooks struct {
+	Established func(event ConnectionEvent)
+	Reused      func(event ConnectionEvent)
+	Closed      func(event ConnectionEvent)
+}
+
+// SetConnectionHooks sets the hooks called as connections are established, reused and
+// closed. It applies to connections made after it is called.
+func (c *Client) SetConnectionHooks(hooks ConnectionHooks) error {
+	transport, err := c.cloneTransport()
+	if err != nil {
+		return err
+	}
+	c.connHooks = &hooks
+	c.setDialContext(transport, c.baseDial(transport))
+	c.setTransport(transport)
+	return nil
+}
+
+func (hooks *ConnectionHooks) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		established := time.Now()
+		event := ConnectionEvent{Host: addr, LocalAddr: conn.LocalAddr().String(), RemoteAddr: conn.RemoteAddr().String()}
+		if hooks.Established != nil {
+			hooks.Established(event)
+		}
+		if hooks.Closed == nil {
+			return conn, nil
+		}
+		return &closeNotifyConn{Conn: conn, onClose: func() {
+			event.Lifetime = time.Since(established)
+			hooks.Closed(event)
+		}}, nil
+	}
+}
+
+// traceConnectionReuse adds a client trace to ctx calling the Reused connection hook, if set.
+func (c *Client) traceConnectionReuse(ctx context.Context, req *http.Request) context.Context {
+	if c.connHooks == nil || c.connHooks.Reused == nil {
+		return ctx
+	}
+	reused := c.connHooks.Reused
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				return
+			}
+			reused(ConnectionEvent{
+				Host:       canonicalAddr(req),
+				LocalAddr:  info.Conn.LocalAddr().String(),
+				RemoteAddr: info.Conn.RemoteAddr().String(),
+				IdleTime:   info.IdleTime,
+			})
+		},
+	})
+}
+
+// canonicalAddr returns the "host:port" address of req's URL, as dialled by the transport.
+func canonicalAddr(req *http.Request) string {
+	if port := req.URL.Port(); port != "" {
+		return net.JoinHostPort(req.URL.Hostname(), port)
+	}
+	port := "80"
+	if req.URL.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(req.URL.Hostname(), port)
+}
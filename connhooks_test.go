@@ -0,0 +1,70 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientConnectionHooks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	Convey("Given an rchttp client with connection hooks", t, func() {
+		var mutex sync.Mutex
+		var established, reused, closed []ConnectionEvent
+		record := func(events *[]ConnectionEvent) func(ConnectionEvent) {
+			return func(event ConnectionEvent) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				*events = append(*events, event)
+			}
+		}
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		So(httpClient.EnableConnectionGauge(), ShouldBeNil)
+		So(httpClient.SetConnectionHooks(ConnectionHooks{
+			Established: record(&established),
+			Reused:      record(&reused),
+			Closed:      record(&closed),
+		}), ShouldBeNil)
+
+		Convey("When two requests are made over a kept-alive connection which is then closed", func() {
+			for i := 0; i < 2; i++ {
+				resp, err := httpClient.Get(context.Background(), ts.URL)
+				So(err, ShouldBeNil)
+				ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			httpClient.HTTPClient.Transport.(*http.Transport).CloseIdleConnections()
+			So(waitFor(func() bool {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return len(closed) == 1
+			}), ShouldBeTrue)
+
+			Convey("Then the connection is reported established, reused and closed for the host", func() {
+				mutex.Lock()
+				defer mutex.Unlock()
+				So(established, ShouldHaveLength, 1)
+				So(established[0].Host, ShouldEqual, host)
+				So(established[0].RemoteAddr, ShouldEqual, host)
+				So(reused, ShouldHaveLength, 1)
+				So(reused[0].Host, ShouldEqual, host)
+				So(reused[0].LocalAddr, ShouldEqual, established[0].LocalAddr)
+				So(closed[0].Host, ShouldEqual, host)
+				So(closed[0].Lifetime, ShouldBeGreaterThan, 0)
+			})
+
+			Convey("And the connection gauge still counts connections", func() {
+				So(httpClient.OpenConnections(), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package rchttp
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+type contextKey string
+
+const (
+	backoffKey    = contextKey("rchttp-backoff")
+	hostHeaderKey = contextKey("rchttp-host-header")
+)
+
+// WithBackoff returns a context which overrides whether requests made with it are retried
+// with exponential backoff. When enabled is false requests fail fast after the first attempt
+// (e.g. for health checks); when true they are retried even if their path is in
+// PathsWithNoRetries, up to the client's MaxRetries.
+func WithBackoff(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, backoffKey, enabled)
+}
+
+func backoffOverride(ctx context.Context) (enabled, ok bool) {
+	enabled, ok = ctx.Value(backoffKey).(bool)
+	return
+}
+
+// WithHostHeader returns a context which makes requests made with it present host as their
+// Host header, e.g. when calling a service via an IP address or internal load balancer.
+// It takes precedence over the client's HostHeader.
+func WithHostHeader(ctx context.Context, host string) context.Context {
+	return context.WithValue(ctx, hostHeaderKey, host)
+}
+
+// applyHostHeader sets the Host of req from the context or client, unless it was set explicitly.
+func (c *Client) applyHostHeader(ctx context.Context, req *http.Request) {
+	if req.Host != "" && req.Host != req.URL.Host {
+		return
+	}
+	if host, ok := ctx.Value(hostHeaderKey).(string); ok && host != "" {
+		req.Host = host
+	} else if c.HostHeader != "" {
+		req.Host = c.HostHeader
+	}
+}
+
+// SetContextDecorator sets a function applied to the context of every call before anything
+// else, so that deadlines, trace baggage or tenancy values can be added in one place
+// rather than at every call site.
+func (c *Client) SetContextDecorator(decorator func(ctx context.Context) context.Context) {
+	c.ContextDecorator = decorator
+}
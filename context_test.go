@@ -0,0 +1,155 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithBackoff(t *testing.T) {
+	Convey("Given an rchttp client with retries and a server which always fails", t, func() {
+		ts := rchttptest.NewTestServer(500)
+		defer ts.Close()
+
+		httpClient := ClientWithTimeout(nil, 5*time.Second)
+		httpClient.SetMaxRetries(2)
+
+		Convey("When Get() is called with backoff disabled in the context", func() {
+			resp, err := httpClient.Get(WithBackoff(context.Background(), false), ts.URL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 500)
+
+			Convey("Then the server sees one GET call", func() {
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When Get() is called with backoff forced on a path with no retries", func() {
+			path := "/flaky"
+			httpClient.SetPathsWithNoRetries([]string{path})
+			resp, err := httpClient.Get(WithBackoff(context.Background(), true), ts.URL+path)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 500)
+
+			Convey("Then the server sees the call and every retry", func() {
+				So(ts.GetCalls(0), ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func TestWithHostHeader(t *testing.T) {
+	Convey("Given an rchttp client with a default host header", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, HostHeader: "client.example.com"}
+
+		Convey("When a request is made without a host in the context", func() {
+			req, _ := http.NewRequest("GET", "http://10.0.0.1:8080/datasets", nil)
+			httpClient.applyHostHeader(context.Background(), req)
+
+			Convey("Then the client host header is used", func() {
+				So(req.Host, ShouldEqual, "client.example.com")
+			})
+		})
+
+		Convey("When a request is made with a host in the context", func() {
+			req, _ := http.NewRequest("GET", "http://10.0.0.1:8080/datasets", nil)
+			httpClient.applyHostHeader(WithHostHeader(context.Background(), "api.example.com"), req)
+
+			Convey("Then the context host header is used", func() {
+				So(req.Host, ShouldEqual, "api.example.com")
+			})
+		})
+
+		Convey("When a request has its host set explicitly", func() {
+			req, _ := http.NewRequest("GET", "http://10.0.0.1:8080/datasets", nil)
+			req.Host = "explicit.example.com"
+			httpClient.applyHostHeader(WithHostHeader(context.Background(), "api.example.com"), req)
+
+			Convey("Then the explicit host is kept", func() {
+				So(req.Host, ShouldEqual, "explicit.example.com")
+			})
+		})
+	})
+
+	Convey("Given a test server", t, func() {
+		var seenHost string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seenHost = r.Host
+		}))
+		defer ts.Close()
+
+		Convey("When Get() is called with a host in the context", func() {
+			resp, err := NewClient().Get(WithHostHeader(context.Background(), "api.example.com"), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the server sees that host", func() {
+				So(seenHost, ShouldEqual, "api.example.com")
+			})
+		})
+	})
+}
+
+func TestSetContextDecorator(t *testing.T) {
+	var host string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with a context decorator", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+		httpClient.SetContextDecorator(func(ctx context.Context) context.Context {
+			return WithHostHeader(ctx, "decorated.internal")
+		})
+
+		Convey("When Get() is called with a plain context", func() {
+			_, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the request is made with the decorated context", func() {
+				So(host, ShouldEqual, "decorated.internal")
+			})
+		})
+	})
+}
+
+func TestShouldRetryFeature(t *testing.T) {
+	Convey("Given an rchttp client with retries and a server which always fails", t, func() {
+		ts := rchttptest.NewTestServer(500)
+		defer ts.Close()
+
+		var hosts []string
+		disabled := false
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 2, RetryTime: time.Millisecond}
+		httpClient.ShouldRetryFeature = func(ctx context.Context, host string) bool {
+			hosts = append(hosts, host)
+			return !disabled
+		}
+
+		Convey("When the feature flag allows retries", func() {
+			_, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the request is retried and the hook sees the host", func() {
+				So(ts.GetCalls(0), ShouldEqual, 3)
+				So(hosts, ShouldResemble, []string{ts.Server.Listener.Addr().String()})
+			})
+		})
+
+		Convey("When the feature flag disables retries, even with backoff forced", func() {
+			disabled = true
+			_, err := httpClient.Get(WithBackoff(context.Background(), true), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the request is not retried", func() {
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,147 @@
+package rchttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// redactedHeaders are the headers whose values are not written by a ContractRecorder.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Florence-Token"}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ContractRecorder writes each request made by a client, with its response, to a JSON file
+// in Dir, e.g. during integration tests so that consumer-driven contracts can be generated
+// for the downstream APIs called. Credentials in headers and sensitive body fields (see
+// RedactBody) are redacted. Request bodies are only recorded if they can be replayed (see
+// BufferRequestBodies).
+type ContractRecorder struct {
+	Dir string
+	// OnError, if set, is called with any error recording an interaction. Recording
+	// errors never fail the request.
+	OnError func(err error)
+
+	seq uint64
+}
+
+// ContractInteraction is a request and its response, as written by a ContractRecorder.
+type ContractInteraction struct {
+	Request  ContractRequest  `json:"request"`
+	Response ContractResponse `json:"response"`
+}
+
+// ContractRequest is the recorded part of a request.
+type ContractRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   map[string][]string `json:"query,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    json.RawMessage     `json:"body,omitempty"`
+}
+
+// ContractResponse is the recorded part of a response.
+type ContractResponse struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    json.RawMessage     `json:"body,omitempty"`
+}
+
+// NewContractRecorder returns a ContractRecorder writing to dir, which is created if need be.
+func NewContractRecorder(dir string) (*ContractRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &ContractRecorder{Dir: dir}, nil
+}
+
+// record writes req and resp to a new file, leaving the body of resp to be read again.
+func (r *ContractRecorder) record(req *http.Request, resp *http.Response) {
+	if err := r.write(req, resp); err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+}
+
+func (r *ContractRecorder) write(req *http.Request, resp *http.Response) error {
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		return err
+	}
+
+	interaction := ContractInteraction{
+		Request: ContractRequest{
+			Method:  req.Method,
+			Path:    req.URL.Path,
+			Headers: contractHeaders(req.Header),
+		},
+		Response: ContractResponse{
+			Status:  resp.StatusCode,
+			Headers: contractHeaders(resp.Header),
+			Body:    contractBody(resp.Header.Get("Content-Type"), respBody),
+		},
+	}
+	if query := req.URL.Query(); len(query) > 0 {
+		interaction.Request.Query = query
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+		interaction.Request.Body = contractBody(req.Header.Get("Content-Type"), b)
+	}
+
+	b, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	seq := atomic.AddUint64(&r.seq, 1)
+	name := fmt.Sprintf("%04d-%s-%s.json", seq, req.Method, strings.Trim(unsafeFileChars.ReplaceAllString(req.URL.Path, "_"), "_"))
+	return ioutil.WriteFile(filepath.Join(r.Dir, name), b, 0644)
+}
+
+// contractHeaders returns a copy of h with credentials redacted.
+func contractHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	headers := make(map[string][]string, len(h))
+	for key, values := range h {
+		headers[key] = values
+	}
+	for _, key := range redactedHeaders {
+		if _, ok := headers[key]; ok {
+			headers[key] = []string{"[REDACTED]"}
+		}
+	}
+	return headers
+}
+
+// contractBody returns b, redacted, as JSON if it is a JSON document, or else as a JSON string.
+func contractBody(contentType string, b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	b = RedactBody(b)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && json.Valid(b) {
+		return b
+	}
+	s, _ := json.Marshal(string(b))
+	return s
+}
@@ -0,0 +1,69 @@
+package rchttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientContractRecorder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"1","token":"abc"}`))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client recording contracts to a directory", t, func() {
+		dir := filepath.Join(t.TempDir(), "contracts")
+		recorder, err := NewContractRecorder(dir)
+		So(err, ShouldBeNil)
+		httpClient := &Client{HTTPClient: &http.Client{}, ContractRecorder: recorder}
+
+		Convey("When a request is made", func() {
+			req, _ := http.NewRequest("POST", ts.URL+"/filters?submitted=true", strings.NewReader(`{"dataset":"cpih","password":"hunter2"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer secret")
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then the caller can still read the response", func() {
+				So(string(body), ShouldEqual, `{"id":"1","token":"abc"}`)
+			})
+
+			Convey("And the sanitized interaction is written to a file", func() {
+				files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+				So(err, ShouldBeNil)
+				So(files, ShouldHaveLength, 1)
+				So(filepath.Base(files[0]), ShouldEqual, "0001-POST-filters.json")
+
+				b, _ := ioutil.ReadFile(files[0])
+				var interaction ContractInteraction
+				So(json.Unmarshal(b, &interaction), ShouldBeNil)
+				So(interaction.Request.Method, ShouldEqual, "POST")
+				So(interaction.Request.Path, ShouldEqual, "/filters")
+				So(interaction.Request.Query, ShouldResemble, map[string][]string{"submitted": {"true"}})
+				So(interaction.Request.Headers["Authorization"], ShouldResemble, []string{"[REDACTED]"})
+				So(compactJSON(interaction.Request.Body), ShouldEqual, `{"dataset":"cpih","password":"[REDACTED]"}`)
+				So(interaction.Response.Status, ShouldEqual, http.StatusCreated)
+				So(compactJSON(interaction.Response.Body), ShouldEqual, `{"id":"1","token":"[REDACTED]"}`)
+			})
+		})
+	})
+}
+
+func compactJSON(b []byte) string {
+	var buf bytes.Buffer
+	json.Compact(&buf, b)
+	return buf.String()
+}
@@ -0,0 +1,59 @@
+package rchttp
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+const cookiesKey = contextKey("rchttp-cookies")
+
+// WithCookies returns a context carrying cookies to be added to outbound requests made
+// with it. Only cookies named in the client's PropagatedCookies are sent.
+func WithCookies(ctx context.Context, cookies ...*http.Cookie) context.Context {
+	existing := cookiesFromContext(ctx)
+	all := make([]*http.Cookie, 0, len(existing)+len(cookies))
+	all = append(all, existing...)
+	all = append(all, cookies...)
+	return context.WithValue(ctx, cookiesKey, all)
+}
+
+// WithCookiesFromRequest returns a context carrying the cookies of an inbound request,
+// to be added to outbound requests made with it (subject to the client's PropagatedCookies).
+func WithCookiesFromRequest(ctx context.Context, r *http.Request) context.Context {
+	return WithCookies(ctx, r.Cookies()...)
+}
+
+func cookiesFromContext(ctx context.Context) []*http.Cookie {
+	cookies, _ := ctx.Value(cookiesKey).([]*http.Cookie)
+	return cookies
+}
+
+// addCookies adds the allowlisted cookies in the context to req. Cookies already set on
+// the request are left alone, and cookies marked Secure are only sent over https.
+func (c *Client) addCookies(ctx context.Context, req *http.Request) {
+	if len(c.PropagatedCookies) == 0 {
+		return
+	}
+	for _, cookie := range cookiesFromContext(ctx) {
+		if !c.isPropagatedCookie(cookie.Name) {
+			continue
+		}
+		if cookie.Secure && req.URL.Scheme != "https" {
+			continue
+		}
+		if _, err := req.Cookie(cookie.Name); err == nil {
+			continue
+		}
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+}
+
+func (c *Client) isPropagatedCookie(name string) bool {
+	for _, allowed := range c.PropagatedCookies {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientPropagatesCookies(t *testing.T) {
+	ts := rchttptest.NewTestServer(200)
+	defer ts.Close()
+
+	Convey("Given an rchttp client which propagates the access_token cookie", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, PropagatedCookies: []string{"access_token", "lang"}}
+
+		inbound, err := http.NewRequest("GET", "http://localhost", nil)
+		So(err, ShouldBeNil)
+		inbound.AddCookie(&http.Cookie{Name: "access_token", Value: "tok123"})
+		inbound.AddCookie(&http.Cookie{Name: "session", Value: "private"})
+		ctx := WithCookiesFromRequest(context.Background(), inbound)
+		ctx = WithCookies(ctx, &http.Cookie{Name: "lang", Value: "cy", Secure: true})
+
+		Convey("When Get() is called over http with cookies in the context", func() {
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then only the allowlisted, non-secure cookie is sent", func() {
+				So(call.Headers["Cookie"], ShouldResemble, []string{"access_token=tok123"})
+			})
+		})
+
+		Convey("When the request already has the cookie set", func() {
+			req, err := http.NewRequest("GET", ts.URL, nil)
+			So(err, ShouldBeNil)
+			req.AddCookie(&http.Cookie{Name: "access_token", Value: "explicit"})
+
+			resp, err := httpClient.Do(ctx, req)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the explicit cookie is not overridden", func() {
+				So(call.Headers["Cookie"], ShouldResemble, []string{"access_token=explicit"})
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with no propagated cookies", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("When Get() is called with cookies in the context", func() {
+			resp, err := httpClient.Get(WithCookies(context.Background(), &http.Cookie{Name: "access_token", Value: "tok123"}), ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then no cookies are sent", func() {
+				So(call.Headers["Cookie"], ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,107 @@
+package rchttp
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/go-ns/common"
+	"golang.org/x/net/context"
+)
+
+// DeadlineHeaderKey is the header holding the absolute deadline of the caller's context.
+const DeadlineHeaderKey = "X-Deadline"
+
+// maxCorrelationIDSegmentLength is the longest upstream correlation ID segment forwarded;
+// longer segments are replaced.
+const maxCorrelationIDSegmentLength = 64
+
+const noCorrelationChainingKey = contextKey("rchttp-no-correlation-chaining")
+
+// WithoutCorrelationChaining returns a context which stops the client from appending a new
+// segment to the correlation ID for requests made with it. Any upstream correlation ID is
+// forwarded as it is, other than being limited by MaxCorrelationIDSegments and
+// MaxCorrelationIDLength. Intended for high-frequency calls such as health checks and polls.
+func WithoutCorrelationChaining(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCorrelationChainingKey, true)
+}
+
+func isCorrelationChainingDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noCorrelationChainingKey).(bool)
+	return disabled
+}
+
+// addCorrelationID gets any existing correlation-id (might be "id1,id2"), appends a new one
+// (unless chaining is disabled), and adds the result to the request headers.
+func (c *Client) addCorrelationID(ctx context.Context, req *http.Request) {
+	upstreamCorrelationIDs := normaliseCorrelationIDs(common.GetRequestId(ctx))
+	if isCorrelationChainingDisabled(ctx) {
+		common.AddRequestIdHeader(req, truncateCorrelationIDs(upstreamCorrelationIDs, c.MaxCorrelationIDSegments, c.MaxCorrelationIDLength))
+		return
+	}
+
+	addedIDLen := 20
+	if upstreamCorrelationIDs != "" {
+		// get length of (first of) IDs (e.g. "id1" is 3), new ID will be half that size
+		addedIDLen = len(upstreamCorrelationIDs) / 2
+		if commaPosition := strings.Index(upstreamCorrelationIDs, ","); commaPosition > 1 {
+			addedIDLen = commaPosition / 2
+		}
+		upstreamCorrelationIDs += ","
+	}
+	correlationIDs := upstreamCorrelationIDs + common.NewRequestID(addedIDLen)
+	common.AddRequestIdHeader(req, truncateCorrelationIDs(correlationIDs, c.MaxCorrelationIDSegments, c.MaxCorrelationIDLength))
+}
+
+// normaliseCorrelationIDs sanitises an upstream correlation ID chain, which may come from
+// an attacker-controlled request header, before it is forwarded: characters other than
+// letters, digits, '-', '_' and '.' are removed from each segment, and segments which are
+// then empty or longer than maxCorrelationIDSegmentLength are replaced with new IDs.
+func normaliseCorrelationIDs(ids string) string {
+	if ids == "" {
+		return ""
+	}
+	segments := strings.Split(ids, ",")
+	for i, segment := range segments {
+		segment = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+				return r
+			}
+			return -1
+		}, segment)
+		if segment == "" || len(segment) > maxCorrelationIDSegmentLength {
+			segment = common.NewRequestID(20)
+		}
+		segments[i] = segment
+	}
+	return strings.Join(segments, ",")
+}
+
+// truncateCorrelationIDs drops segments from the middle of the comma-separated chain ids,
+// always keeping the first (originating) and last (newest) IDs, until it has at most
+// maxSegments segments and is at most maxLength long. A limit of zero or less is ignored.
+// A chain of only the first and last IDs is never truncated further, so may exceed maxLength.
+func truncateCorrelationIDs(ids string, maxSegments, maxLength int) string {
+	segments := strings.Split(ids, ",")
+	if maxSegments > 0 && maxSegments < 2 {
+		maxSegments = 2
+	}
+
+	length := len(ids)
+	for len(segments) > 2 {
+		if (maxSegments <= 0 || len(segments) <= maxSegments) && (maxLength <= 0 || length <= maxLength) {
+			break
+		}
+		length -= len(segments[1]) + 1
+		segments = append(segments[:1], segments[2:]...)
+	}
+	return strings.Join(segments, ",")
+}
+
+// addDeadlineHeader sets the deadline header to the context deadline in RFC3339Nano (UTC)
+// format, so downstream logs can correlate their timeouts with ours.
+func addDeadlineHeader(ctx context.Context, req *http.Request) {
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(DeadlineHeaderKey, deadline.UTC().Format(time.RFC3339Nano))
+	}
+}
@@ -0,0 +1,166 @@
+package rchttp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientWithoutCorrelationChaining(t *testing.T) {
+	ts := rchttptest.NewTestServer(200)
+	defer ts.Close()
+
+	Convey("Given an rchttp client and a context with correlation chaining disabled", t, func() {
+		httpClient := NewClient()
+		ctx := WithoutCorrelationChaining(context.Background())
+
+		Convey("When Get() is called with an upstream correlation ID", func() {
+			resp, err := httpClient.Get(common.WithRequestId(ctx, "call1234,abcd"), ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the server sees the upstream ID unchanged", func() {
+				So(call.Headers[common.RequestHeaderKey], ShouldResemble, []string{"call1234,abcd"})
+			})
+		})
+
+		Convey("When Get() is called without an upstream correlation ID", func() {
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the server sees no request ID header", func() {
+				So(call.Headers[common.RequestHeaderKey], ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestTruncateCorrelationIDs(t *testing.T) {
+	Convey("Given a deep correlation ID chain", t, func() {
+		ids := "origin,id1,id2,id3,id4,newest"
+
+		Convey("When there are no limits the chain is unchanged", func() {
+			So(truncateCorrelationIDs(ids, 0, 0), ShouldEqual, ids)
+		})
+
+		Convey("When the number of segments is limited the first and last are kept", func() {
+			So(truncateCorrelationIDs(ids, 4, 0), ShouldEqual, "origin,id3,id4,newest")
+			So(truncateCorrelationIDs(ids, 2, 0), ShouldEqual, "origin,newest")
+			So(truncateCorrelationIDs(ids, 1, 0), ShouldEqual, "origin,newest")
+		})
+
+		Convey("When the length is limited the first and last are kept", func() {
+			So(truncateCorrelationIDs(ids, 0, 22), ShouldEqual, "origin,id3,id4,newest")
+			So(truncateCorrelationIDs(ids, 0, 5), ShouldEqual, "origin,newest")
+		})
+
+		Convey("When both limits are set the stricter one wins", func() {
+			So(truncateCorrelationIDs(ids, 5, 18), ShouldEqual, "origin,id4,newest")
+		})
+	})
+}
+
+func TestNormaliseCorrelationIDs(t *testing.T) {
+	Convey("Given upstream correlation ID chains", t, func() {
+		Convey("When the IDs are valid they are unchanged", func() {
+			So(normaliseCorrelationIDs("abc-123,Def_4.5"), ShouldEqual, "abc-123,Def_4.5")
+			So(normaliseCorrelationIDs(""), ShouldEqual, "")
+		})
+
+		Convey("When an ID contains invalid characters they are removed", func() {
+			So(normaliseCorrelationIDs("abc\r\nSet-Cookie: x=1,def"), ShouldEqual, "abcSet-Cookiex1,def")
+		})
+
+		Convey("When an ID is empty or too long it is replaced", func() {
+			ids := strings.Split(normaliseCorrelationIDs("abc,<>,"+strings.Repeat("a", 65)), ",")
+			So(ids, ShouldHaveLength, 3)
+			So(ids[0], ShouldEqual, "abc")
+			So(ids[1], ShouldHaveLength, 20)
+			So(ids[2], ShouldHaveLength, 20)
+		})
+	})
+}
+
+func TestClientLimitsCorrelationIDChain(t *testing.T) {
+	ts := rchttptest.NewTestServer(200)
+	defer ts.Close()
+
+	Convey("Given an rchttp client with a maximum of three correlation ID segments", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, MaxCorrelationIDSegments: 3}
+
+		Convey("When Get() is called with a deep upstream correlation ID chain", func() {
+			resp, err := httpClient.Get(common.WithRequestId(context.Background(), "origin12,id1,id2,id3,id4"), ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the server sees the origin, the last upstream and the new ID", func() {
+				So(call.Headers[common.RequestHeaderKey], ShouldHaveLength, 1)
+				header := call.Headers[common.RequestHeaderKey][0]
+				So(header, ShouldStartWith, "origin12,id4,")
+				So(len(header), ShouldEqual, len("origin12,id4,")+4)
+			})
+		})
+
+		Convey("When the chain is forwarded without chaining", func() {
+			ctx := WithoutCorrelationChaining(common.WithRequestId(context.Background(), "origin12,id1,id2,id3,id4"))
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is still limited to three segments", func() {
+				So(call.Headers[common.RequestHeaderKey], ShouldResemble, []string{"origin12,id3,id4"})
+			})
+		})
+	})
+}
+
+func TestClientSendsDeadlineHeader(t *testing.T) {
+	ts := rchttptest.NewTestServer(200)
+	defer ts.Close()
+
+	Convey("Given an rchttp client which sends the deadline header", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, SendDeadlineHeader: true}
+
+		Convey("When Get() is called with a context deadline", func() {
+			deadline := time.Now().Add(time.Minute)
+			ctx, cancel := context.WithDeadline(context.Background(), deadline)
+			defer cancel()
+
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the server sees the deadline", func() {
+				So(call.Headers[DeadlineHeaderKey], ShouldResemble, []string{deadline.UTC().Format(time.RFC3339Nano)})
+			})
+		})
+
+		Convey("When Get() is called without a context deadline", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the server sees no deadline header", func() {
+				So(call.Headers[DeadlineHeaderKey], ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,35 @@
+package rchttp
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// PossiblyCreatedError is returned by Do, with the response, when a POST fails with a
+// response giving the Location of a resource, which suggests that the resource was created
+// despite the failure. It is not retried, even if non-idempotent retries are allowed (see
+// AllowNonIdempotentRetries), as replaying the POST could create a duplicate; callers
+// should check for the resource at Location instead.
+type PossiblyCreatedError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Location   string
+}
+
+func (e *PossiblyCreatedError) Error() string {
+	return fmt.Sprintf("rchttp: not retrying %s %s: status %d but resource possibly created at %s", e.Method, e.URL, e.StatusCode, e.Location)
+}
+
+// possiblyCreated returns a *PossiblyCreatedError if resp is a failed response to a POST
+// which gives a Location, otherwise nil.
+func possiblyCreated(req *http.Request, resp *http.Response) error {
+	if req.Method != "POST" || resp == nil || resp.StatusCode < http.StatusInternalServerError {
+		return nil
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil
+	}
+	return &PossiblyCreatedError{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode, Location: location}
+}
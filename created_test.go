@@ -0,0 +1,52 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientPossiblyCreated(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Location", "/filters/1")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with retries and a server failing after creating a resource", t, func() {
+		calls = 0
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 3, RetryTime: time.Millisecond}
+
+		Convey("When a POST is made", func() {
+			resp, err := httpClient.Post(context.Background(), ts.URL, "application/json", strings.NewReader(`{}`))
+
+			Convey("Then it is not retried and a possibly-created error is returned with the response", func() {
+				var created *PossiblyCreatedError
+				So(errors.As(err, &created), ShouldBeTrue)
+				So(created.Location, ShouldEqual, "/filters/1")
+				So(created.StatusCode, ShouldEqual, http.StatusBadGateway)
+				So(resp.StatusCode, ShouldEqual, http.StatusBadGateway)
+				resp.Body.Close()
+				So(calls, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a PUT is made", func() {
+			resp, err := httpClient.Put(context.Background(), ts.URL, "application/json", strings.NewReader(`{}`))
+
+			Convey("Then it is retried as usual", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(calls, ShouldEqual, 4)
+			})
+		})
+	})
+}
@@ -0,0 +1,64 @@
+package rchttp
+
+import (
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// maxStatusErrorBody is the most of a response body kept in a StatusError by the streaming helpers.
+const maxStatusErrorBody = 64 * 1024
+
+// GetCSV calls Get, accepting CSV, and calls fn with each record of the response body as it
+// is read, without buffering the whole body. It stops and returns the error if fn returns
+// an error or ctx is done. The record slice is reused between calls, so fn must copy it
+// to keep it.
+func (c *Client) GetCSV(ctx context.Context, url string, fn func(record []string) error) (*CallInfo, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/csv")
+
+	start := time.Now()
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	info := c.newCallInfo(resp, start)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxStatusErrorBody))
+		return info, statusError(req, resp, b)
+	}
+
+	body, err := utf8Reader(info.contentType, resp.Body)
+	if err != nil {
+		return info, err
+	}
+	r := csv.NewReader(body)
+	r.ReuseRecord = true
+	for {
+		if err := ctx.Err(); err != nil {
+			return info, err
+		}
+		record, err := r.Read()
+		if err == io.EOF {
+			return info, nil
+		}
+		if err != nil {
+			return info, err
+		}
+		if err := fn(record); err != nil {
+			return info, err
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientGetCSV(t *testing.T) {
+	var accept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("time,value\n2019,1.5\n2020,2.5\n"))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("When a CSV body is streamed", func() {
+			var records [][]string
+			info, err := httpClient.GetCSV(context.Background(), ts.URL, func(record []string) error {
+				records = append(records, append([]string(nil), record...))
+				return nil
+			})
+
+			Convey("Then fn is called with each record", func() {
+				So(err, ShouldBeNil)
+				So(accept, ShouldEqual, "text/csv")
+				So(info.StatusCode, ShouldEqual, 200)
+				So(records, ShouldResemble, [][]string{{"time", "value"}, {"2019", "1.5"}, {"2020", "2.5"}})
+			})
+		})
+
+		Convey("When fn returns an error", func() {
+			errStop := errors.New("stop")
+			calls := 0
+			_, err := httpClient.GetCSV(context.Background(), ts.URL, func(record []string) error {
+				calls++
+				return errStop
+			})
+
+			Convey("Then streaming stops with that error", func() {
+				So(err, ShouldEqual, errStop)
+				So(calls, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the context is cancelled while streaming", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			calls := 0
+			_, err := httpClient.GetCSV(ctx, ts.URL, func(record []string) error {
+				calls++
+				cancel()
+				return nil
+			})
+
+			Convey("Then streaming stops with the context error", func() {
+				So(err, ShouldEqual, context.Canceled)
+				So(calls, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the response is not 2xx", func() {
+			_, err := httpClient.GetCSV(context.Background(), ts.URL+"/missing", func(record []string) error { return nil })
+
+			Convey("Then a StatusError is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &StatusError{})
+				So(string(err.(*StatusError).Body), ShouldEqual, "not found")
+			})
+		})
+	})
+}
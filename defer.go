@@ -0,0 +1,199 @@
+package rchttp
+
+import (
+	"bytes"
+	"container/heap"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Errors returned by Defer.
+var (
+	ErrDeferredNotEnabled = errors.New("rchttp: deferred requests are not enabled")
+	ErrDeferredQueueFull  = errors.New("rchttp: deferred request queue is full")
+)
+
+// deferredRequest is a request waiting in the deferred queue.
+type deferredRequest struct {
+	req       *http.Request
+	notBefore time.Time
+	seq       uint64
+}
+
+// deferredHeap orders deferred requests by notBefore, then by when they were deferred.
+type deferredHeap []*deferredRequest
+
+func (h deferredHeap) Len() int { return len(h) }
+func (h deferredHeap) Less(i, j int) bool {
+	if h[i].notBefore.Equal(h[j].notBefore) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].notBefore.Before(h[j].notBefore)
+}
+func (h deferredHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *deferredHeap) Push(x interface{}) { *h = append(*h, x.(*deferredRequest)) }
+func (h *deferredHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// deferredQueue holds deferred requests until they are due and hands them to its workers.
+type deferredQueue struct {
+	mutex    sync.Mutex
+	requests deferredHeap
+	seq      uint64
+	inflight int
+	wake     chan struct{}
+}
+
+// EnableDeferred starts workers goroutines which make requests queued by Defer once they
+// are due, until ctx is done. Deferred requests are made with Do, so they are retried and
+// paced like any other request, and their outcomes can be observed with EnableEvents;
+// failures are reported to OnDeferredError.
+func (c *Client) EnableDeferred(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	queue := &deferredQueue{wake: make(chan struct{}, 1)}
+	c.deferred = queue
+
+	due := make(chan *http.Request)
+	go queue.dispatch(ctx, due)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for req := range due {
+				resp, err := c.Do(valuesContext{Context: ctx, values: req.Context()}, req)
+				if resp != nil {
+					io.Copy(ioutil.Discard, resp.Body)
+					resp.Body.Close()
+					if err == nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+						err = &StatusError{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode}
+					}
+				}
+				if err != nil && c.OnDeferredError != nil {
+					c.OnDeferredError(req, err)
+				}
+				queue.mutex.Lock()
+				queue.inflight--
+				queue.mutex.Unlock()
+			}
+		}()
+	}
+}
+
+// Defer queues req to be made no earlier than notBefore by the workers started by
+// EnableDeferred, for non-urgent calls (e.g. analytics) which should not hold up the
+// caller. The request body, if any, is buffered so that the caller need not keep it open.
+// The request is made with the values (e.g. the correlation ID) of its context, but not
+// its deadline or cancellation, as it outlives the caller.
+func (c *Client) Defer(req *http.Request, notBefore time.Time) error {
+	if c.deferred == nil {
+		return ErrDeferredNotEnabled
+	}
+	if !isReplayable(req) {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		req.ContentLength = int64(len(b))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+	return c.deferred.push(req, notBefore, c.MaxDeferredRequests)
+}
+
+// PendingDeferred returns the number of deferred requests not yet completed.
+func (c *Client) PendingDeferred() int {
+	if c.deferred == nil {
+		return 0
+	}
+	c.deferred.mutex.Lock()
+	defer c.deferred.mutex.Unlock()
+	return len(c.deferred.requests) + c.deferred.inflight
+}
+
+// push queues req, unless max (if positive) requests are already pending.
+func (queue *deferredQueue) push(req *http.Request, notBefore time.Time, max int) error {
+	queue.mutex.Lock()
+	if max > 0 && len(queue.requests)+queue.inflight >= max {
+		queue.mutex.Unlock()
+		return ErrDeferredQueueFull
+	}
+	queue.seq++
+	heap.Push(&queue.requests, &deferredRequest{req: req, notBefore: notBefore, seq: queue.seq})
+	queue.mutex.Unlock()
+
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// dispatch sends each deferred request to due once it is due, until ctx is done.
+func (queue *deferredQueue) dispatch(ctx context.Context, due chan<- *http.Request) {
+	defer close(due)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		queue.mutex.Lock()
+		var next *deferredRequest
+		wait := time.Hour
+		if len(queue.requests) > 0 {
+			if wait = time.Until(queue.requests[0].notBefore); wait <= 0 {
+				next = heap.Pop(&queue.requests).(*deferredRequest)
+				queue.inflight++
+			}
+		}
+		queue.mutex.Unlock()
+
+		if next != nil {
+			select {
+			case due <- next.req:
+			case <-ctx.Done():
+				// leave it pending rather than losing it
+				queue.mutex.Lock()
+				heap.Push(&queue.requests, next)
+				queue.inflight--
+				queue.mutex.Unlock()
+				return
+			}
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-queue.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// valuesContext has the values of one context, and the deadline and cancellation of another.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (ctx valuesContext) Value(key interface{}) interface{} {
+	return ctx.values.Value(key)
+}
@@ -0,0 +1,144 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientDefer(t *testing.T) {
+	var mutex sync.Mutex
+	var received, ids []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		received = append(received, r.URL.Path+":"+string(body))
+		ids = append(ids, r.Header.Get(common.RequestHeaderKey))
+		mutex.Unlock()
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	receivedRequests := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), received...)
+	}
+	receivedIDs := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), ids...)
+	}
+
+	Convey("Given a client without deferred requests enabled", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("Then Defer returns an error", func() {
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			So(httpClient.Defer(req, time.Now()), ShouldEqual, ErrDeferredNotEnabled)
+		})
+	})
+
+	Convey("Given a client with deferred requests enabled", t, func() {
+		received = nil
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+		httpClient.EnableDeferred(ctx, 1)
+
+		Convey("When requests are deferred out of order", func() {
+			later, _ := http.NewRequest("POST", ts.URL+"/later", ioutil.NopCloser(strings.NewReader("b")))
+			sooner, _ := http.NewRequest("POST", ts.URL+"/sooner", ioutil.NopCloser(strings.NewReader("a")))
+			So(httpClient.Defer(later, time.Now().Add(100*time.Millisecond)), ShouldBeNil)
+			So(httpClient.Defer(sooner, time.Now()), ShouldBeNil)
+
+			Convey("Then each is made once due, in order, with its body", func() {
+				waitFor(func() bool { return len(receivedRequests()) == 1 })
+				So(receivedRequests(), ShouldResemble, []string{"/sooner:a"})
+
+				waitFor(func() bool { return httpClient.PendingDeferred() == 0 })
+				So(receivedRequests(), ShouldResemble, []string{"/sooner:a", "/later:b"})
+			})
+		})
+
+		Convey("When more requests are deferred than the client allows", func() {
+			httpClient.MaxDeferredRequests = 1
+			first, _ := http.NewRequest("GET", ts.URL+"/first", nil)
+			second, _ := http.NewRequest("GET", ts.URL+"/second", nil)
+			So(httpClient.Defer(first, time.Now().Add(time.Hour)), ShouldBeNil)
+
+			Convey("Then Defer rejects the excess", func() {
+				So(httpClient.Defer(second, time.Now()), ShouldEqual, ErrDeferredQueueFull)
+				So(httpClient.PendingDeferred(), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a deferred request fails", func() {
+			failures := make(chan error, 1)
+			httpClient.OnDeferredError = func(req *http.Request, err error) {
+				failures <- err
+			}
+			req, _ := http.NewRequest("GET", ts.URL+"/missing", nil)
+			So(httpClient.Defer(req, time.Now()), ShouldBeNil)
+
+			Convey("Then OnDeferredError is called with its status", func() {
+				var err error
+				select {
+				case err = <-failures:
+				case <-time.After(time.Second):
+				}
+				statusErr, ok := err.(*StatusError)
+				So(ok, ShouldBeTrue)
+				So(statusErr.StatusCode, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
+		Convey("When a request is deferred with values in its context", func() {
+			reqCtx, reqCancel := context.WithCancel(common.WithRequestId(context.Background(), "deferred1"))
+			req, _ := http.NewRequestWithContext(reqCtx, "GET", ts.URL+"/values", nil)
+			So(httpClient.Defer(req, time.Now().Add(20*time.Millisecond)), ShouldBeNil)
+			reqCancel()
+
+			Convey("Then it is made with those values after its context is cancelled", func() {
+				waitFor(func() bool { return httpClient.PendingDeferred() == 0 })
+				seen := receivedIDs()
+				So(seen[len(seen)-1], ShouldStartWith, "deferred1,")
+			})
+		})
+	})
+
+	Convey("Given a client whose deferred workers stop while a request is due", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		queue := &deferredQueue{wake: make(chan struct{}, 1)}
+		due := make(chan *http.Request)
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		queue.push(req, time.Now(), 0)
+		stopped := make(chan struct{})
+		go func() {
+			queue.dispatch(ctx, due)
+			close(stopped)
+		}()
+		waitFor(func() bool {
+			queue.mutex.Lock()
+			defer queue.mutex.Unlock()
+			return queue.inflight == 1
+		})
+		cancel()
+		<-stopped
+
+		Convey("Then the request is left pending rather than lost", func() {
+			So(queue.requests, ShouldHaveLength, 1)
+			So(queue.inflight, ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,270 @@
+package rchttp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DeliveryIDHeaderKey is the header holding the ID of a delivery, which is the same for
+// every attempt so that receivers can ignore redeliveries they have already processed.
+const DeliveryIDHeaderKey = "X-Delivery-Id"
+
+// Delivery is an outbound notification (e.g. a webhook) held by a Deliverer until it is
+// delivered or dead-lettered.
+type Delivery struct {
+	ID          string
+	URL         string
+	Header      http.Header
+	Body        []byte
+	Created     time.Time
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+}
+
+// DeliveryStore persists deliveries which have not yet succeeded.
+type DeliveryStore interface {
+	// Save adds or updates a delivery.
+	Save(delivery *Delivery) error
+	// Due returns up to limit deliveries whose NextAttempt is not after now.
+	Due(now time.Time, limit int) ([]*Delivery, error)
+	// Delete removes a delivered delivery.
+	Delete(id string) error
+	// DeadLetter removes a delivery which will not be attempted again, keeping it for inspection.
+	DeadLetter(delivery *Delivery) error
+}
+
+// Deliverer POSTs deliveries using its Client (and so its retries, as each delivery is sent
+// with its ID as its Idempotency-Key), redelivering those that fail with an exponentially
+// increasing interval until MaxAttempts is reached, after which they are dead-lettered.
+// Client errors other than 408 and 429 are dead-lettered immediately.
+type Deliverer struct {
+	Client *Client
+	Store  DeliveryStore
+	// MaxAttempts is the number of redeliveries before a delivery is dead-lettered.
+	MaxAttempts int
+	// RedeliveryInterval is the delay before the first redelivery, doubling up to MaxRedeliveryInterval.
+	RedeliveryInterval    time.Duration
+	MaxRedeliveryInterval time.Duration
+	// PollInterval is how often Run checks the store for due deliveries.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of deliveries attempted per poll.
+	BatchSize int
+	// OnDeadLetter, if set, is called with each dead-lettered delivery.
+	OnDeadLetter func(delivery *Delivery)
+}
+
+// NewDeliverer returns a Deliverer with sensible defaults, redelivering over roughly a day.
+func NewDeliverer(client *Client, store DeliveryStore) *Deliverer {
+	return &Deliverer{
+		Client:                client,
+		Store:                 store,
+		MaxAttempts:           20,
+		RedeliveryInterval:    time.Minute,
+		MaxRedeliveryInterval: 2 * time.Hour,
+		PollInterval:          10 * time.Second,
+		BatchSize:             100,
+	}
+}
+
+// Enqueue saves a delivery of body to url, to be attempted on the next poll.
+func (d *Deliverer) Enqueue(url string, header http.Header, body []byte) (*Delivery, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := &Delivery{
+		ID:          hex.EncodeToString(id),
+		URL:         url,
+		Header:      cloneHeader(header),
+		Body:        body,
+		Created:     now,
+		NextAttempt: now,
+	}
+	if err := d.Store.Save(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Run attempts due deliveries every PollInterval until ctx is done.
+func (d *Deliverer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// DeliverDue attempts one batch of due deliveries, returning how many were delivered.
+func (d *Deliverer) DeliverDue(ctx context.Context) (int, error) {
+	due, err := d.Store.Due(time.Now(), d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		permanent, err := d.attempt(ctx, delivery)
+		if err == nil {
+			delivered++
+			if err := d.Store.Delete(delivery.ID); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		delivery.Attempts++
+		delivery.LastError = err.Error()
+		if permanent || delivery.Attempts > d.MaxAttempts {
+			if err := d.Store.DeadLetter(delivery); err != nil {
+				return delivered, err
+			}
+			if d.OnDeadLetter != nil {
+				d.OnDeadLetter(delivery)
+			}
+			continue
+		}
+		delivery.NextAttempt = time.Now().Add(d.redeliveryInterval(delivery.Attempts))
+		if err := d.Store.Save(delivery); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// attempt POSTs delivery, reporting whether a failure is permanent.
+func (d *Deliverer) attempt(ctx context.Context, delivery *Delivery) (bool, error) {
+	req, err := http.NewRequest("POST", delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return true, err
+	}
+	req.Header = cloneHeader(delivery.Header)
+	req.Header.Set(DeliveryIDHeaderKey, delivery.ID)
+	if req.Header.Get(IdempotencyKeyHeader) == "" {
+		// the ID makes replays safe to retry, which POSTs otherwise are not
+		req.Header.Set(IdempotencyKeyHeader, delivery.ID)
+	}
+
+	resp, err := d.Client.Do(ctx, req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return false, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return true, fmt.Errorf("delivery rejected with status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("delivery failed with status %d", resp.StatusCode)
+	}
+}
+
+func (d *Deliverer) redeliveryInterval(attempts int) time.Duration {
+	interval := d.RedeliveryInterval
+	for i := 1; i < attempts && interval < d.MaxRedeliveryInterval; i++ {
+		interval *= 2
+	}
+	if d.MaxRedeliveryInterval > 0 && interval > d.MaxRedeliveryInterval {
+		interval = d.MaxRedeliveryInterval
+	}
+	return interval
+}
+
+// MemoryDeliveryStore is a DeliveryStore held in memory, so deliveries do not survive a restart.
+type MemoryDeliveryStore struct {
+	mutex       sync.Mutex
+	deliveries  map[string]Delivery
+	deadLetters []Delivery
+}
+
+// NewMemoryDeliveryStore returns an empty MemoryDeliveryStore.
+func NewMemoryDeliveryStore() *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{deliveries: make(map[string]Delivery)}
+}
+
+// Save implements DeliveryStore.
+func (store *MemoryDeliveryStore) Save(delivery *Delivery) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// Due implements DeliveryStore, returning the oldest due deliveries first.
+func (store *MemoryDeliveryStore) Due(now time.Time, limit int) ([]*Delivery, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var due []*Delivery
+	for _, delivery := range store.deliveries {
+		if !delivery.NextAttempt.After(now) {
+			delivery := delivery
+			due = append(due, &delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttempt.Before(due[j].NextAttempt) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Delete implements DeliveryStore.
+func (store *MemoryDeliveryStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.deliveries, id)
+	return nil
+}
+
+// DeadLetter implements DeliveryStore.
+func (store *MemoryDeliveryStore) DeadLetter(delivery *Delivery) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.deliveries, delivery.ID)
+	store.deadLetters = append(store.deadLetters, *delivery)
+	return nil
+}
+
+// Len returns the number of deliveries not yet delivered or dead-lettered.
+func (store *MemoryDeliveryStore) Len() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.deliveries)
+}
+
+// DeadLetters returns the dead-lettered deliveries.
+func (store *MemoryDeliveryStore) DeadLetters() []Delivery {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return append([]Delivery(nil), store.deadLetters...)
+}
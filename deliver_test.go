@@ -0,0 +1,128 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeliverer(t *testing.T) {
+	var mutex sync.Mutex
+	var statuses []int
+	var ids []string
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		ids = append(ids, r.Header.Get(DeliveryIDHeaderKey))
+		bodies = append(bodies, string(body))
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	Convey("Given a deliverer whose client does not retry", t, func() {
+		statuses, ids, bodies = nil, nil, nil
+		store := NewMemoryDeliveryStore()
+		deliverer := NewDeliverer(&Client{HTTPClient: DefaultClient.HTTPClient}, store)
+		deliverer.RedeliveryInterval = time.Millisecond
+		deliverer.MaxAttempts = 2
+		var deadLettered []*Delivery
+		deliverer.OnDeadLetter = func(delivery *Delivery) { deadLettered = append(deadLettered, delivery) }
+
+		delivery, err := deliverer.Enqueue(ts.URL, http.Header{"Content-Type": {"application/json"}}, []byte(`{"event":"published"}`))
+		So(err, ShouldBeNil)
+		So(store.Len(), ShouldEqual, 1)
+
+		Convey("When the receiver accepts it", func() {
+			delivered, err := deliverer.DeliverDue(context.Background())
+
+			Convey("Then it is delivered and removed from the store", func() {
+				So(err, ShouldBeNil)
+				So(delivered, ShouldEqual, 1)
+				So(store.Len(), ShouldEqual, 0)
+				So(ids, ShouldResemble, []string{delivery.ID})
+				So(bodies, ShouldResemble, []string{`{"event":"published"}`})
+			})
+		})
+
+		Convey("When the receiver fails and then recovers", func() {
+			statuses = []int{http.StatusServiceUnavailable}
+			delivered, err := deliverer.DeliverDue(context.Background())
+			So(err, ShouldBeNil)
+			So(delivered, ShouldEqual, 0)
+			So(store.Len(), ShouldEqual, 1)
+
+			time.Sleep(5 * time.Millisecond)
+			delivered, err = deliverer.DeliverDue(context.Background())
+
+			Convey("Then it is redelivered with the same ID", func() {
+				So(err, ShouldBeNil)
+				So(delivered, ShouldEqual, 1)
+				So(store.Len(), ShouldEqual, 0)
+				So(ids, ShouldHaveLength, 2)
+				So(ids[1], ShouldEqual, ids[0])
+			})
+		})
+
+		Convey("When the receiver keeps failing", func() {
+			statuses = []int{500, 500, 500}
+			for i := 0; i < 3; i++ {
+				deliverer.DeliverDue(context.Background())
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			Convey("Then it is dead-lettered after MaxAttempts redeliveries", func() {
+				So(ids, ShouldHaveLength, 3)
+				So(store.Len(), ShouldEqual, 0)
+				So(store.DeadLetters(), ShouldHaveLength, 1)
+				So(deadLettered, ShouldHaveLength, 1)
+				So(deadLettered[0].Attempts, ShouldEqual, 3)
+				So(deadLettered[0].LastError, ShouldEqual, "delivery failed with status 500")
+			})
+		})
+
+		Convey("When the receiver is briefly unavailable and the client retries", func() {
+			deliverer.Client = &Client{HTTPClient: DefaultClient.HTTPClient, MaxRetries: 2, RetryTime: time.Millisecond}
+			statuses = []int{http.StatusServiceUnavailable}
+			delivered, err := deliverer.DeliverDue(context.Background())
+
+			Convey("Then the POST is retried with the delivery ID as its idempotency key", func() {
+				So(err, ShouldBeNil)
+				So(delivered, ShouldEqual, 1)
+				So(store.Len(), ShouldEqual, 0)
+				So(ids, ShouldResemble, []string{delivery.ID, delivery.ID})
+			})
+		})
+
+		Convey("When the receiver rejects it", func() {
+			statuses = []int{http.StatusBadRequest}
+			deliverer.DeliverDue(context.Background())
+
+			Convey("Then it is dead-lettered immediately", func() {
+				So(ids, ShouldHaveLength, 1)
+				So(store.DeadLetters(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestDelivererRedeliveryInterval(t *testing.T) {
+	Convey("Redelivery intervals double up to the maximum", t, func() {
+		d := &Deliverer{RedeliveryInterval: time.Minute, MaxRedeliveryInterval: 5 * time.Minute}
+		So(d.redeliveryInterval(1), ShouldEqual, time.Minute)
+		So(d.redeliveryInterval(2), ShouldEqual, 2*time.Minute)
+		So(d.redeliveryInterval(3), ShouldEqual, 4*time.Minute)
+		So(d.redeliveryInterval(10), ShouldEqual, 5*time.Minute)
+	})
+}
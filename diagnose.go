@@ -0,0 +1,144 @@
+package rchttp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Names of the phases of a diagnosis.
+const (
+	DiagnosePhaseDNS  = "dns"
+	DiagnosePhaseTCP  = "tcp"
+	DiagnosePhaseTLS  = "tls"
+	DiagnosePhaseHead = "head"
+	DiagnosePhaseGet  = "get"
+)
+
+// DiagnosticPhase is the outcome of one phase of a diagnosis.
+type DiagnosticPhase struct {
+	Name     string
+	Duration time.Duration
+	// Detail describes what the phase found, e.g. the addresses resolved or the status returned.
+	Detail string
+	Err    error
+}
+
+// DiagnosticReport is the outcome of Diagnose. Phases stop at the first which fails.
+type DiagnosticReport struct {
+	URL    string
+	Phases []DiagnosticPhase
+}
+
+// OK reports whether every phase succeeded.
+func (report *DiagnosticReport) OK() bool {
+	for _, phase := range report.Phases {
+		if phase.Err != nil {
+			return false
+		}
+	}
+	return len(report.Phases) > 0
+}
+
+// Diagnose checks connectivity to rawurl step by step: resolving its host, connecting,
+// the TLS handshake (for https), then a HEAD and a GET with the client (without retries),
+// reporting the latency and any error of each phase, for debug endpoints and runbooks.
+func (c *Client) Diagnose(ctx context.Context, rawurl string) (*DiagnosticReport, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	report := &DiagnosticReport{URL: rawurl}
+	run := func(name string, phase func() (string, error)) bool {
+		start := time.Now()
+		detail, err := phase()
+		report.Phases = append(report.Phases, DiagnosticPhase{Name: name, Duration: time.Since(start), Detail: detail, Err: err})
+		return err == nil
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	var addrs []string
+	if !run(DiagnosePhaseDNS, func() (string, error) {
+		addrs, err = net.DefaultResolver.LookupHost(ctx, u.Hostname())
+		return strings.Join(addrs, ","), err
+	}) {
+		return report, nil
+	}
+
+	var conn net.Conn
+	if !run(DiagnosePhaseTCP, func() (string, error) {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(addrs[0], port))
+		if err != nil {
+			return "", err
+		}
+		return conn.RemoteAddr().String(), nil
+	}) {
+		return report, nil
+	}
+
+	if u.Scheme == "https" {
+		ok := run(DiagnosePhaseTLS, func() (string, error) {
+			tlsConn := tls.Client(conn, c.diagnoseTLSConfig(u.Hostname()))
+			conn = tlsConn
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return "", err
+			}
+			state := tlsConn.ConnectionState()
+			return fmt.Sprintf("%s %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite)), nil
+		})
+		if !ok {
+			conn.Close()
+			return report, nil
+		}
+	}
+	conn.Close()
+
+	ctx = WithBackoff(ctx, false)
+	for _, method := range []string{"HEAD", "GET"} {
+		if !run(strings.ToLower(method), func() (string, error) {
+			req, err := http.NewRequest(method, rawurl, nil)
+			if err != nil {
+				return "", err
+			}
+			resp, err := c.Do(ctx, req)
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			n, err := io.Copy(ioutil.Discard, resp.Body)
+			return fmt.Sprintf("%s (%d bytes)", resp.Status, n), err
+		}) {
+			break
+		}
+	}
+	return report, nil
+}
+
+// diagnoseTLSConfig returns the TLS config of the client's transport (if any), for the
+// server name which would be used for host.
+func (c *Client) diagnoseTLSConfig(host string) *tls.Config {
+	config := &tls.Config{}
+	if transport, ok := c.HTTPClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
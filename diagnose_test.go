@@ -0,0 +1,68 @@
+package rchttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientDiagnose(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client which trusts the test server", t, func() {
+		httpClient := &Client{HTTPClient: ts.Client()}
+
+		Convey("When a healthy https server is diagnosed", func() {
+			report, err := httpClient.Diagnose(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then every phase succeeds", func() {
+				So(report.OK(), ShouldBeTrue)
+				var names []string
+				for _, phase := range report.Phases {
+					names = append(names, phase.Name)
+				}
+				So(names, ShouldResemble, []string{DiagnosePhaseDNS, DiagnosePhaseTCP, DiagnosePhaseTLS, DiagnosePhaseHead, DiagnosePhaseGet})
+				So(report.Phases[0].Detail, ShouldEqual, "127.0.0.1")
+				So(report.Phases[4].Detail, ShouldEqual, "200 OK (2 bytes)")
+			})
+		})
+	})
+
+	Convey("Given an rchttp client which does not trust the test server", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+
+		Convey("When the server is diagnosed", func() {
+			report, err := httpClient.Diagnose(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the TLS phase fails and later phases are not run", func() {
+				So(report.OK(), ShouldBeFalse)
+				So(report.Phases, ShouldHaveLength, 3)
+				So(report.Phases[2].Name, ShouldEqual, DiagnosePhaseTLS)
+				So(report.Phases[2].Err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given a port which is not listening", t, func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		Convey("Then the TCP phase fails", func() {
+			report, err := (&Client{HTTPClient: &http.Client{}}).Diagnose(context.Background(), "http://"+addr)
+			So(err, ShouldBeNil)
+			So(report.Phases, ShouldHaveLength, 2)
+			So(report.Phases[1].Err, ShouldNotBeNil)
+		})
+	})
+}
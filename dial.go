@@ -0,0 +1,235 @@
+package rchttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Settings of the dialer used by SetLocalAddr and SetLocalInterface, matching DefaultClient.
+const (
+	defaultDialTimeout   = 5 * time.Second
+	defaultDialKeepAlive = 30 * time.Second
+)
+
+// errNoInterfaceAddr is returned by SetLocalInterface for an interface without an IP address.
+var errNoInterfaceAddr = errors.New("rchttp: network interface has no IP address")
+
+// SetDialer makes the client's transport dial new connections with dialer, e.g. to set the
+// dial timeout or the local address connections are made from.
+func (c *Client) SetDialer(dialer *net.Dialer) error {
+	transport, err := c.cloneTransport()
+	if err != nil {
+		return err
+	}
+	c.setDialContext(transport, dialer.DialContext)
+	c.setTransport(transport)
+	return nil
+}
+
+// SetHostDialers makes the client dial connections to the hosts in dialers with their dial
+// functions, e.g. to reach some downstreams through SSH tunnels or port-forwards in a
+// developer environment, while other hosts are dialled as normal. Keys are either
+// "host:port" or "host", which matches any port; the dial function is passed the address
+// being dialled, and is not subject to the resolver timeout.
+func (c *Client) SetHostDialers(dialers map[string]func(ctx context.Context, network, addr string) (net.Conn, error)) error {
+	transport, err := c.cloneTransport()
+	if err != nil {
+		return err
+	}
+	c.hostDialers = make(map[string]func(ctx context.Context, network, addr string) (net.Conn, error), len(dialers))
+	for host, dial := range dialers {
+		c.hostDialers[host] = dial
+	}
+	c.setDialContext(transport, c.baseDial(transport))
+	c.setTransport(transport)
+	return nil
+}
+
+// dialHosts wraps dial to dial the hosts in dialers with their own dial functions.
+func dialHosts(dialers map[string]func(ctx context.Context, network, addr string) (net.Conn, error), dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if hostDial, ok := dialers[addr]; ok {
+			return hostDial(ctx, network, addr)
+		}
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			if hostDial, ok := dialers[host]; ok {
+				return hostDial(ctx, network, addr)
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// setDialContext makes transport dial with dial, wrapped to resolve hosts if a resolver
+// timeout is set, to dial any hosts with their own dial functions, to count the connections
+// if the connection gauge is enabled and to call any connection hooks, keeping any TLS
+// server names in effect.
+func (c *Client) setDialContext(transport *http.Transport, dial func(ctx context.Context, network, addr string) (net.Conn, error)) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	c.dial = dial
+	if c.resolver != nil {
+		dial = c.resolver.dialContext(dial)
+	}
+	if len(c.hostDialers) > 0 {
+		dial = dialHosts(c.hostDialers, dial)
+	}
+	if c.connGauge != nil {
+		dial = c.connGauge.dialContext(dial)
+	}
+	if c.connHooks != nil {
+		dial = c.connHooks.dialContext(dial)
+	}
+	transport.DialContext = dial
+	if c.tlsServerNames != nil {
+		transport.DialTLSContext = dialTLSWithServerNames(transport, c.tlsServerNames)
+	}
+}
+
+// baseDial returns the dial function set by SetDialer or, if there is none, transport's own.
+func (c *Client) baseDial(transport *http.Transport) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.dial != nil {
+		return c.dial
+	}
+	return transport.DialContext
+}
+
+// SetLocalAddr binds outgoing connections to the local IP address ip, e.g. on a
+// multi-homed host calling an external API which allowlists one of its addresses.
+func (c *Client) SetLocalAddr(ip string) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return fmt.Errorf("rchttp: invalid local IP address %q", ip)
+	}
+	return c.SetDialer(&net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: defaultDialKeepAlive,
+		LocalAddr: &net.TCPAddr{IP: parsed},
+	})
+}
+
+// SetLocalInterface binds outgoing connections to the first IPv4 address (or failing that,
+// IPv6 address) of the named network interface.
+func (c *Client) SetLocalInterface(name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return err
+	}
+
+	var ip net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			ip = ipNet.IP
+			break
+		}
+		if ip == nil {
+			ip = ipNet.IP
+		}
+	}
+	if ip == nil {
+		return errNoInterfaceAddr
+	}
+	return c.SetLocalAddr(ip.String())
+}
+
+// ResourceExhaustedError is returned when a connection could not be made because local
+// resources have run out, e.g. ephemeral ports (EADDRNOTAVAIL) or file descriptors (EMFILE).
+// Such requests are not retried, as retrying only adds to the pressure.
+type ResourceExhaustedError struct {
+	Err error
+}
+
+func (e *ResourceExhaustedError) Error() string {
+	return "rchttp: local resources exhausted: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying dial error.
+func (e *ResourceExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// resourceExhausted returns err as a *ResourceExhaustedError if it was caused by the
+// exhaustion of local ports, file descriptors or buffer space, otherwise err unchanged.
+func resourceExhausted(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, errno := range []syscall.Errno{syscall.EADDRNOTAVAIL, syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS} {
+		if errors.Is(err, errno) {
+			return &ResourceExhaustedError{Err: err}
+		}
+	}
+	return err
+}
+
+// connGauge counts the connections open from a client's transport.
+type connGauge struct {
+	open int64
+}
+
+// EnableConnectionGauge makes the client count the connections it has open (see
+// OpenConnections), to help diagnose connection leaks and port exhaustion.
+func (c *Client) EnableConnectionGauge() error {
+	if c.connGauge != nil {
+		return nil
+	}
+	transport, err := c.cloneTransport()
+	if err != nil {
+		return err
+	}
+	c.connGauge = &connGauge{}
+	c.setDialContext(transport, c.baseDial(transport))
+	c.setTransport(transport)
+	return nil
+}
+
+// OpenConnections returns the number of connections the client has open, including idle
+// keep-alive connections. It is always zero unless EnableConnectionGauge has been called.
+func (c *Client) OpenConnections() int64 {
+	if c.connGauge == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&c.connGauge.open)
+}
+
+func (g *connGauge) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&g.open, 1)
+		return &closeNotifyConn{Conn: conn, onClose: func() {
+			atomic.AddInt64(&g.open, -1)
+		}}, nil
+	}
+}
+
+// closeNotifyConn calls onClose when it is first closed.
+type closeNotifyConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (conn *closeNotifyConn) Close() error {
+	conn.once.Do(conn.onClose)
+	return conn.Conn.Close()
+}
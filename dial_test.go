@@ -0,0 +1,197 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientSetLocalAddr(t *testing.T) {
+	var remoteAddr string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client sharing an HTTP client", t, func() {
+		shared := &http.Client{}
+		httpClient := &Client{HTTPClient: shared}
+
+		Convey("When it is bound to a local address", func() {
+			So(httpClient.SetLocalAddr("127.0.0.1"), ShouldBeNil)
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then connections are made from that address", func() {
+				host, _, err := net.SplitHostPort(remoteAddr)
+				So(err, ShouldBeNil)
+				So(host, ShouldEqual, "127.0.0.1")
+			})
+
+			Convey("And the shared HTTP client is not modified", func() {
+				So(shared.Transport, ShouldBeNil)
+			})
+		})
+
+		Convey("When it is bound to an invalid address", func() {
+			Convey("Then an error is returned", func() {
+				So(httpClient.SetLocalAddr("not-an-ip"), ShouldNotBeNil)
+			})
+		})
+
+		Convey("When it is bound to the loopback interface", func() {
+			ifaces, _ := net.Interfaces()
+			name := ""
+			for _, iface := range ifaces {
+				if iface.Flags&net.FlagLoopback != 0 {
+					name = iface.Name
+				}
+			}
+			if name == "" {
+				return
+			}
+			So(httpClient.SetLocalInterface(name), ShouldBeNil)
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then requests are made from it", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			})
+		})
+	})
+}
+
+func TestClientResourceExhaustion(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with retries, bound to an address the host does not have", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 5, RetryTime: time.Second}
+		So(httpClient.SetLocalAddr("192.0.2.1"), ShouldBeNil)
+
+		Convey("When a request is made", func() {
+			start := time.Now()
+			_, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then a resource exhaustion error is returned without retrying", func() {
+				var exhausted *ResourceExhaustedError
+				So(errors.As(err, &exhausted), ShouldBeTrue)
+				So(errors.Is(err, syscall.EADDRNOTAVAIL), ShouldBeTrue)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+				So(calls, ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given an error caused by running out of file descriptors", t, func() {
+		err := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("socket", syscall.EMFILE)}
+
+		Convey("Then it is classified as resource exhaustion and not retried", func() {
+			So(resourceExhausted(err), ShouldHaveSameTypeAs, &ResourceExhaustedError{})
+			So(wantRetry(resourceExhausted(err), nil), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given an error caused by a refused connection", t, func() {
+		err := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+		Convey("Then it is returned unchanged and retried", func() {
+			So(resourceExhausted(err), ShouldEqual, err)
+			So(wantRetry(resourceExhausted(err), nil), ShouldBeTrue)
+		})
+	})
+}
+
+func TestClientConnectionGauge(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with the connection gauge enabled", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		So(httpClient.OpenConnections(), ShouldEqual, 0)
+		So(httpClient.EnableConnectionGauge(), ShouldBeNil)
+
+		Convey("When a request is made", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then the kept-alive connection is counted", func() {
+				So(httpClient.OpenConnections(), ShouldEqual, 1)
+			})
+
+			Convey("And it is no longer counted once closed", func() {
+				httpClient.HTTPClient.Transport.(*http.Transport).CloseIdleConnections()
+				So(waitFor(func() bool { return httpClient.OpenConnections() == 0 }), ShouldBeTrue)
+			})
+
+			Convey("And it is closed when the transport is replaced by another setter", func() {
+				So(httpClient.SetLocalAddr("127.0.0.1"), ShouldBeNil)
+				So(waitFor(func() bool { return httpClient.OpenConnections() == 0 }), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a dialer is set afterwards", func() {
+			So(httpClient.SetLocalAddr("127.0.0.1"), ShouldBeNil)
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then connections are still counted", func() {
+				So(httpClient.OpenConnections(), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestClientSetHostDialers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	tsAddr := ts.Listener.Addr().String()
+
+	Convey("Given an rchttp client with a dialer for a tunnelled host", t, func() {
+		var tunnelled []string
+		httpClient := &Client{HTTPClient: &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}}
+		err := httpClient.SetHostDialers(map[string]func(ctx context.Context, network, addr string) (net.Conn, error){
+			"dataset-api": func(ctx context.Context, network, addr string) (net.Conn, error) {
+				tunnelled = append(tunnelled, addr)
+				return (&net.Dialer{}).DialContext(ctx, network, tsAddr)
+			},
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When a request is made to the tunnelled host", func() {
+			resp, err := httpClient.Get(context.Background(), "http://dataset-api:22000/datasets")
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then it is dialled with its own dial function", func() {
+				So(tunnelled, ShouldResemble, []string{"dataset-api:22000"})
+			})
+		})
+
+		Convey("When a request is made to another host", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then it is dialled as normal", func() {
+				So(tunnelled, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,101 @@
+package rchttp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultSpoolThreshold is the size above which Download spools a response body to a
+// temporary file, when the client's SpoolThreshold is zero.
+const DefaultSpoolThreshold = 10 * 1024 * 1024
+
+// Download calls Get and returns the response body, held in memory if it is no larger
+// than the client's SpoolThreshold and otherwise spooled to a temporary file, which is
+// removed when the returned reader is closed. This protects services with small memory
+// limits from unexpectedly large responses. The caller must close the reader.
+func (c *Client) Download(ctx context.Context, url string) (io.ReadSeekCloser, *CallInfo, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	info := c.newCallInfo(resp, start)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxStatusErrorBody))
+		return nil, info, statusError(req, resp, b)
+	}
+
+	body, err := spool(resp.Body, c.spoolThreshold())
+	return body, info, err
+}
+
+func (c *Client) spoolThreshold() int64 {
+	if c.SpoolThreshold > 0 {
+		return c.SpoolThreshold
+	}
+	return DefaultSpoolThreshold
+}
+
+// spool reads r into memory if it is no larger than threshold, or otherwise into a
+// temporary file which is removed when the returned reader is closed.
+func spool(r io.Reader, threshold int64) (io.ReadSeekCloser, error) {
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, threshold+1))
+	if err != nil {
+		return nil, err
+	}
+	if n <= threshold {
+		return memorySpool{bytes.NewReader(buf.Bytes())}, nil
+	}
+
+	f, err := ioutil.TempFile("", "rchttp-spool-")
+	if err != nil {
+		return nil, err
+	}
+	file := &fileSpool{f}
+	if _, err = io.Copy(f, io.MultiReader(&buf, r)); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+type memorySpool struct {
+	*bytes.Reader
+}
+
+func (memorySpool) Close() error {
+	return nil
+}
+
+// fileSpool is a spooled body in a temporary file, removed on Close.
+type fileSpool struct {
+	*os.File
+}
+
+func (f *fileSpool) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package rchttp
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientDownload(t *testing.T) {
+	body := strings.Repeat("observation,", 100)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with a spool threshold", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("When a body under the threshold is downloaded", func() {
+			httpClient.SpoolThreshold = int64(len(body))
+			r, info, err := httpClient.Download(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			defer r.Close()
+
+			Convey("Then it is held in memory", func() {
+				So(info.StatusCode, ShouldEqual, 200)
+				So(r, ShouldHaveSameTypeAs, memorySpool{})
+				b, _ := ioutil.ReadAll(r)
+				So(string(b), ShouldEqual, body)
+			})
+		})
+
+		Convey("When a body over the threshold is downloaded", func() {
+			httpClient.SpoolThreshold = 100
+			r, _, err := httpClient.Download(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is spooled to a temporary file which can be re-read", func() {
+				file, ok := r.(*fileSpool)
+				So(ok, ShouldBeTrue)
+				b, _ := ioutil.ReadAll(r)
+				So(string(b), ShouldEqual, body)
+
+				_, err := r.Seek(0, io.SeekStart)
+				So(err, ShouldBeNil)
+				b, _ = ioutil.ReadAll(r)
+				So(string(b), ShouldEqual, body)
+
+				Convey("And the file is removed on Close", func() {
+					So(r.Close(), ShouldBeNil)
+					_, err := os.Stat(file.Name())
+					So(os.IsNotExist(err), ShouldBeTrue)
+				})
+			})
+		})
+
+		Convey("When the response is not 2xx", func() {
+			_, info, err := httpClient.Download(context.Background(), ts.URL+"/missing")
+
+			Convey("Then a StatusError is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &StatusError{})
+				So(info.StatusCode, ShouldEqual, 404)
+			})
+		})
+	})
+}
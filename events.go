@@ -0,0 +1,159 @@
+package rchttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+// Types of Event emitted by a Client.
+const (
+	EventRequestStart  EventType = "request_start"
+	EventRequestFinish EventType = "request_finish"
+	EventRetry         EventType = "retry"
+	// EventCircuitTransition is emitted when the CircuitBreaker's circuit for the host of
+	// a request opens, half-opens or closes.
+	EventCircuitTransition EventType = "circuit_transition"
+)
+
+// RetryReason classifies why a request is being retried, e.g. as a metric label.
+type RetryReason string
+
+// Reasons for a retry.
+const (
+	RetryReasonConnRefused RetryReason = "conn-refused"
+	RetryReasonTimeout     RetryReason = "timeout"
+	RetryReasonError       RetryReason = "error"
+	RetryReason5xx         RetryReason = "5xx"
+	RetryReason429         RetryReason = "429"
+	RetryReasonConflict    RetryReason = "conflict"
+	RetryReason4xx         RetryReason = "4xx"
+	// RetryReasonStatus is for responses outside 4xx and 5xx which a RetryPolicy retries.
+	RetryReasonStatus RetryReason = "status"
+)
+
+// Event describes something the client did, for monitoring client behaviour.
+type Event struct {
+	Type   EventType
+	Time   time.Time
+	Method string
+	URL    string
+	// Call is the name of the preset the request was made with (see WithPreset), if any.
+	Call string
+	// Attempt is the number of the retry about to be made (EventRetry only).
+	Attempt int
+	// Reason is why the retry is being made (EventRetry only).
+	Reason RetryReason
+	// StatusCode and Err are the outcome of the request (EventRequestFinish), or of the
+	// attempt which led to a retry (EventRetry).
+	StatusCode int
+	Err        error
+	// Duration is the total time taken by the request (EventRequestFinish only).
+	Duration time.Duration
+	// From and To are the states the circuit moved between (EventCircuitTransition only).
+	From CircuitState
+	To   CircuitState
+}
+
+// eventBus is a bounded queue of events which drops the oldest event when full.
+type eventBus struct {
+	mutex  sync.Mutex
+	events chan Event
+}
+
+// EnableEvents makes the client emit events on the channel returned by Events, holding at
+// most bufferSize events; when the buffer is full the oldest event is dropped, so a slow
+// consumer never blocks requests.
+func (c *Client) EnableEvents(bufferSize int) {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	c.events = &eventBus{events: make(chan Event, bufferSize)}
+}
+
+// Events returns the channel on which events are emitted, or nil if events are not enabled.
+func (c *Client) Events() <-chan Event {
+	if c.events == nil {
+		return nil
+	}
+	return c.events.events
+}
+
+// emit sends event to the event channel, if events are enabled, dropping the oldest
+// event if the channel is full.
+func (c *Client) emit(eventType EventType, req *http.Request, fill func(*Event)) {
+	if c.events == nil {
+		return
+	}
+	event := Event{Type: eventType, Time: time.Now(), Method: req.Method, URL: req.URL.String()}
+	if fill != nil {
+		fill(&event)
+	}
+
+	c.events.mutex.Lock()
+	defer c.events.mutex.Unlock()
+	for {
+		select {
+		case c.events.events <- event:
+			return
+		default:
+		}
+		select {
+		case <-c.events.events:
+		default:
+		}
+	}
+}
+
+// emitCircuitTransition emits an EventCircuitTransition for req if its host's circuit moved
+// from one state to another.
+func (c *Client) emitCircuitTransition(req *http.Request, from, to CircuitState) {
+	if from == to {
+		return
+	}
+	c.emit(EventCircuitTransition, req, func(event *Event) {
+		event.From, event.To = from, to
+	})
+}
+
+// outcome sets the status code (if any) and error of an event.
+func outcome(resp *http.Response, err error) func(*Event) {
+	return func(event *Event) {
+		if resp != nil {
+			event.StatusCode = resp.StatusCode
+		}
+		event.Err = err
+	}
+}
+
+// retryReason classifies the outcome of an attempt which is to be retried. An attempt with
+// a response is classified by its status, including statuses only a RetryPolicy retries.
+func retryReason(resp *http.Response, err error) RetryReason {
+	if resp != nil {
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			return RetryReason429
+		case resp.StatusCode == http.StatusConflict:
+			return RetryReasonConflict
+		case resp.StatusCode >= http.StatusInternalServerError:
+			return RetryReason5xx
+		case resp.StatusCode >= http.StatusBadRequest:
+			return RetryReason4xx
+		}
+		return RetryReasonStatus
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return RetryReasonConnRefused
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return RetryReasonTimeout
+	}
+	return RetryReasonError
+}
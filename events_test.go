@@ -0,0 +1,134 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientEvents(t *testing.T) {
+	Convey("Given an rchttp client with events enabled and a server which always fails", t, func() {
+		ts := rchttptest.NewTestServer(503)
+		defer ts.Close()
+
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}, MaxRetries: 2, RetryTime: time.Millisecond}
+		httpClient.EnableEvents(10)
+
+		Convey("When Get() is called", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then start, retry and finish events are emitted", func() {
+				var events []Event
+				for len(httpClient.Events()) > 0 {
+					events = append(events, <-httpClient.Events())
+				}
+				So(events, ShouldHaveLength, 4)
+				So(events[0].Type, ShouldEqual, EventRequestStart)
+				So(events[0].Method, ShouldEqual, "GET")
+				So(events[0].URL, ShouldEqual, ts.URL)
+				So(events[1].Type, ShouldEqual, EventRetry)
+				So(events[1].Attempt, ShouldEqual, 1)
+				So(events[1].StatusCode, ShouldEqual, 503)
+				So(events[1].Reason, ShouldEqual, RetryReason5xx)
+				So(events[2].Type, ShouldEqual, EventRetry)
+				So(events[2].Attempt, ShouldEqual, 2)
+				So(events[3].Type, ShouldEqual, EventRequestFinish)
+				So(events[3].StatusCode, ShouldEqual, 503)
+				So(events[3].Duration, ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with events enabled and a policy retrying a custom status", t, func() {
+		ts := rchttptest.NewTestServer(460)
+		defer ts.Close()
+
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}, MaxRetries: 1, RetryTime: time.Millisecond}
+		httpClient.RetryPolicy = RetryPolicyFunc(func(resp *http.Response, err error, attempt int) bool {
+			return resp != nil && resp.StatusCode == 460
+		})
+		httpClient.EnableEvents(10)
+
+		Convey("When Get() is called", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the retry is classified by its status", func() {
+				So(len(httpClient.Events()), ShouldEqual, 3)
+				<-httpClient.Events()
+				retry := <-httpClient.Events()
+				So(retry.Type, ShouldEqual, EventRetry)
+				So(retry.StatusCode, ShouldEqual, 460)
+				So(retry.Reason, ShouldEqual, RetryReason4xx)
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with a small event buffer", t, func() {
+		ts := rchttptest.NewTestServer(200)
+		defer ts.Close()
+
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}}
+		httpClient.EnableEvents(1)
+
+		Convey("When more events are emitted than fit in the buffer", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the oldest events are dropped", func() {
+				So(len(httpClient.Events()), ShouldEqual, 1)
+				So((<-httpClient.Events()).Type, ShouldEqual, EventRequestFinish)
+			})
+		})
+	})
+
+	Convey("Given an rchttp client without events enabled", t, func() {
+		Convey("Then there is no event channel", func() {
+			So(NewClient().(*Client).Events(), ShouldBeNil)
+		})
+	})
+}
+
+func TestRetryReason(t *testing.T) {
+	Convey("Given a server which is not listening", t, func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		Convey("Then a refused connection is classified as conn-refused", func() {
+			_, err := http.Get("http://" + addr)
+			So(retryReason(nil, err), ShouldEqual, RetryReasonConnRefused)
+		})
+	})
+
+	Convey("Given attempts which failed with a status", t, func() {
+		Convey("Then they are classified by status", func() {
+			So(retryReason(&http.Response{StatusCode: 502}, nil), ShouldEqual, RetryReason5xx)
+			So(retryReason(&http.Response{StatusCode: 429}, nil), ShouldEqual, RetryReason429)
+			So(retryReason(&http.Response{StatusCode: 409}, nil), ShouldEqual, RetryReasonConflict)
+		})
+
+		Convey("Then statuses retried only by a custom policy are classified by status too", func() {
+			So(retryReason(&http.Response{StatusCode: 460}, nil), ShouldEqual, RetryReason4xx)
+			So(retryReason(&http.Response{StatusCode: 304}, nil), ShouldEqual, RetryReasonStatus)
+		})
+	})
+
+	Convey("Given an attempt which timed out", t, func() {
+		Convey("Then it is classified as a timeout", func() {
+			err := &TimeoutError{Reason: ErrAttemptTimeout, Err: errors.New("Client.Timeout exceeded")}
+			So(retryReason(nil, err), ShouldEqual, RetryReasonTimeout)
+		})
+	})
+}
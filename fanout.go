@@ -0,0 +1,68 @@
+package rchttp
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/go-ns/common"
+	"golang.org/x/net/context"
+)
+
+const fanOutKey = contextKey("rchttp-fan-out")
+
+// FanOutReport summarises the outbound requests made within the lifetime of a context,
+// typically that of one inbound request, to help find N+1 call patterns.
+type FanOutReport struct {
+	// CorrelationID is the correlation ID of the context, if any.
+	CorrelationID string
+	// Calls is the total number of outbound requests (excluding retries).
+	Calls int
+	// Hosts holds the number of outbound requests to each host.
+	Hosts    map[string]int
+	Started  time.Time
+	Duration time.Duration
+}
+
+type fanOutAccount struct {
+	mutex  sync.Mutex
+	report FanOutReport
+}
+
+// WithFanOutAccounting returns a context which counts the outbound requests made with it
+// (or contexts derived from it) by any Client, and passes the totals to report once ctx is
+// done. ctx must be cancellable, e.g. the context of an inbound request; if it is not,
+// ctx is returned unchanged.
+func WithFanOutAccounting(ctx context.Context, report func(FanOutReport)) context.Context {
+	if ctx.Done() == nil {
+		return ctx
+	}
+	account := &fanOutAccount{report: FanOutReport{
+		CorrelationID: common.GetRequestId(ctx),
+		Hosts:         make(map[string]int),
+		Started:       time.Now(),
+	}}
+
+	go func() {
+		<-ctx.Done()
+		account.mutex.Lock()
+		r := account.report
+		r.Duration = time.Since(r.Started)
+		account.mutex.Unlock()
+		report(r)
+	}()
+
+	return context.WithValue(ctx, fanOutKey, account)
+}
+
+// countFanOut records an outbound request in the fan-out account of ctx, if any.
+func countFanOut(ctx context.Context, req *http.Request) {
+	account, ok := ctx.Value(fanOutKey).(*fanOutAccount)
+	if !ok {
+		return
+	}
+	account.mutex.Lock()
+	account.report.Calls++
+	account.report.Hosts[req.URL.Host]++
+	account.mutex.Unlock()
+}
@@ -0,0 +1,60 @@
+package rchttp
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithFanOutAccounting(t *testing.T) {
+	ts := rchttptest.NewTestServer(200)
+	defer ts.Close()
+	tsURL, _ := url.Parse(ts.URL)
+
+	Convey("Given a context with fan-out accounting for an inbound request", t, func() {
+		reports := make(chan FanOutReport, 1)
+		inbound, cancel := context.WithCancel(common.WithRequestId(context.Background(), "inbound1"))
+		ctx := WithFanOutAccounting(inbound, func(r FanOutReport) {
+			reports <- r
+		})
+		httpClient := NewClient()
+
+		Convey("When several outbound requests are made and the inbound request completes", func() {
+			for i := 0; i < 3; i++ {
+				callCtx, callCancel := context.WithTimeout(ctx, time.Second)
+				resp, err := httpClient.Get(callCtx, ts.URL)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				callCancel()
+			}
+			cancel()
+
+			Convey("Then a report of the fan-out is made", func() {
+				var report FanOutReport
+				select {
+				case report = <-reports:
+				case <-time.After(time.Second):
+				}
+				So(report.CorrelationID, ShouldEqual, "inbound1")
+				So(report.Calls, ShouldEqual, 3)
+				So(report.Hosts, ShouldResemble, map[string]int{tsURL.Host: 3})
+				So(report.Duration, ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+
+	Convey("Given a context which is never done", t, func() {
+		ctx := context.Background()
+
+		Convey("Then fan-out accounting is not enabled", func() {
+			accounted := WithFanOutAccounting(ctx, func(FanOutReport) {})
+			So(accounted, ShouldResemble, ctx)
+			So(accounted.Value(fanOutKey), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,40 @@
+package rchttp
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// canSendDirectly reports whether a request made with ctx, for which retries are disabled,
+// can be sent in a single attempt without the retry machinery of send: nothing needs to
+// run around the attempt (auth, rate limiting, certificate monitoring, circuit breaking,
+// hedging, chunked upload fallback, header casing or progress reporting).
+func (c *Client) canSendDirectly(ctx context.Context) bool {
+	return c.rateLimitPacer == nil &&
+		c.rateLimiter() == nil &&
+		c.CircuitBreaker == nil &&
+		c.Hedging == nil &&
+		c.ChunkedUploader == nil &&
+		len(c.ExactCaseHeaders) == 0 &&
+		c.certMonitor == nil &&
+		c.AuthRefresher == nil &&
+		c.tokenSource(ctx) == nil &&
+		c.basicAuth(ctx) == nil &&
+		progressCallback(ctx) == nil
+}
+
+// sendDirectly sends req in a single attempt, avoiding the closures and buffering needed
+// for retries.
+func (c *Client) sendDirectly(ctx context.Context, req *http.Request) (*http.Response, error) {
+	resp, err := ctxhttp.Do(ctx, c.attemptClient(ctx, c.HTTPClient), req)
+	err = resourceExhausted(err)
+	if err == nil {
+		err = possiblyCreated(req, resp)
+	}
+	if c.ErrorBodySnapshotSize > 0 && wantRetry(err, resp) {
+		return c.snapshotFailure(req, resp, err)
+	}
+	return resp, err
+}
@@ -0,0 +1,85 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientFastPath(t *testing.T) {
+	ts := rchttptest.NewTestServer(http.StatusInternalServerError)
+	defer ts.Close()
+
+	Convey("Given an rchttp client with retries disabled and no hooks", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(`{"a":1}`))
+
+		Convey("Then requests are sent directly", func() {
+			So(httpClient.retriesEnabled(context.Background(), req), ShouldBeFalse)
+			So(httpClient.canSendDirectly(context.Background()), ShouldBeTrue)
+		})
+
+		Convey("When a request fails", func() {
+			calls := ts.GetCalls(0)
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then its response is returned after a single attempt with its body sent", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusInternalServerError)
+				call, _ := unmarshallResp(resp)
+				So(call.Body, ShouldEqual, `{"a":1}`)
+				So(ts.GetCalls(0), ShouldEqual, calls+1)
+			})
+		})
+
+		Convey("And with ErrorBodySnapshotSize set, the failure is still snapshotted", func() {
+			httpClient.ErrorBodySnapshotSize = 10
+			_, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldHaveSameTypeAs, &RequestError{})
+			So(err.(*RequestError).RequestBody, ShouldEqual, `{"a":1}`)
+		})
+
+		Convey("But requests are not sent directly if they need auth", func() {
+			httpClient.BasicAuth = &BasicAuth{Username: "user", Password: "pass"}
+			So(httpClient.canSendDirectly(context.Background()), ShouldBeFalse)
+		})
+
+		Convey("But requests are not sent directly if they report progress", func() {
+			ctx := WithProgress(context.Background(), func(done, total int64) {})
+			So(httpClient.canSendDirectly(ctx), ShouldBeFalse)
+		})
+
+		Convey("But requests with retries enabled are retried", func() {
+			httpClient.MaxRetries = 1
+			httpClient.RetryTime = time.Millisecond
+			httpClient.NonIdempotentRetries = true
+			calls := ts.GetCalls(0)
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(ts.GetCalls(0), ShouldEqual, calls+2)
+		})
+	})
+}
+
+func BenchmarkClientDoFastPath(b *testing.B) {
+	ts := rchttptest.NewTestServer(http.StatusOK)
+	defer ts.Close()
+	httpClient := &Client{HTTPClient: &http.Client{}}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		resp, err := httpClient.Get(context.Background(), ts.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,19 @@
+package rchttp
+
+import (
+	"golang.org/x/net/context"
+)
+
+// Get calls client.GetAs and returns the response body decoded into a T.
+func Get[T any](ctx context.Context, client *Client, url string) (T, error) {
+	var v T
+	_, err := client.GetAs(ctx, url, &v)
+	return v, err
+}
+
+// Post calls client.PostAs with body and returns the response body decoded into a TResp.
+func Post[TReq, TResp any](ctx context.Context, client *Client, url string, body TReq) (TResp, error) {
+	var v TResp
+	_, err := client.PostAs(ctx, url, body, &v)
+	return v, err
+}
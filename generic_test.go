@@ -0,0 +1,53 @@
+package rchttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type genericDataset struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+func TestGenericHelpers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "POST" {
+			var in genericDataset
+			json.NewDecoder(r.Body).Decode(&in)
+			in.Title = "created " + in.ID
+			json.NewEncoder(w).Encode(in)
+			return
+		}
+		w.Write([]byte(`{"id":"cpih","title":"CPIH"}`))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("When Get is called with a type", func() {
+			dataset, err := Get[genericDataset](context.Background(), httpClient, ts.URL)
+
+			Convey("Then the typed response is returned", func() {
+				So(err, ShouldBeNil)
+				So(dataset, ShouldResemble, genericDataset{ID: "cpih", Title: "CPIH"})
+			})
+		})
+
+		Convey("When Post is called with request and response types", func() {
+			dataset, err := Post[genericDataset, genericDataset](context.Background(), httpClient, ts.URL, genericDataset{ID: "cpih"})
+
+			Convey("Then the typed response is returned", func() {
+				So(err, ShouldBeNil)
+				So(dataset.Title, ShouldEqual, "created cpih")
+			})
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package rchttp
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultHashRingReplicas is the number of points each target has on a HashRing, when
+// NewHashRing is given zero.
+const DefaultHashRingReplicas = 100
+
+const routingKeyKey = contextKey("rchttp-routing-key")
+
+// ErrNoTargets is returned by NewHashRing when given no base URLs.
+var ErrNoTargets = errors.New("rchttp: no target base URLs")
+
+// HashRing routes requests across several base URLs by consistent hashing of a routing
+// key, so that requests for the same key (e.g. a dataset ID) go to the same replica, and
+// only a small share of keys move when a replica is added or removed.
+type HashRing struct {
+	points  []uint32
+	targets map[uint32]*url.URL
+}
+
+// NewHashRing returns a HashRing across baseURLs, each with replicas points on the ring
+// (DefaultHashRingReplicas if zero).
+func NewHashRing(baseURLs []string, replicas int) (*HashRing, error) {
+	if len(baseURLs) == 0 {
+		return nil, ErrNoTargets
+	}
+	if replicas <= 0 {
+		replicas = DefaultHashRingReplicas
+	}
+	ring := &HashRing{targets: make(map[uint32]*url.URL, len(baseURLs)*replicas)}
+	for _, baseURL := range baseURLs {
+		target, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < replicas; i++ {
+			point := ringHash(strconv.Itoa(i) + "-" + baseURL)
+			if _, ok := ring.targets[point]; ok {
+				continue
+			}
+			ring.targets[point] = target
+			ring.points = append(ring.points, point)
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring, nil
+}
+
+// Pick returns the base URL which key routes to.
+func (ring *HashRing) Pick(key string) *url.URL {
+	hash := ringHash(key)
+	i := sort.Search(len(ring.points), func(i int) bool { return ring.points[i] >= hash })
+	if i == len(ring.points) {
+		i = 0
+	}
+	return ring.targets[ring.points[i]]
+}
+
+// ringHash hashes s onto the ring. A checksum such as CRC-32 would cluster the points of
+// similar base URLs, unbalancing the ring.
+func ringHash(s string) uint32 {
+	sum := md5.Sum([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+// WithRoutingKey returns a context which makes requests made with it routed by key across
+// the client's HashRing, rather than by their path.
+func WithRoutingKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, routingKeyKey, key)
+}
+
+// route makes a request for a relative URL (with no host) to the base URL picked from the
+// client's HashRing by the routing key in ctx, or else by the request's path.
+func (c *Client) route(ctx context.Context, req *http.Request) {
+	if c.HashRing == nil || req.URL.Host != "" {
+		return
+	}
+	key, ok := ctx.Value(routingKeyKey).(string)
+	if !ok {
+		key = req.URL.Path
+	}
+	target := c.HashRing.Pick(key)
+
+	u := *req.URL
+	u.Scheme = target.Scheme
+	u.Host = target.Host
+	u.Path = strings.TrimSuffix(target.Path, "/") + "/" + strings.TrimPrefix(req.URL.Path, "/")
+	u.RawPath = ""
+	req.URL = &u
+	req.Host = target.Host
+}
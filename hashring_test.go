@@ -0,0 +1,94 @@
+package rchttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientHashRing(t *testing.T) {
+	hits := make(map[string][]string)
+	var urls []string
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("replica%d", i)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[name] = append(hits[name], r.URL.Path)
+		}))
+		defer ts.Close()
+		urls = append(urls, ts.URL+"/v1")
+	}
+
+	Convey("Given an rchttp client routing across replicas by consistent hashing", t, func() {
+		for name := range hits {
+			delete(hits, name)
+		}
+		ring, err := NewHashRing(urls, 0)
+		So(err, ShouldBeNil)
+		httpClient := &Client{HTTPClient: &http.Client{}, HashRing: ring}
+
+		Convey("When requests are made for the same routing key", func() {
+			for _, path := range []string{"/datasets/cpih", "/datasets/cpih/editions", "/datasets/cpih/versions"} {
+				resp, err := httpClient.Get(WithRoutingKey(context.Background(), "cpih"), path)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then they all go to one replica, under its base path", func() {
+				So(hits, ShouldHaveLength, 1)
+				for _, paths := range hits {
+					So(paths, ShouldResemble, []string{"/v1/datasets/cpih", "/v1/datasets/cpih/editions", "/v1/datasets/cpih/versions"})
+				}
+			})
+		})
+
+		Convey("When requests are made for many paths without a routing key", func() {
+			for i := 0; i < 50; i++ {
+				resp, err := httpClient.Get(context.Background(), fmt.Sprintf("/datasets/%d", i))
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then they are spread across the replicas", func() {
+				So(hits, ShouldHaveLength, 3)
+			})
+		})
+
+		Convey("When a request is made for an absolute URL", func() {
+			resp, err := httpClient.Get(context.Background(), urls[0]+"/datasets")
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then it is not rerouted", func() {
+				So(hits["replica0"], ShouldResemble, []string{"/v1/datasets"})
+			})
+		})
+
+		Convey("When a replica is added", func() {
+			bigger, err := NewHashRing(append(urls, "http://localhost:1"), 0)
+			So(err, ShouldBeNil)
+
+			Convey("Then most keys keep their replica", func() {
+				moved := 0
+				for i := 0; i < 1000; i++ {
+					key := fmt.Sprintf("dataset-%d", i)
+					if ring.Pick(key).String() != bigger.Pick(key).String() {
+						moved++
+					}
+				}
+				So(moved, ShouldBeLessThan, 400)
+			})
+		})
+	})
+
+	Convey("Given no base URLs", t, func() {
+		_, err := NewHashRing(nil, 0)
+
+		Convey("Then NewHashRing returns ErrNoTargets", func() {
+			So(err, ShouldEqual, ErrNoTargets)
+		})
+	})
+}
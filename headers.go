@@ -0,0 +1,66 @@
+package rchttp
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HeaderTooLargeError is returned by Do, before any connection is made, when the headers
+// of a request exceed the client's MaxHeaderBytes. Largest is the header contributing the
+// most bytes, usually the correlation ID chain.
+type HeaderTooLargeError struct {
+	Method  string
+	URL     string
+	Size    int
+	Limit   int
+	Largest string
+}
+
+func (e *HeaderTooLargeError) Error() string {
+	return fmt.Sprintf("rchttp: %s %s headers are %d bytes, over the limit of %d (largest: %s)", e.Method, e.URL, e.Size, e.Limit, e.Largest)
+}
+
+// headerSize returns the size of h on the wire ("Key: value\r\n" per value) and the key
+// of the header contributing the most bytes.
+func headerSize(h http.Header) (int, string) {
+	size, largestSize, largest := 0, 0, ""
+	for key, values := range h {
+		keySize := 0
+		for _, value := range values {
+			keySize += len(key) + len(value) + 4
+		}
+		size += keySize
+		if keySize > largestSize {
+			largestSize, largest = keySize, key
+		}
+	}
+	return size, largest
+}
+
+// applyHeaderCasing moves the values of the headers in the client's ExactCaseHeaders from
+// their canonical keys to their exact names, so that they are sent with that casing.
+func (c *Client) applyHeaderCasing(req *http.Request) {
+	for _, name := range c.ExactCaseHeaders {
+		key := http.CanonicalHeaderKey(name)
+		if key == name {
+			continue
+		}
+		if values, ok := req.Header[key]; ok {
+			delete(req.Header, key)
+			req.Header[name] = values
+		}
+	}
+}
+
+// checkHeaderSize returns a *HeaderTooLargeError if the headers of req exceed the client's
+// MaxHeaderBytes.
+func (c *Client) checkHeaderSize(req *http.Request) error {
+	if c.MaxHeaderBytes <= 0 {
+		return nil
+	}
+	size, largest := headerSize(req.Header)
+	if size <= c.MaxHeaderBytes {
+		return nil
+	}
+	return &HeaderTooLargeError{Method: req.Method, URL: req.URL.String(), Size: size, Limit: c.MaxHeaderBytes, Largest: largest}
+}
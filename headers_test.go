@@ -0,0 +1,99 @@
+package rchttp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientMaxHeaderBytes(t *testing.T) {
+	ts := rchttptest.NewTestServer(200)
+	defer ts.Close()
+
+	Convey("Given an rchttp client with a header size limit", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxHeaderBytes: 512}
+
+		Convey("When the correlation ID chain takes the headers over the limit", func() {
+			ctx := common.WithRequestId(context.Background(), strings.Repeat("abcdefghij,", 60))
+			resp, err := httpClient.Get(ctx, ts.URL)
+
+			Convey("Then the request is rejected before being sent", func() {
+				So(resp, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &HeaderTooLargeError{})
+				So(err.(*HeaderTooLargeError).Limit, ShouldEqual, 512)
+				So(err.(*HeaderTooLargeError).Largest, ShouldEqual, common.RequestHeaderKey)
+				So(ts.GetCalls(0), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the headers are within the limit", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the request is sent", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestClientExactCaseHeaders(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	requests := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			var head strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				head.WriteString(line)
+				if err != nil || line == "\r\n" {
+					break
+				}
+			}
+			requests <- head.String()
+			conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+
+	Convey("Given an rchttp client with exact case headers", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, ExactCaseHeaders: []string{"x-legacy-TOKEN"}}
+
+		Convey("When a request is made with that header", func() {
+			req, _ := http.NewRequest("GET", "http://"+l.Addr().String(), nil)
+			req.Header.Set("X-Legacy-Token", "abc")
+			req.Header.Set("X-Other", "def")
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			head := <-requests
+
+			Convey("Then it is sent with its exact casing", func() {
+				So(head, ShouldContainSubstring, "\r\nx-legacy-TOKEN: abc\r\n")
+				So(head, ShouldNotContainSubstring, "X-Legacy-Token")
+				So(head, ShouldContainSubstring, "\r\nX-Other: def\r\n")
+			})
+
+			Convey("And the caller's request is not changed", func() {
+				So(req.Header.Get("X-Legacy-Token"), ShouldEqual, "abc")
+			})
+		})
+	})
+}
@@ -0,0 +1,159 @@
+package rchttp
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// Defaults for a HedgePolicy.
+const (
+	DefaultHedgeMinSamples = 20
+	hedgeSamples           = 100
+)
+
+// HedgePolicy sends a second copy of a GET request which has not had a response within
+// the given percentile of the recent latencies of GETs to its host, and uses whichever
+// response comes first, cancelling the other, to cut tail latency. Until MinSamples
+// latencies have been recorded for a host, Delay is waited instead; it is also the least
+// wait before hedging.
+type HedgePolicy struct {
+	// Percentile of recent latencies after which to hedge, e.g. 95. Values outside 0-100
+	// are clamped to that range.
+	Percentile float64
+	Delay      time.Duration
+	// MinSamples is the number of latencies needed for a host before Percentile is used,
+	// DefaultHedgeMinSamples if zero.
+	MinSamples int
+
+	mutex     sync.Mutex
+	latencies map[string][]time.Duration
+	next      map[string]int
+}
+
+// NewHedgePolicy returns a HedgePolicy hedging GETs after the percentile of recent
+// latencies to their host, and waiting at least delay.
+func NewHedgePolicy(percentile float64, delay time.Duration) *HedgePolicy {
+	return &HedgePolicy{Percentile: percentile, Delay: delay}
+}
+
+// hedgeable reports whether req may be sent twice: it must be a GET or HEAD with no body.
+func hedgeable(req *http.Request) bool {
+	return (req.Method == "" || req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+		(req.Body == nil || req.Body == http.NoBody)
+}
+
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	index   int
+	latency time.Duration
+}
+
+// do sends req with client, sending a second copy of it if there is no response within
+// the hedge delay, and returns the first response (or, if both fail, the last error),
+// cancelling the other copy. The response's body cancels its request when closed.
+func (h *HedgePolicy) do(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		hedgeCtx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		started := time.Now()
+		go func() {
+			resp, err := ctxhttp.Do(hedgeCtx, client, req.Clone(hedgeCtx))
+			results <- hedgeResult{resp: resp, err: err, index: index, latency: time.Since(started)}
+		}()
+	}
+
+	send()
+	inflight := 1
+	timer := time.NewTimer(h.delay(host))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) == 1 {
+				send()
+				inflight++
+			}
+		case result := <-results:
+			inflight--
+			if result.err != nil {
+				cancels[result.index]()
+				if inflight == 0 {
+					return nil, result.err
+				}
+				continue
+			}
+
+			h.record(host, result.latency)
+			for i, cancel := range cancels {
+				if i != result.index {
+					cancel()
+				}
+			}
+			if inflight > 0 {
+				go discardHedge(results)
+			}
+			result.resp.Body = &finishOnClose{ReadCloser: result.resp.Body, finish: cancels[result.index]}
+			return result.resp, nil
+		}
+	}
+}
+
+// discardHedge closes the response, if any, of the cancelled copy of a request.
+func discardHedge(results chan hedgeResult) {
+	if result := <-results; result.resp != nil {
+		result.resp.Body.Close()
+	}
+}
+
+// delay returns how long to wait for a response from host before hedging.
+func (h *HedgePolicy) delay(host string) time.Duration {
+	h.mutex.Lock()
+	samples := append([]time.Duration(nil), h.latencies[host]...)
+	h.mutex.Unlock()
+
+	minSamples := h.MinSamples
+	if minSamples <= 0 {
+		minSamples = DefaultHedgeMinSamples
+	}
+	if len(samples) < minSamples {
+		return h.Delay
+	}
+	percentile := h.Percentile
+	if !(percentile >= 0) { // also catches NaN
+		percentile = 0
+	} else if percentile > 100 {
+		percentile = 100
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	delay := samples[int(float64(len(samples)-1)*percentile/100)]
+	if delay < h.Delay {
+		return h.Delay
+	}
+	return delay
+}
+
+// record adds the latency of a response from host, keeping the most recent.
+func (h *HedgePolicy) record(host string, latency time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.latencies == nil {
+		h.latencies = make(map[string][]time.Duration)
+		h.next = make(map[string]int)
+	}
+	if len(h.latencies[host]) < hedgeSamples {
+		h.latencies[host] = append(h.latencies[host], latency)
+		return
+	}
+	h.latencies[host][h.next[host]] = latency
+	h.next[host] = (h.next[host] + 1) % hedgeSamples
+}
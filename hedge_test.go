@@ -0,0 +1,82 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientHedging(t *testing.T) {
+	var calls, cancelled int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				atomic.AddInt32(&cancelled, 1)
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client which hedges GETs", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, Hedging: NewHedgePolicy(95, 20*time.Millisecond)}
+
+		Convey("When the first copy of a request is slow", func() {
+			start := time.Now()
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then the response to the second copy is used", func() {
+				So(string(body), ShouldEqual, "ok")
+				So(time.Since(start), ShouldBeLessThan, 500*time.Millisecond)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+			})
+
+			Convey("And the first copy is cancelled", func() {
+				for i := 0; i < 50 && atomic.LoadInt32(&cancelled) == 0; i++ {
+					time.Sleep(10 * time.Millisecond)
+				}
+				So(atomic.LoadInt32(&cancelled), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a hedge policy", t, func() {
+		policy := &HedgePolicy{Percentile: 90, Delay: 5 * time.Millisecond, MinSamples: 10}
+
+		Convey("Then the delay is used until enough latencies are recorded", func() {
+			policy.record("dataset-api", time.Second)
+			So(policy.delay("dataset-api"), ShouldEqual, 5*time.Millisecond)
+		})
+
+		Convey("Then the percentile of recent latencies is used once they are", func() {
+			for i := 1; i <= 150; i++ {
+				policy.record("dataset-api", time.Duration(i)*time.Millisecond)
+			}
+			// the last 100 latencies are 51ms to 150ms
+			So(policy.delay("dataset-api"), ShouldEqual, 140*time.Millisecond)
+			So(policy.delay("filter-api"), ShouldEqual, 5*time.Millisecond)
+		})
+
+		Convey("Then a percentile outside 0-100 is clamped rather than panicking", func() {
+			for i := 1; i <= 100; i++ {
+				policy.record("dataset-api", time.Duration(i)*time.Millisecond)
+			}
+			policy.Percentile = 150
+			So(policy.delay("dataset-api"), ShouldEqual, 100*time.Millisecond)
+			policy.Percentile = -10
+			So(policy.delay("dataset-api"), ShouldEqual, 5*time.Millisecond)
+		})
+	})
+}
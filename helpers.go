@@ -0,0 +1,191 @@
+package rchttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultCallInfoHeaders are the response headers kept in CallInfo when the client's
+// CallInfoHeaders is nil. A trailing "*" matches any header with that prefix.
+var DefaultCallInfoHeaders = []string{"ETag", "Content-Type", "Cache-Control", "Last-Modified", "Location", "RateLimit-*", "X-RateLimit-*", "Retry-After"}
+
+// CallInfo describes the response to a call made by one of the JSON/bytes helpers.
+type CallInfo struct {
+	StatusCode int
+	// Header holds only the allowlisted response headers (see Client.CallInfoHeaders).
+	Header   http.Header
+	Duration time.Duration
+	// RateLimit holds the parsed RateLimit-* headers, if the response had any.
+	RateLimit *RateLimit
+	// Connection describes the protocol and TLS parameters the response was received over.
+	Connection *ConnectionInfo
+	// Redirects are the redirects followed to get the response, if any.
+	Redirects []Redirect
+
+	// contentType and etag are the response Content-Type and ETag, regardless of CallInfoHeaders.
+	contentType string
+	etag        string
+}
+
+// StatusError is returned by the JSON/bytes helpers when the response status is not 2xx,
+// wrapped in a *ProblemDetails if the response body is a problem document.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("rchttp: %s %s returned unexpected status %d", e.Method, e.URL, e.StatusCode)
+}
+
+// GetBytes calls Get and returns the response body, transcoded to UTF-8 if it has a
+// charset (see UnsupportedCharsetError), or a *StatusError (or *ProblemDetails) if the
+// status is not 2xx.
+func (c *Client) GetBytes(ctx context.Context, url string) ([]byte, *CallInfo, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.doBytes(ctx, req)
+}
+
+// GetJSON calls Get, accepting JSON, and decodes the response body into v.
+func (c *Client) GetJSON(ctx context.Context, url string, v interface{}) (*CallInfo, error) {
+	return c.doCodec(ctx, "GET", url, JSONCodec, false, nil, v)
+}
+
+// PostJSON calls Post with body encoded as JSON, and decodes the response body into v
+// (unless v is nil). Like any POST it is not retried unless non-idempotent retries are
+// allowed, by the client (see AllowNonIdempotentRetries) or ctx (WithNonIdempotentRetries).
+func (c *Client) PostJSON(ctx context.Context, url string, body, v interface{}) (*CallInfo, error) {
+	return c.doCodec(ctx, "POST", url, JSONCodec, false, body, v)
+}
+
+// PutJSON calls Put with body encoded as JSON, and decodes the response body into v
+// (unless v is nil).
+func (c *Client) PutJSON(ctx context.Context, url string, body, v interface{}) (*CallInfo, error) {
+	return c.doCodec(ctx, "PUT", url, JSONCodec, false, body, v)
+}
+
+// doCodec makes a request with body encoded by codec, and decodes the response body into
+// v with codec or, if negotiate is set, the codec registered for the response Content-Type.
+func (c *Client) doCodec(ctx context.Context, method, url string, codec Codec, negotiate bool, body, v interface{}) (*CallInfo, error) {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := codec.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", codec.ContentType())
+	if body != nil {
+		req.Header.Set("Content-Type", codec.ContentType())
+	}
+
+	b, info, err := c.doBytes(ctx, req)
+	if err != nil {
+		return info, err
+	}
+	if v != nil && len(b) > 0 {
+		if negotiate {
+			if responseCodec, ok := CodecFor(info.contentType); ok {
+				codec = responseCodec
+			}
+		}
+		if err := codec.Unmarshal(b, v); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// doBytes calls Do and reads the response body, transcoded to UTF-8 and then passed through
+// the client's ResponseTransformers, returning a *StatusError (or *ProblemDetails) with the
+// body as received if the status is not 2xx.
+func (c *Client) doBytes(ctx context.Context, req *http.Request) ([]byte, *CallInfo, error) {
+	start := time.Now()
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	info := c.newCallInfo(resp, start)
+	if err != nil {
+		return nil, info, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, info, statusError(req, resp, b)
+	}
+	if b, err = toUTF8(info.contentType, b); err != nil {
+		return nil, info, err
+	}
+	if b, err = c.transformResponseBody(resp, b); err != nil {
+		return nil, info, err
+	}
+	return b, info, nil
+}
+
+// newCallInfo describes resp to a call made at start.
+func (c *Client) newCallInfo(resp *http.Response, start time.Time) *CallInfo {
+	info := &CallInfo{
+		StatusCode: resp.StatusCode,
+		Header:     filterHeaders(resp.Header, c.callInfoHeaders()),
+		Duration:   time.Since(start),
+
+		contentType: resp.Header.Get("Content-Type"),
+		etag:        resp.Header.Get("ETag"),
+	}
+	info.RateLimit, _ = ParseRateLimit(resp.Header)
+	info.Connection = ResponseConnectionInfo(resp)
+	info.Redirects = RedirectHistory(resp)
+	return info
+}
+
+func (c *Client) callInfoHeaders() []string {
+	if c.CallInfoHeaders != nil {
+		return c.CallInfoHeaders
+	}
+	return DefaultCallInfoHeaders
+}
+
+// filterHeaders returns the headers in h matching allowed, where a trailing "*" in
+// allowed matches any header with that prefix.
+func filterHeaders(h http.Header, allowed []string) http.Header {
+	filtered := make(http.Header)
+	for key, values := range h {
+		for _, a := range allowed {
+			if headerMatches(key, a) {
+				filtered[key] = values
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+func headerMatches(key, pattern string) bool {
+	if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+		return strings.HasPrefix(strings.ToLower(key), strings.ToLower(prefix))
+	}
+	return strings.EqualFold(key, pattern)
+}
@@ -0,0 +1,88 @@
+package rchttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONHelpers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("RateLimit-Remaining", "9")
+		w.Header().Set("X-Internal-Debug", "lots of data")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":       r.Method,
+			"accept":       r.Header.Get("Accept"),
+			"content_type": r.Header.Get("Content-Type"),
+			"name":         body["name"],
+		})
+	}))
+	defer ts.Close()
+
+	Convey("Given a default rchttp client", t, func() {
+		httpClient := NewClient().(*Client)
+
+		Convey("When GetJSON() is called", func() {
+			var result map[string]string
+			info, err := httpClient.GetJSON(context.Background(), ts.URL, &result)
+			So(err, ShouldBeNil)
+
+			Convey("Then the response is decoded and only allowlisted headers are returned", func() {
+				So(result["method"], ShouldEqual, "GET")
+				So(result["accept"], ShouldEqual, "application/json")
+				So(info.StatusCode, ShouldEqual, 200)
+				So(info.Header.Get("ETag"), ShouldEqual, `"v1"`)
+				So(info.Header.Get("RateLimit-Remaining"), ShouldEqual, "9")
+				So(info.Header.Get("Content-Type"), ShouldEqual, "application/json")
+				So(info.Header, ShouldNotContainKey, "X-Internal-Debug")
+				So(info.Header, ShouldNotContainKey, "Date")
+			})
+		})
+
+		Convey("When PostJSON() is called", func() {
+			var result map[string]string
+			_, err := httpClient.PostJSON(context.Background(), ts.URL, map[string]string{"name": "cpih"}, &result)
+			So(err, ShouldBeNil)
+
+			Convey("Then the body is sent as JSON", func() {
+				So(result["method"], ShouldEqual, "POST")
+				So(result["content_type"], ShouldEqual, "application/json")
+				So(result["name"], ShouldEqual, "cpih")
+			})
+		})
+
+		Convey("When GetBytes() is called on a URL which is not found", func() {
+			b, info, err := httpClient.GetBytes(context.Background(), ts.URL+"/missing")
+
+			Convey("Then a StatusError is returned", func() {
+				So(b, ShouldBeNil)
+				So(info.StatusCode, ShouldEqual, http.StatusNotFound)
+				So(err, ShouldHaveSameTypeAs, &StatusError{})
+				So(string(err.(*StatusError).Body), ShouldEqual, `{"error":"not found"}`)
+			})
+		})
+
+		Convey("When a custom header allowlist is set", func() {
+			httpClient.CallInfoHeaders = []string{"x-internal-*"}
+			_, info, err := httpClient.GetBytes(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then only those headers are returned", func() {
+				So(info.Header, ShouldResemble, http.Header{"X-Internal-Debug": {"lots of data"}})
+			})
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package rchttp
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+const nonIdempotentRetriesKey = contextKey("rchttp-non-idempotent-retries")
+
+// IdempotencyKeyHeader is the header which, when set on a request, marks it as safe to
+// retry whatever its method, as the server is expected to deduplicate repeats of it.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// AllowNonIdempotentRetries sets whether requests with methods which are not idempotent,
+// e.g. POST and PATCH, are retried like any other. By default only GET, HEAD, PUT, DELETE,
+// OPTIONS and TRACE requests, and those with an Idempotency-Key header, are retried, so
+// that a retry cannot repeat a side effect in the downstream API.
+func (c *Client) AllowNonIdempotentRetries(allow bool) {
+	c.NonIdempotentRetries = allow
+}
+
+// WithNonIdempotentRetries returns a context which overrides the client's
+// NonIdempotentRetries for requests made with it.
+func WithNonIdempotentRetries(ctx context.Context, allow bool) context.Context {
+	return context.WithValue(ctx, nonIdempotentRetriesKey, allow)
+}
+
+// retryableMethod reports whether req may be retried given its method.
+func (c *Client) retryableMethod(ctx context.Context, req *http.Request) bool {
+	if isIdempotent(req) {
+		return true
+	}
+	if allow, ok := ctx.Value(nonIdempotentRetriesKey).(bool); ok {
+		return allow
+	}
+	return c.NonIdempotentRetries
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
@@ -0,0 +1,70 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientRetriesIdempotentMethods(t *testing.T) {
+	ts := rchttptest.NewTestServer(http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	do := func(httpClient *Client, ctx context.Context, method string, header http.Header) int {
+		calls := ts.GetCalls(0)
+		req, _ := http.NewRequest(method, ts.URL, strings.NewReader(`{"a":1}`))
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := httpClient.Do(ctx, req)
+		So(err, ShouldBeNil)
+		resp.Body.Close()
+		return ts.GetCalls(0) - calls
+	}
+
+	Convey("Given an rchttp client with retries", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 2, RetryTime: time.Millisecond}
+
+		Convey("Then idempotent requests are retried", func() {
+			for _, method := range []string{"GET", "HEAD", "PUT", "DELETE"} {
+				So(do(httpClient, context.Background(), method, nil), ShouldEqual, 3)
+			}
+		})
+
+		Convey("But POST and PATCH requests are not", func() {
+			So(do(httpClient, context.Background(), "POST", nil), ShouldEqual, 1)
+			So(do(httpClient, context.Background(), "PATCH", nil), ShouldEqual, 1)
+		})
+
+		Convey("And POST requests with an Idempotency-Key are", func() {
+			header := http.Header{IdempotencyKeyHeader: {"abc"}}
+			So(do(httpClient, context.Background(), "POST", header), ShouldEqual, 3)
+		})
+
+		Convey("When non-idempotent retries are allowed for a request", func() {
+			ctx := WithNonIdempotentRetries(context.Background(), true)
+
+			Convey("Then it is retried", func() {
+				So(do(httpClient, ctx, "POST", nil), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When non-idempotent retries are allowed for the client", func() {
+			httpClient.AllowNonIdempotentRetries(true)
+
+			Convey("Then POST requests are retried", func() {
+				So(do(httpClient, context.Background(), "POST", nil), ShouldEqual, 3)
+			})
+
+			Convey("But not if they are disallowed for the request", func() {
+				ctx := WithNonIdempotentRetries(context.Background(), false)
+				So(do(httpClient, ctx, "POST", nil), ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,100 @@
+package rchttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/go-ns/common"
+	"golang.org/x/net/context"
+)
+
+const (
+	// OnBehalfOfHeaderKey is the header holding the identity a request is made on behalf of.
+	OnBehalfOfHeaderKey = "X-On-Behalf-Of"
+	// OnBehalfOfSignatureHeaderKey is the header holding the signature of the on-behalf-of identity.
+	OnBehalfOfSignatureHeaderKey = "X-On-Behalf-Of-Signature"
+
+	onBehalfOfKey = contextKey("rchttp-on-behalf-of")
+)
+
+// Errors returned by VerifyOnBehalfOf.
+var (
+	ErrOnBehalfOfMissing          = errors.New("rchttp: on-behalf-of identity or signature missing")
+	ErrOnBehalfOfInvalidSignature = errors.New("rchttp: on-behalf-of signature invalid")
+	ErrOnBehalfOfExpired          = errors.New("rchttp: on-behalf-of signature expired")
+)
+
+// WithOnBehalfOf returns a context which makes requests made with it be signed as being on
+// behalf of identity, in preference to the user identity from go-ns/common.
+func WithOnBehalfOf(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, onBehalfOfKey, identity)
+}
+
+func onBehalfOf(ctx context.Context) string {
+	if identity, ok := ctx.Value(onBehalfOfKey).(string); ok {
+		return identity
+	}
+	if common.IsUserPresent(ctx) {
+		return common.User(ctx)
+	}
+	return ""
+}
+
+// addOnBehalfOf adds the on-behalf-of identity for ctx, if any, to req with a signature of
+// the identity, time, method and path made with key.
+func addOnBehalfOf(ctx context.Context, req *http.Request, key []byte) {
+	identity := onBehalfOf(ctx)
+	if identity == "" {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(OnBehalfOfHeaderKey, identity)
+	req.Header.Set(OnBehalfOfSignatureHeaderKey, "t="+timestamp+",sig="+signOnBehalfOf(key, identity, timestamp, req.Method, req.URL.Path))
+}
+
+func signOnBehalfOf(key []byte, identity, timestamp, method, path string) string {
+	if path == "" {
+		path = "/"
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(identity + "\n" + timestamp + "\n" + method + "\n" + path))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyOnBehalfOf checks the on-behalf-of headers of an inbound request were signed with
+// key no more than maxAge ago, and returns the identity the request was made on behalf of.
+func VerifyOnBehalfOf(r *http.Request, key []byte, maxAge time.Duration) (string, error) {
+	identity := r.Header.Get(OnBehalfOfHeaderKey)
+	signature := r.Header.Get(OnBehalfOfSignatureHeaderKey)
+	if identity == "" || signature == "" {
+		return "", ErrOnBehalfOfMissing
+	}
+
+	var timestamp, sig string
+	for _, part := range strings.Split(signature, ",") {
+		if strings.HasPrefix(part, "t=") {
+			timestamp = strings.TrimPrefix(part, "t=")
+		} else if strings.HasPrefix(part, "sig=") {
+			sig = strings.TrimPrefix(part, "sig=")
+		}
+	}
+	expected := signOnBehalfOf(key, identity, timestamp, r.Method, r.URL.Path)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return "", ErrOnBehalfOfInvalidSignature
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrOnBehalfOfInvalidSignature
+	}
+	if age := time.Since(time.Unix(signedAt, 0)); age > maxAge || age < -maxAge {
+		return "", ErrOnBehalfOfExpired
+	}
+	return identity, nil
+}
@@ -0,0 +1,92 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientOnBehalfOf(t *testing.T) {
+	key := []byte("shared-secret")
+	var identity string
+	var verifyErr error
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, verifyErr = VerifyOnBehalfOf(r, key, time.Minute)
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with an on-behalf-of signing key", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, OnBehalfOfSigningKey: key}
+
+		Convey("When Get() is called with a user in the context", func() {
+			ctx := common.SetUser(context.Background(), "publisher@ons.gov.uk")
+			resp, err := httpClient.Get(ctx, ts.URL+"/datasets")
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the server verifies the identity", func() {
+				So(verifyErr, ShouldBeNil)
+				So(identity, ShouldEqual, "publisher@ons.gov.uk")
+			})
+		})
+
+		Convey("When Get() is called with an explicit on-behalf-of identity", func() {
+			resp, err := httpClient.Get(WithOnBehalfOf(context.Background(), "someone@ons.gov.uk"), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the server verifies that identity", func() {
+				So(verifyErr, ShouldBeNil)
+				So(identity, ShouldEqual, "someone@ons.gov.uk")
+			})
+		})
+
+		Convey("When Get() is called without an identity", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then no identity is sent", func() {
+				So(verifyErr, ShouldEqual, ErrOnBehalfOfMissing)
+			})
+		})
+	})
+}
+
+func TestVerifyOnBehalfOf(t *testing.T) {
+	key := []byte("shared-secret")
+
+	Convey("Given a request signed on behalf of a user", t, func() {
+		req := httptest.NewRequest("POST", "/datasets/cpih", nil)
+		addOnBehalfOf(WithOnBehalfOf(context.Background(), "user"), req, key)
+
+		Convey("Then it is rejected with a different key", func() {
+			_, err := VerifyOnBehalfOf(req, []byte("other"), time.Minute)
+			So(err, ShouldEqual, ErrOnBehalfOfInvalidSignature)
+		})
+
+		Convey("Then it is rejected if the identity is changed", func() {
+			req.Header.Set(OnBehalfOfHeaderKey, "admin")
+			_, err := VerifyOnBehalfOf(req, key, time.Minute)
+			So(err, ShouldEqual, ErrOnBehalfOfInvalidSignature)
+		})
+
+		Convey("Then it is rejected for a different path", func() {
+			req.URL.Path = "/datasets/other"
+			_, err := VerifyOnBehalfOf(req, key, time.Minute)
+			So(err, ShouldEqual, ErrOnBehalfOfInvalidSignature)
+		})
+
+		Convey("Then it is rejected once too old", func() {
+			old := "1000000000"
+			req.Header.Set(OnBehalfOfSignatureHeaderKey, "t="+old+",sig="+signOnBehalfOf(key, "user", old, "POST", "/datasets/cpih"))
+			_, err := VerifyOnBehalfOf(req, key, time.Minute)
+			So(err, ShouldEqual, ErrOnBehalfOfExpired)
+		})
+	})
+}
@@ -0,0 +1,34 @@
+package rchttp
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+
+	"golang.org/x/net/context"
+)
+
+const informationalKey = contextKey("rchttp-informational")
+
+// WithInformationalResponses returns a context which makes requests made with it call fn
+// with each 1xx informational response received before the final response, e.g. 103 Early
+// Hints, whose Link headers allow linked resources to be prefetched while the final
+// response is still being generated. (100 Continue is handled by the transport itself.)
+func WithInformationalResponses(ctx context.Context, fn func(code int, header http.Header)) context.Context {
+	return context.WithValue(ctx, informationalKey, fn)
+}
+
+// traceInformational adds a client trace to ctx reporting 1xx responses to the callback
+// set by WithInformationalResponses, if any.
+func traceInformational(ctx context.Context) context.Context {
+	fn, ok := ctx.Value(informationalKey).(func(code int, header http.Header))
+	if !ok || fn == nil {
+		return ctx
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			fn(code, http.Header(header).Clone())
+			return nil
+		},
+	})
+}
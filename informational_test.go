@@ -0,0 +1,52 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientInformationalResponses(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+		w.Write([]byte("page"))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client and a server sending 103 Early Hints", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+
+		Convey("When a request is made with an informational response callback", func() {
+			var codes []int
+			var links []string
+			ctx := WithInformationalResponses(context.Background(), func(code int, header http.Header) {
+				codes = append(codes, code)
+				links = append(links, header.Get("Link"))
+			})
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the callback receives the early hints before the final response", func() {
+				So(codes, ShouldResemble, []int{http.StatusEarlyHints})
+				So(links, ShouldResemble, []string{"</style.css>; rel=preload; as=style"})
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When a request is made without a callback", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then only the final response is returned", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				resp.Body.Close()
+			})
+		})
+	})
+}
@@ -0,0 +1,81 @@
+package rchttp
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// JitterMode selects how the sleep between retries is randomised, so that many clients
+// retrying after the same failure do not all hit the server at the same time.
+type JitterMode int
+
+const (
+	// JitterFixed subtracts 1-4ms from the exponential backoff. It is the default.
+	JitterFixed JitterMode = iota
+	// JitterNone sleeps for exactly the exponential backoff.
+	JitterNone
+	// JitterFull sleeps for a random time between zero and the exponential backoff.
+	JitterFull
+	// JitterEqual sleeps for half the exponential backoff plus a random time up to the
+	// other half.
+	JitterEqual
+	// JitterDecorrelated sleeps for a random time between RetryTime and three times the
+	// previous sleep, growing with each retry but less predictably than the others.
+	JitterDecorrelated
+)
+
+// sleepTime returns how long to sleep before the given retry, from the BackoffFunc of the
+// call's Options or the client, or else the client's Jitter mode, where prev is the previous sleep (or retryTime
+// before the first retry), capped at MaxRetryTime if it is set.
+func (c *Client) sleepTime(ctx context.Context, attempt int, retryTime, prev time.Duration) time.Duration {
+	if opts := callOptions(ctx); opts != nil && opts.BackoffFunc != nil {
+		return c.capRetryTime(opts.BackoffFunc(attempt))
+	}
+	if c.BackoffFunc != nil {
+		return c.capRetryTime(c.BackoffFunc(attempt))
+	}
+	var sleep time.Duration
+	switch c.Jitter {
+	case JitterNone, JitterFull, JitterEqual:
+		sleep = c.capRetryTime(time.Duration(math.Pow(2, float64(attempt))) * retryTime)
+		switch c.Jitter {
+		case JitterFull:
+			sleep = randomDuration(sleep)
+		case JitterEqual:
+			sleep = sleep/2 + randomDuration(sleep-sleep/2)
+		}
+		return sleep
+	case JitterDecorrelated:
+		upper := prev * 3
+		if upper < prev {
+			// overflowed
+			upper = math.MaxInt64
+		}
+		return c.capRetryTime(retryTime + randomDuration(upper-retryTime))
+	default:
+		return c.capRetryTime(getSleepTime(attempt, retryTime))
+	}
+}
+
+// capRetryTime caps sleep at MaxRetryTime, if it is set.
+func (c *Client) capRetryTime(sleep time.Duration) time.Duration {
+	if c.MaxRetryTime > 0 && (sleep > c.MaxRetryTime || sleep < 0) {
+		// a negative sleep time means the exponential backoff overflowed
+		return c.MaxRetryTime
+	}
+	return sleep
+}
+
+// randomDuration returns a random duration between zero and max inclusive.
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	if max == math.MaxInt64 {
+		return time.Duration(rand.Int63())
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
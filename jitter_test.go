@@ -0,0 +1,132 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientJitter(t *testing.T) {
+	retryTime := 10 * time.Millisecond
+
+	Convey("Given a client with no jitter", t, func() {
+		httpClient := &Client{Jitter: JitterNone}
+
+		Convey("Then it sleeps for exactly the exponential backoff", func() {
+			So(httpClient.sleepTime(context.Background(), 1, retryTime, retryTime), ShouldEqual, 20*time.Millisecond)
+			So(httpClient.sleepTime(context.Background(), 3, retryTime, retryTime), ShouldEqual, 80*time.Millisecond)
+		})
+	})
+
+	Convey("Given a client with the default jitter", t, func() {
+		httpClient := &Client{}
+
+		Convey("Then it sleeps for 1-4ms less than the exponential backoff", func() {
+			sleep := httpClient.sleepTime(context.Background(), 1, retryTime, retryTime)
+			So(sleep, ShouldBeBetweenOrEqual, 16*time.Millisecond, 19*time.Millisecond)
+		})
+	})
+
+	Convey("Given a client with full jitter", t, func() {
+		httpClient := &Client{Jitter: JitterFull}
+
+		Convey("Then it sleeps for up to the exponential backoff", func() {
+			for i := 0; i < 50; i++ {
+				So(httpClient.sleepTime(context.Background(), 2, retryTime, retryTime), ShouldBeBetweenOrEqual, time.Duration(0), 40*time.Millisecond)
+			}
+		})
+	})
+
+	Convey("Given a client with equal jitter", t, func() {
+		httpClient := &Client{Jitter: JitterEqual}
+
+		Convey("Then it sleeps for between half and all of the exponential backoff", func() {
+			for i := 0; i < 50; i++ {
+				So(httpClient.sleepTime(context.Background(), 2, retryTime, retryTime), ShouldBeBetweenOrEqual, 20*time.Millisecond, 40*time.Millisecond)
+			}
+		})
+	})
+
+	Convey("Given a client with decorrelated jitter", t, func() {
+		httpClient := &Client{Jitter: JitterDecorrelated}
+
+		Convey("Then it sleeps for between RetryTime and three times the previous sleep", func() {
+			for i := 0; i < 50; i++ {
+				So(httpClient.sleepTime(context.Background(), 4, retryTime, 50*time.Millisecond), ShouldBeBetweenOrEqual, retryTime, 150*time.Millisecond)
+			}
+		})
+
+		Convey("And with MaxRetryTime set, the sleep is capped", func() {
+			httpClient.MaxRetryTime = 20 * time.Millisecond
+			for i := 0; i < 50; i++ {
+				So(httpClient.sleepTime(context.Background(), 4, retryTime, time.Hour), ShouldBeBetweenOrEqual, retryTime, 20*time.Millisecond)
+			}
+		})
+	})
+
+	Convey("Given a client with full jitter retrying a failing server", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusInternalServerError)
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 3, RetryTime: time.Millisecond, Jitter: JitterFull}
+
+		Convey("When a request is made", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then every retry is made", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusInternalServerError)
+				So(ts.CallCount, ShouldEqual, 4)
+			})
+		})
+	})
+}
+
+func TestClientBackoffFunc(t *testing.T) {
+	Convey("Given a client with a fibonacci BackoffFunc", t, func() {
+		fibonacci := func(attempt int) time.Duration {
+			a, b := 1, 1
+			for i := 1; i < attempt; i++ {
+				a, b = b, a+b
+			}
+			return time.Duration(a) * time.Millisecond
+		}
+		httpClient := &Client{BackoffFunc: fibonacci, Jitter: JitterFull}
+
+		Convey("Then it replaces the exponential backoff and jitter", func() {
+			sleeps := []time.Duration{}
+			for attempt := 1; attempt <= 5; attempt++ {
+				sleeps = append(sleeps, httpClient.sleepTime(context.Background(), attempt, time.Second, time.Second))
+			}
+			So(sleeps, ShouldResemble, []time.Duration{time.Millisecond, time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 5 * time.Millisecond})
+		})
+
+		Convey("And with MaxRetryTime set, the sleep is capped", func() {
+			httpClient.MaxRetryTime = 2 * time.Millisecond
+			So(httpClient.sleepTime(context.Background(), 5, time.Second, time.Second), ShouldEqual, 2*time.Millisecond)
+		})
+	})
+
+	Convey("Given a client with a BackoffFunc retrying a failing server", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusInternalServerError)
+		defer ts.Close()
+		var attempts []int
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 3, RetryTime: time.Hour, BackoffFunc: func(attempt int) time.Duration {
+			attempts = append(attempts, attempt)
+			return time.Millisecond
+		}}
+
+		Convey("When a request is made", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the BackoffFunc is called for each retry", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(attempts, ShouldResemble, []int{1, 2, 3})
+			})
+		})
+	})
+}
@@ -0,0 +1,137 @@
+package rchttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// errNotMultiStatus is returned by ParseMultiStatus for a body which is not a list of items.
+var errNotMultiStatus = errors.New("rchttp: multi-status body is not a list of items")
+
+// ItemResult is the outcome of one item of a batch request, from a 207 Multi-Status (or
+// application/problem+json) response body.
+type ItemResult struct {
+	Index      int
+	StatusCode int
+	// Body is the item's JSON document, e.g. the created resource or a problem document.
+	Body json.RawMessage
+}
+
+// OK reports whether the item succeeded (has a 2xx status).
+func (item ItemResult) OK() bool {
+	return item.StatusCode >= 200 && item.StatusCode < 300
+}
+
+// Decode decodes the item's body into v.
+func (item ItemResult) Decode(v interface{}) error {
+	return json.Unmarshal(item.Body, v)
+}
+
+// MultiStatus holds the per-item results of a batch request.
+type MultiStatus struct {
+	Items []ItemResult
+}
+
+// Succeeded returns the items with a 2xx status.
+func (ms *MultiStatus) Succeeded() []ItemResult {
+	return ms.filter(true)
+}
+
+// Failed returns the items without a 2xx status.
+func (ms *MultiStatus) Failed() []ItemResult {
+	return ms.filter(false)
+}
+
+// PartialFailure reports whether some, but not all, items failed.
+func (ms *MultiStatus) PartialFailure() bool {
+	failed := len(ms.Failed())
+	return failed > 0 && failed < len(ms.Items)
+}
+
+func (ms *MultiStatus) filter(ok bool) []ItemResult {
+	var items []ItemResult
+	for _, item := range ms.Items {
+		if item.OK() == ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseMultiStatus parses a batch response body: a JSON array of item documents, or an
+// object holding them in "results", each with a numeric "status" member. Items without a
+// status take statusCode, the status of the response as a whole (unless that is 207).
+func ParseMultiStatus(statusCode int, body []byte) (*MultiStatus, error) {
+	var raw []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		var wrapper struct {
+			Results []json.RawMessage `json:"results"`
+		}
+		if err := json.Unmarshal(body, &wrapper); err != nil {
+			return nil, err
+		}
+		if wrapper.Results == nil {
+			return nil, errNotMultiStatus
+		}
+		raw = wrapper.Results
+	} else if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	ms := &MultiStatus{Items: make([]ItemResult, len(raw))}
+	for i, doc := range raw {
+		var status struct {
+			Status int `json:"status"`
+		}
+		json.Unmarshal(doc, &status)
+		if status.Status == 0 && statusCode != http.StatusMultiStatus {
+			status.Status = statusCode
+		}
+		ms.Items[i] = ItemResult{Index: i, StatusCode: status.Status, Body: doc}
+	}
+	return ms, nil
+}
+
+// PostMultiStatus calls Post with body encoded as JSON and parses the per-item results of
+// the (207 or other 2xx) response. A 4xx or 5xx application/problem+json response listing
+// per-item results is parsed likewise, rather than returned as an error, so that items
+// which succeeded in a partly rejected batch are not lost; info.StatusCode gives its status.
+func (c *Client) PostMultiStatus(ctx context.Context, url string, body interface{}) (*MultiStatus, *CallInfo, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	respBody, info, err := c.doBytes(ctx, req)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && isProblemContentType(info.contentType) {
+		// unlike successful bodies, the body of a StatusError is as received
+		if body, convErr := toUTF8(info.contentType, statusErr.Body); convErr == nil {
+			if ms, parseErr := ParseMultiStatus(info.StatusCode, body); parseErr == nil {
+				return ms, info, nil
+			}
+		}
+		return nil, info, err
+	}
+	if err != nil {
+		return nil, info, err
+	}
+	ms, err := ParseMultiStatus(info.StatusCode, respBody)
+	return ms, info, err
+}
+
+func isProblemContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == ProblemContentType
+}
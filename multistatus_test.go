@@ -0,0 +1,114 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseMultiStatus(t *testing.T) {
+	Convey("Given a 207 body with successes and problem documents", t, func() {
+		body := []byte(`[{"status":201,"id":"a"},{"type":"about:blank","title":"Conflict","status":409},{"status":200,"id":"c"}]`)
+
+		Convey("Then each item's result is parsed", func() {
+			ms, err := ParseMultiStatus(http.StatusMultiStatus, body)
+			So(err, ShouldBeNil)
+			So(ms.Items, ShouldHaveLength, 3)
+			So(ms.Succeeded(), ShouldHaveLength, 2)
+			So(ms.Failed(), ShouldHaveLength, 1)
+			So(ms.Failed()[0].Index, ShouldEqual, 1)
+			So(ms.Failed()[0].StatusCode, ShouldEqual, 409)
+			So(ms.PartialFailure(), ShouldBeTrue)
+
+			var created struct{ ID string }
+			So(ms.Items[0].Decode(&created), ShouldBeNil)
+			So(created.ID, ShouldEqual, "a")
+		})
+	})
+
+	Convey("Given a 200 body of results without statuses", t, func() {
+		body := []byte(`{"results":[{"id":"a"},{"id":"b"}]}`)
+
+		Convey("Then items take the response status", func() {
+			ms, err := ParseMultiStatus(http.StatusOK, body)
+			So(err, ShouldBeNil)
+			So(ms.Succeeded(), ShouldHaveLength, 2)
+			So(ms.PartialFailure(), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given a body which is not a list of items", t, func() {
+		Convey("Then an error is returned", func() {
+			_, err := ParseMultiStatus(http.StatusOK, []byte(`{"id":"a"}`))
+			So(err, ShouldEqual, errNotMultiStatus)
+		})
+	})
+}
+
+func TestClientPostMultiStatus(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received = string(b)
+		switch r.URL.Path {
+		case "/rejected":
+			w.Header().Set("Content-Type", ProblemContentType)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`[{"status":201,"id":"a"},{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid code"}]`))
+			return
+		case "/failed":
+			w.Header().Set("Content-Type", ProblemContentType)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"type":"about:blank","title":"Bad Request","status":400}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`[{"status":201},{"status":422,"detail":"invalid code"}]`))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("When a batch is posted", func() {
+			ms, info, err := httpClient.PostMultiStatus(context.Background(), ts.URL, []string{"a", "b"})
+
+			Convey("Then the per-item results are returned", func() {
+				So(err, ShouldBeNil)
+				So(received, ShouldEqual, `["a","b"]`)
+				So(info.StatusCode, ShouldEqual, http.StatusMultiStatus)
+				So(ms.Failed(), ShouldHaveLength, 1)
+				So(ms.Failed()[0].StatusCode, ShouldEqual, 422)
+			})
+		})
+
+		Convey("When a batch is rejected with a problem+json array of mixed results", func() {
+			ms, info, err := httpClient.PostMultiStatus(context.Background(), ts.URL+"/rejected", []string{"a", "b"})
+
+			Convey("Then the per-item results are still returned", func() {
+				So(err, ShouldBeNil)
+				So(info.StatusCode, ShouldEqual, http.StatusBadRequest)
+				So(ms.Succeeded(), ShouldHaveLength, 1)
+				So(ms.Failed(), ShouldHaveLength, 1)
+				So(ms.Failed()[0].Index, ShouldEqual, 1)
+				So(ms.PartialFailure(), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a batch is rejected with a single problem document", func() {
+			ms, _, err := httpClient.PostMultiStatus(context.Background(), ts.URL+"/failed", []string{"a"})
+
+			Convey("Then the problem is returned as the error", func() {
+				So(ms, ShouldBeNil)
+				problem, ok := err.(*ProblemDetails)
+				So(ok, ShouldBeTrue)
+				So(problem.Status, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	})
+}
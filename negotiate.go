@@ -0,0 +1,109 @@
+package rchttp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Decoder decodes a response body into v.
+type Decoder func(body io.Reader, v interface{}) error
+
+// DefaultDecoders are the decoders used by GetNegotiated when the client's Decoders is
+// nil, keyed by media type. CSV is decoded into a *[][]string.
+var DefaultDecoders = map[string]Decoder{
+	"application/json": decodeJSON,
+	"application/xml":  decodeXML,
+	"text/xml":         decodeXML,
+	"text/csv":         decodeCSV,
+}
+
+// UnsupportedContentTypeError is returned by GetNegotiated when there is no decoder for
+// the Content-Type of the response.
+type UnsupportedContentTypeError struct {
+	URL         string
+	ContentType string
+}
+
+func (e *UnsupportedContentTypeError) Error() string {
+	return fmt.Sprintf("rchttp: no decoder for content type %q from %s", e.ContentType, e.URL)
+}
+
+// GetNegotiated calls Get, preferring JSON but also accepting the other media types the
+// client has decoders for, and decodes the response body into v with the decoder for its
+// Content-Type. A response without a Content-Type is decoded as JSON.
+func (c *Client) GetNegotiated(ctx context.Context, url string, v interface{}) (*CallInfo, error) {
+	decoders := c.decoders()
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", acceptHeader(decoders))
+
+	b, info, err := c.doBytes(ctx, req)
+	if err != nil {
+		return info, err
+	}
+	if v == nil || len(b) == 0 {
+		return info, nil
+	}
+
+	mediaType := "application/json"
+	if info.contentType != "" {
+		if mediaType, _, err = mime.ParseMediaType(info.contentType); err != nil {
+			return info, err
+		}
+	}
+	decode, ok := decoders[mediaType]
+	if !ok {
+		return info, &UnsupportedContentTypeError{URL: url, ContentType: info.contentType}
+	}
+	return info, decode(bytes.NewReader(b), v)
+}
+
+func (c *Client) decoders() map[string]Decoder {
+	if c.Decoders != nil {
+		return c.Decoders
+	}
+	return DefaultDecoders
+}
+
+// acceptHeader lists JSON first, then the other media types with a lower quality.
+func acceptHeader(decoders map[string]Decoder) string {
+	var others []string
+	for mediaType := range decoders {
+		if mediaType != "application/json" {
+			others = append(others, mediaType+";q=0.5")
+		}
+	}
+	sort.Strings(others)
+	return strings.Join(append([]string{"application/json"}, others...), ", ")
+}
+
+func decodeJSON(body io.Reader, v interface{}) error {
+	return json.NewDecoder(body).Decode(v)
+}
+
+func decodeXML(body io.Reader, v interface{}) error {
+	return xml.NewDecoder(body).Decode(v)
+}
+
+func decodeCSV(body io.Reader, v interface{}) error {
+	records, ok := v.(*[][]string)
+	if !ok {
+		return fmt.Errorf("rchttp: CSV can only be decoded into *[][]string, not %T", v)
+	}
+	var err error
+	*records, err = csv.NewReader(body).ReadAll()
+	return err
+}
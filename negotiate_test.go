@@ -0,0 +1,76 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientGetNegotiated(t *testing.T) {
+	var accept string
+	var contentType, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with the default decoders", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("When the server responds with JSON", func() {
+			contentType, body = "application/json; charset=utf-8", `{"id":"cpih"}`
+			var v struct {
+				ID string `json:"id" xml:"id"`
+			}
+			_, err := httpClient.GetNegotiated(context.Background(), ts.URL, &v)
+
+			Convey("Then JSON is preferred and the body is decoded", func() {
+				So(err, ShouldBeNil)
+				So(accept, ShouldEqual, "application/json, application/xml;q=0.5, text/csv;q=0.5, text/xml;q=0.5")
+				So(v.ID, ShouldEqual, "cpih")
+			})
+		})
+
+		Convey("When the server responds with XML", func() {
+			contentType, body = "text/xml", `<dataset><id>cpih</id></dataset>`
+			var v struct {
+				ID string `json:"id" xml:"id"`
+			}
+			_, err := httpClient.GetNegotiated(context.Background(), ts.URL, &v)
+
+			Convey("Then the body is decoded as XML", func() {
+				So(err, ShouldBeNil)
+				So(v.ID, ShouldEqual, "cpih")
+			})
+		})
+
+		Convey("When the server responds with CSV", func() {
+			contentType, body = "text/csv", "time,value\n2019,1.5\n"
+			var records [][]string
+			_, err := httpClient.GetNegotiated(context.Background(), ts.URL, &records)
+
+			Convey("Then the body is decoded into records", func() {
+				So(err, ShouldBeNil)
+				So(records, ShouldResemble, [][]string{{"time", "value"}, {"2019", "1.5"}})
+			})
+		})
+
+		Convey("When the server responds with an unsupported type", func() {
+			contentType, body = "text/html", "<html></html>"
+			var v interface{}
+			_, err := httpClient.GetNegotiated(context.Background(), ts.URL, &v)
+
+			Convey("Then an UnsupportedContentTypeError is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &UnsupportedContentTypeError{})
+				So(err.(*UnsupportedContentTypeError).ContentType, ShouldEqual, "text/html")
+			})
+		})
+	})
+}
@@ -0,0 +1,450 @@
+package rchttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenAPIViolationError is returned by Do, when the client has an OpenAPIValidator, for a
+// request or response which does not match the downstream's OpenAPI spec. A request which
+// does not match is not sent; a response which does not match is returned with the error.
+type OpenAPIViolationError struct {
+	Method string
+	URL    string
+	// Response is true if it was the response which did not match the spec.
+	Response   bool
+	Violations []string
+}
+
+func (e *OpenAPIViolationError) Error() string {
+	part := "request"
+	if e.Response {
+		part = "response"
+	}
+	return fmt.Sprintf("rchttp: %s %s %s does not match OpenAPI spec: %s", e.Method, e.URL, part, strings.Join(e.Violations, "; "))
+}
+
+// OpenAPIValidator validates requests and responses against an OpenAPI 3 spec (in JSON):
+// their path, method, parameters, status and JSON bodies. Only the common subset of JSON
+// Schema is checked (type, required, properties, items, enum and nullable, with local $refs).
+// It is intended for non-production environments, to catch drift between a client and the
+// downstream API early.
+type OpenAPIValidator struct {
+	basePath   string
+	paths      []openAPIPath
+	schemas    map[string]*openAPISchema
+	parameters map[string]*openAPIParameter
+}
+
+type openAPIPath struct {
+	template   string
+	segments   []string
+	operations map[string]*openAPIOperation
+	parameters []*openAPIParameter
+}
+
+type openAPIOperation struct {
+	Parameters  []*openAPIParameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool                        `json:"required"`
+		Content  map[string]openAPIMediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]openAPIMediaType `json:"content"`
+	} `json:"responses"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIParameter struct {
+	Ref      string         `json:"$ref"`
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Nullable   bool                      `json:"nullable"`
+	Required   []string                  `json:"required"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Items      *openAPISchema            `json:"items"`
+	Enum       []interface{}             `json:"enum"`
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// NewOpenAPIValidator returns a validator for the OpenAPI 3 spec, given as JSON. The path of
+// the first of the spec's servers, if any, is expected before each of its paths.
+func NewOpenAPIValidator(spec []byte) (*OpenAPIValidator, error) {
+	var doc struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas    map[string]*openAPISchema    `json:"schemas"`
+			Parameters map[string]*openAPIParameter `json:"parameters"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+
+	v := &OpenAPIValidator{schemas: doc.Components.Schemas, parameters: doc.Components.Parameters}
+	if len(doc.Servers) > 0 {
+		server, err := url.Parse(doc.Servers[0].URL)
+		if err != nil {
+			return nil, err
+		}
+		v.basePath = strings.TrimSuffix(server.Path, "/")
+	}
+
+	for template, item := range doc.Paths {
+		path := openAPIPath{
+			template:   template,
+			segments:   strings.Split(strings.Trim(template, "/"), "/"),
+			operations: make(map[string]*openAPIOperation),
+		}
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &path.parameters); err != nil {
+				return nil, err
+			}
+		}
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			op := &openAPIOperation{}
+			if err := json.Unmarshal(raw, op); err != nil {
+				return nil, err
+			}
+			path.operations[strings.ToUpper(method)] = op
+		}
+		v.paths = append(v.paths, path)
+	}
+	// prefer the most specific (least templated) path when several match
+	sort.Slice(v.paths, func(i, j int) bool {
+		return templatedSegments(v.paths[i].segments) < templatedSegments(v.paths[j].segments)
+	})
+	return v, nil
+}
+
+// ValidateRequest returns an *OpenAPIViolationError if req does not match the spec. The
+// body of req is read and replaced.
+func (v *OpenAPIValidator) ValidateRequest(req *http.Request) error {
+	violation := &OpenAPIViolationError{Method: req.Method, URL: req.URL.String()}
+	path, pathParams, ok := v.match(req.URL.Path)
+	if !ok {
+		violation.Violations = []string{"path " + req.URL.Path + " is not in the spec"}
+		return violation
+	}
+	op, ok := path.operations[req.Method]
+	if !ok {
+		violation.Violations = []string{fmt.Sprintf("method %s is not allowed for %s", req.Method, path.template)}
+		return violation
+	}
+
+	for _, param := range append(path.parameters, op.Parameters...) {
+		param = v.resolveParameter(param)
+		if param == nil {
+			continue
+		}
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value, present = pathParams[param.Name]
+		case "query":
+			_, present = req.URL.Query()[param.Name]
+			value = req.URL.Query().Get(param.Name)
+		case "header":
+			value = req.Header.Get(param.Name)
+			present = value != ""
+		default:
+			continue
+		}
+		if !present {
+			if param.Required {
+				violation.Violations = append(violation.Violations, fmt.Sprintf("%s parameter %q is required", param.In, param.Name))
+			}
+			continue
+		}
+		if msg := v.validateParameter(param.Schema, value); msg != "" {
+			violation.Violations = append(violation.Violations, fmt.Sprintf("%s parameter %q %s", param.In, param.Name, msg))
+		}
+	}
+
+	if op.RequestBody != nil {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return err
+		}
+		if len(body) == 0 {
+			if op.RequestBody.Required {
+				violation.Violations = append(violation.Violations, "request body is required")
+			}
+		} else {
+			violation.Violations = append(violation.Violations, v.validateContent(op.RequestBody.Content, req.Header.Get("Content-Type"), body)...)
+		}
+	}
+
+	if len(violation.Violations) > 0 {
+		return violation
+	}
+	return nil
+}
+
+// ValidateResponse returns an *OpenAPIViolationError if resp, the response to req, does
+// not match the spec. The body of resp is read and replaced.
+func (v *OpenAPIValidator) ValidateResponse(req *http.Request, resp *http.Response) error {
+	violation := &OpenAPIViolationError{Method: req.Method, URL: req.URL.String(), Response: true}
+	path, _, ok := v.match(req.URL.Path)
+	if !ok {
+		return nil
+	}
+	op, ok := path.operations[req.Method]
+	if !ok {
+		return nil
+	}
+
+	code := strconv.Itoa(resp.StatusCode)
+	response, ok := op.Responses[code]
+	if !ok {
+		response, ok = op.Responses[code[:1]+"XX"]
+	}
+	if !ok {
+		response, ok = op.Responses["default"]
+	}
+	if !ok {
+		violation.Violations = []string{fmt.Sprintf("status %d is not in the spec", resp.StatusCode)}
+		return violation
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if len(body) > 0 && len(response.Content) > 0 {
+		violation.Violations = v.validateContent(response.Content, resp.Header.Get("Content-Type"), body)
+	}
+
+	if len(violation.Violations) > 0 {
+		return violation
+	}
+	return nil
+}
+
+// match returns the spec path matching path, with the values of its path parameters.
+func (v *OpenAPIValidator) match(path string) (*openAPIPath, map[string]string, bool) {
+	if v.basePath != "" {
+		if !strings.HasPrefix(path, v.basePath) {
+			return nil, nil, false
+		}
+		path = strings.TrimPrefix(path, v.basePath)
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i := range v.paths {
+		candidate := &v.paths[i]
+		if len(candidate.segments) != len(segments) {
+			continue
+		}
+		params := make(map[string]string)
+		matched := true
+		for j, segment := range candidate.segments {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && segments[j] != "" {
+				params[strings.Trim(segment, "{}")], _ = url.PathUnescape(segments[j])
+			} else if segment != segments[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return candidate, params, true
+		}
+	}
+	return nil, nil, false
+}
+
+func (v *OpenAPIValidator) validateContent(content map[string]openAPIMediaType, contentType string, body []byte) []string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := content[mediaType]
+	if !ok {
+		media, ok = content["*/*"]
+	}
+	if !ok {
+		return []string{fmt.Sprintf("content type %q is not in the spec", contentType)}
+	}
+	if media.Schema == nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []string{"body is not valid JSON: " + err.Error()}
+	}
+	return v.validateValue(media.Schema, value, "body")
+}
+
+// validateValue returns the ways value, decoded from JSON, does not match schema.
+func (v *OpenAPIValidator) validateValue(schema *openAPISchema, value interface{}, at string) []string {
+	schema = v.resolveSchema(schema)
+	if schema == nil {
+		return nil
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return []string{at + " must not be null"}
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return []string{fmt.Sprintf("%s is not one of %v", at, schema.Enum)}
+	}
+
+	var violations []string
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{at + " must be an object"}
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s.%s is required", at, name))
+			}
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := object[name]; ok {
+				violations = append(violations, v.validateValue(schema.Properties[name], property, at+"."+name)...)
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []string{at + " must be an array"}
+		}
+		for i, item := range array {
+			violations = append(violations, v.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{at + " must be a string"}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			return []string{at + " must be an integer"}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return []string{at + " must be a number"}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{at + " must be a boolean"}
+		}
+	}
+	return violations
+}
+
+// validateParameter returns how value, a parameter's string value, does not match schema.
+func (v *OpenAPIValidator) validateParameter(schema *openAPISchema, value string) string {
+	schema = v.resolveSchema(schema)
+	if schema == nil {
+		return ""
+	}
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "must be an integer"
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "must be a number"
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be a boolean"
+		}
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return fmt.Sprintf("is not one of %v", schema.Enum)
+	}
+	return ""
+}
+
+func (v *OpenAPIValidator) resolveSchema(schema *openAPISchema) *openAPISchema {
+	for i := 0; schema != nil && schema.Ref != "" && i < 32; i++ {
+		schema = v.schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+func (v *OpenAPIValidator) resolveParameter(param *openAPIParameter) *openAPIParameter {
+	if param != nil && param.Ref != "" {
+		return v.parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+	}
+	return param
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func templatedSegments(segments []string) int {
+	n := 0
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, "{") {
+			n++
+		}
+	}
+	return n
+}
+
+// readRequestBody returns the body of req, leaving it to be read again.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return ioutil.ReadAll(body)
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, err
+}
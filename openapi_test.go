@@ -0,0 +1,220 @@
+package rchttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testOpenAPISpec = `{
+	"openapi": "3.0.0",
+	"servers": [{"url": "http://localhost/v1"}],
+	"paths": {
+		"/datasets/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+			"get": {
+				"parameters": [{"$ref": "#/components/parameters/limit"}],
+				"responses": {
+					"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Dataset"}}}},
+					"404": {}
+				}
+			},
+			"put": {
+				"requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Dataset"}}}},
+				"responses": {"2XX": {}}
+			}
+		},
+		"/datasets/latest": {
+			"get": {"responses": {"default": {}}}
+		}
+	},
+	"components": {
+		"parameters": {
+			"limit": {"name": "limit", "in": "query", "schema": {"type": "integer"}}
+		},
+		"schemas": {
+			"Dataset": {
+				"type": "object",
+				"required": ["id", "title"],
+				"properties": {
+					"id": {"type": "string"},
+					"title": {"type": "string"},
+					"state": {"type": "string", "enum": ["created", "published"]},
+					"editions": {"type": "array", "items": {"type": "integer"}}
+				}
+			}
+		}
+	}
+}`
+
+func TestClientOpenAPIValidator(t *testing.T) {
+	var calls int32
+	var respBody atomic.Value
+	respBody.Store(`{"id":"cpih","title":"CPIH"}`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/v1/datasets/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/v1/datasets/teapot" {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(respBody.Load().(string)))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client validating calls against an OpenAPI spec", t, func() {
+		atomic.StoreInt32(&calls, 0)
+		validator, err := NewOpenAPIValidator([]byte(testOpenAPISpec))
+		So(err, ShouldBeNil)
+		httpClient := &Client{HTTPClient: &http.Client{}, OpenAPIValidator: validator}
+
+		Convey("When a request and its response match the spec", func() {
+			req, _ := http.NewRequest("GET", ts.URL+"/v1/datasets/cpih?limit=10", nil)
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then the response is returned with its body intact", func() {
+				So(err, ShouldBeNil)
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				So(string(body), ShouldEqual, `{"id":"cpih","title":"CPIH"}`)
+			})
+		})
+
+		Convey("When a request is for a path not in the spec", func() {
+			req, _ := http.NewRequest("GET", ts.URL+"/v1/filters/1", nil)
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then it is not sent and an *OpenAPIViolationError is returned", func() {
+				So(resp, ShouldBeNil)
+				violation, ok := err.(*OpenAPIViolationError)
+				So(ok, ShouldBeTrue)
+				So(violation.Response, ShouldBeFalse)
+				So(violation.Violations, ShouldResemble, []string{"path /v1/filters/1 is not in the spec"})
+				So(atomic.LoadInt32(&calls), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When a request uses a method the path does not allow", func() {
+			req, _ := http.NewRequest("DELETE", ts.URL+"/v1/datasets/cpih", nil)
+			_, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then an *OpenAPIViolationError is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &OpenAPIViolationError{})
+				So(err.Error(), ShouldContainSubstring, "method DELETE is not allowed for /datasets/{id}")
+			})
+		})
+
+		Convey("When a request has an invalid query parameter", func() {
+			req, _ := http.NewRequest("GET", ts.URL+"/v1/datasets/cpih?limit=ten", nil)
+			_, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then the parameter is reported", func() {
+				So(err, ShouldHaveSameTypeAs, &OpenAPIViolationError{})
+				So(err.(*OpenAPIViolationError).Violations, ShouldResemble, []string{`query parameter "limit" must be an integer`})
+			})
+		})
+
+		Convey("When a literal path matches as well as a templated one", func() {
+			req, _ := http.NewRequest("PUT", ts.URL+"/v1/datasets/latest", strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", "application/json")
+			_, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then the literal path is used", func() {
+				So(err, ShouldHaveSameTypeAs, &OpenAPIViolationError{})
+				So(err.Error(), ShouldContainSubstring, "method PUT is not allowed for /datasets/latest")
+			})
+		})
+
+		Convey("When a request body does not match its schema", func() {
+			req, _ := http.NewRequest("PUT", ts.URL+"/v1/datasets/cpih", strings.NewReader(`{"id":"cpih","state":"deleted","editions":[2020,"2021"]}`))
+			req.Header.Set("Content-Type", "application/json")
+			_, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then every violation is reported and the request is not sent", func() {
+				So(err, ShouldHaveSameTypeAs, &OpenAPIViolationError{})
+				So(err.(*OpenAPIViolationError).Violations, ShouldResemble, []string{
+					"body.title is required",
+					"body.editions[1] must be an integer",
+					"body.state is not one of [created published]",
+				})
+				So(atomic.LoadInt32(&calls), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When a request body matches its schema", func() {
+			req, _ := http.NewRequest("PUT", ts.URL+"/v1/datasets/cpih", strings.NewReader(`{"id":"cpih","title":"CPIH","editions":[2020]}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then the request is sent with its body", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a required request body is missing", func() {
+			req, _ := http.NewRequest("PUT", ts.URL+"/v1/datasets/cpih", nil)
+			_, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then it is reported", func() {
+				So(err, ShouldHaveSameTypeAs, &OpenAPIViolationError{})
+				So(err.(*OpenAPIViolationError).Violations, ShouldResemble, []string{"request body is required"})
+			})
+		})
+
+		Convey("When a response does not match its schema", func() {
+			respBody.Store(`{"id":1,"title":"CPIH"}`)
+			defer respBody.Store(`{"id":"cpih","title":"CPIH"}`)
+			req, _ := http.NewRequest("GET", ts.URL+"/v1/datasets/cpih", nil)
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then the response is returned with an *OpenAPIViolationError", func() {
+				So(resp, ShouldNotBeNil)
+				resp.Body.Close()
+				So(err, ShouldHaveSameTypeAs, &OpenAPIViolationError{})
+				So(err.(*OpenAPIViolationError).Response, ShouldBeTrue)
+				So(err.(*OpenAPIViolationError).Violations, ShouldResemble, []string{"body.id must be a string"})
+			})
+		})
+
+		Convey("When a response has a status declared without content", func() {
+			req, _ := http.NewRequest("GET", ts.URL+"/v1/datasets/missing", nil)
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then it is valid", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When a response has a status not in the spec", func() {
+			req, _ := http.NewRequest("GET", ts.URL+"/v1/datasets/teapot", nil)
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then it is reported", func() {
+				resp.Body.Close()
+				So(err, ShouldHaveSameTypeAs, &OpenAPIViolationError{})
+				So(err.(*OpenAPIViolationError).Violations, ShouldResemble, []string{"status 418 is not in the spec"})
+			})
+		})
+	})
+
+	Convey("Given an invalid OpenAPI spec", t, func() {
+		_, err := NewOpenAPIValidator([]byte(`{"paths": []}`))
+
+		Convey("Then NewOpenAPIValidator returns an error", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
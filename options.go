@@ -0,0 +1,40 @@
+package rchttp
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Options override the client's settings for a single call made with DoWithOptions.
+// Zero fields keep the client's settings.
+type Options struct {
+	// MaxRetries, if positive, overrides the client's MaxRetries, and NoRetries disables
+	// retries altogether.
+	MaxRetries int
+	NoRetries  bool
+	// RetryTime, if positive, overrides the client's RetryTime.
+	RetryTime time.Duration
+	// Timeout, if positive, is the timeout of each attempt, overriding the HTTP client's.
+	Timeout time.Duration
+	// RetryPolicy and BackoffFunc, if set, override the client's.
+	RetryPolicy RetryPolicy
+	BackoffFunc func(attempt int) time.Duration
+}
+
+const optionsKey = contextKey("rchttp-options")
+
+// DoWithOptions calls Do with opts overriding the client's settings (including any
+// applied with ApplyConfig), so that one client can make some calls with aggressive
+// retries and others with none. A preset in the context (see WithPreset) still takes
+// precedence over both.
+func (c *Client) DoWithOptions(ctx context.Context, req *http.Request, opts Options) (*http.Response, error) {
+	return c.Do(context.WithValue(ctx, optionsKey, &opts), req)
+}
+
+// callOptions returns the Options of a call made with DoWithOptions, or nil.
+func callOptions(ctx context.Context) *Options {
+	opts, _ := ctx.Value(optionsKey).(*Options)
+	return opts
+}
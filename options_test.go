@@ -0,0 +1,111 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientDoWithOptions(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client which retries twice", t, func() {
+		atomic.StoreInt32(&calls, 0)
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}, MaxRetries: 2, RetryTime: time.Millisecond}
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+
+		Convey("When a call is made with NoRetries", func() {
+			resp, err := httpClient.DoWithOptions(context.Background(), req, Options{NoRetries: true})
+
+			Convey("Then it is not retried", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			})
+
+			Convey("And the client's own settings are unchanged", func() {
+				So(httpClient.MaxRetries, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a call is made with more retries", func() {
+			resp, err := httpClient.DoWithOptions(context.Background(), req, Options{MaxRetries: 4})
+
+			Convey("Then it is retried more", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(atomic.LoadInt32(&calls), ShouldEqual, 5)
+			})
+		})
+
+		Convey("When a call is made with a RetryPolicy and BackoffFunc", func() {
+			var sleeps int
+			resp, err := httpClient.DoWithOptions(context.Background(), req, Options{
+				RetryPolicy: RetryPolicyFunc(func(resp *http.Response, err error, attempt int) bool { return attempt < 2 }),
+				BackoffFunc: func(attempt int) time.Duration { sleeps++; return time.Millisecond },
+			})
+
+			Convey("Then they are used instead of the client's", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+				So(sleeps, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a call is made with a short timeout", func() {
+			slow, _ := http.NewRequest("GET", ts.URL+"/slow", nil)
+			_, err := httpClient.DoWithOptions(context.Background(), slow, Options{Timeout: 50 * time.Millisecond, NoRetries: true})
+
+			Convey("Then the attempt times out", func() {
+				So(err, ShouldNotBeNil)
+				So(httpClient.HTTPClient.Timeout, ShouldEqual, 5*time.Second)
+			})
+		})
+
+		Convey("When a config has been applied to the client", func() {
+			httpClient.ApplyConfig(Config{Timeout: 5 * time.Second, MaxRetries: 2, RetryTime: time.Millisecond})
+			resp, err := httpClient.DoWithOptions(context.Background(), req, Options{NoRetries: true})
+
+			Convey("Then the options still take precedence", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+				So(httpClient.Config().MaxRetries, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When calls are made with options while configs are applied", func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 20; i++ {
+					httpClient.ApplyConfig(Config{Timeout: 5 * time.Second, MaxRetries: i % 3, RetryTime: time.Millisecond})
+				}
+			}()
+			for i := 0; i < 5; i++ {
+				resp, err := httpClient.DoWithOptions(context.Background(), req, Options{NoRetries: true})
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+			<-done
+
+			Convey("Then each call uses its own options", func() {
+				So(atomic.LoadInt32(&calls), ShouldEqual, 5)
+			})
+		})
+	})
+}
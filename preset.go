@@ -0,0 +1,98 @@
+package rchttp
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const presetKey = contextKey("rchttp-preset")
+
+// ErrUnknownPreset is returned by Do for a request made with a preset (see WithPreset)
+// which has not been registered on the client.
+var ErrUnknownPreset = errors.New("rchttp: unknown request preset")
+
+// Preset is a named set of options for calling a given downstream operation, so that it
+// is called the same way throughout a codebase (see RegisterPreset and WithPreset).
+type Preset struct {
+	// Header holds headers added to requests which do not already have them.
+	Header http.Header
+	// Timeout, if positive, is the timeout of each attempt, overriding the HTTP client's.
+	Timeout time.Duration
+	// MaxRetries, if positive, overrides the client's MaxRetries, and NoRetries disables
+	// retries altogether.
+	MaxRetries int
+	NoRetries  bool
+}
+
+// RegisterPreset registers preset on the client under name, which is also reported as the
+// call name of events for requests made with it. Presets should be registered before the
+// client is used.
+func (c *Client) RegisterPreset(name string, preset Preset) {
+	if c.Presets == nil {
+		c.Presets = make(map[string]Preset)
+	}
+	c.Presets[name] = preset
+}
+
+// WithPreset returns a context which makes requests made with it use the preset
+// registered on the client under name.
+func WithPreset(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, presetKey, name)
+}
+
+// callName returns the name of the preset in the context, if any.
+func callName(ctx context.Context) string {
+	name, _ := ctx.Value(presetKey).(string)
+	return name
+}
+
+// preset returns the preset in the context, nil if there is none, or ErrUnknownPreset.
+func (c *Client) preset(ctx context.Context) (*Preset, error) {
+	name := callName(ctx)
+	if name == "" {
+		return nil, nil
+	}
+	preset, ok := c.Presets[name]
+	if !ok {
+		return nil, ErrUnknownPreset
+	}
+	return &preset, nil
+}
+
+// applyPresetHeaders adds the headers of the preset in the context to req, unless already set.
+func (c *Client) applyPresetHeaders(ctx context.Context, req *http.Request) error {
+	preset, err := c.preset(ctx)
+	if err != nil || preset == nil {
+		return err
+	}
+	for key, values := range preset.Header {
+		if _, ok := req.Header[http.CanonicalHeaderKey(key)]; !ok {
+			req.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	}
+	return nil
+}
+
+// maxRetries returns the maximum number of retries for a request made with ctx.
+func (c *Client) maxRetries(ctx context.Context) int {
+	if preset, _ := c.preset(ctx); preset != nil {
+		if preset.NoRetries {
+			return 0
+		}
+		if preset.MaxRetries > 0 {
+			return preset.MaxRetries
+		}
+	}
+	if opts := callOptions(ctx); opts != nil {
+		if opts.NoRetries {
+			return 0
+		}
+		if opts.MaxRetries > 0 {
+			return opts.MaxRetries
+		}
+	}
+	return c.GetMaxRetries()
+}
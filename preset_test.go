@@ -0,0 +1,82 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientPresets(t *testing.T) {
+	Convey("Given an rchttp client with a search-query preset and a server which always fails", t, func() {
+		ts := rchttptest.NewTestServer(500)
+		defer ts.Close()
+
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 5, RetryTime: time.Millisecond}
+		httpClient.RegisterPreset("search-query", Preset{
+			Header:     http.Header{"Accept": {"application/json"}, "X-Search-Profile": {"default"}},
+			MaxRetries: 1,
+		})
+		httpClient.RegisterPreset("health", Preset{NoRetries: true})
+		httpClient.EnableEvents(10)
+
+		Convey("When a request is made with the preset", func() {
+			req, err := http.NewRequest("GET", ts.URL, nil)
+			So(err, ShouldBeNil)
+			req.Header.Set("X-Search-Profile", "explicit")
+			resp, err := httpClient.Do(WithPreset(context.Background(), "search-query"), req)
+			So(err, ShouldBeNil)
+
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the preset's headers and retries are used", func() {
+				So(call.Headers["Accept"], ShouldResemble, []string{"application/json"})
+				So(call.Headers["X-Search-Profile"], ShouldResemble, []string{"explicit"})
+				So(ts.GetCalls(0), ShouldEqual, 2)
+			})
+
+			Convey("And events carry the call name", func() {
+				event := <-httpClient.Events()
+				So(event.Type, ShouldEqual, EventRequestStart)
+				So(event.Call, ShouldEqual, "search-query")
+			})
+		})
+
+		Convey("When a request is made with a preset which disables retries", func() {
+			_, err := httpClient.Get(WithPreset(context.Background(), "health"), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is not retried", func() {
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a request is made with an unknown preset", func() {
+			_, err := httpClient.Get(WithPreset(context.Background(), "unknown"), ts.URL)
+
+			Convey("Then ErrUnknownPreset is returned without a call", func() {
+				So(err, ShouldEqual, ErrUnknownPreset)
+				So(ts.GetCalls(0), ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with a preset timeout and a slow server", t, func() {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 5 * time.Second}}
+		httpClient.RegisterPreset("quick", Preset{Timeout: 50 * time.Millisecond})
+
+		Convey("Then requests made with the preset time out", func() {
+			_, err := httpClient.Get(WithPreset(context.Background(), "quick"), ts.URL)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
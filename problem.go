@@ -0,0 +1,101 @@
+package rchttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is an RFC 7807 problem document. It is returned as the error by the
+// helpers for a non-2xx response with a problem document body, and unwraps to the
+// *StatusError which would otherwise have been returned.
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+	// Extensions holds any other members of the problem document.
+	Extensions map[string]json.RawMessage `json:"-"`
+
+	statusError *StatusError
+}
+
+func (p *ProblemDetails) Error() string {
+	msg := "rchttp: " + p.Title
+	if p.Title == "" {
+		msg = "rchttp: " + http.StatusText(p.Status)
+	}
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+	return fmt.Sprintf("%s (status %d)", msg, p.Status)
+}
+
+// Unwrap returns the *StatusError for the response, if the problem came from one.
+func (p *ProblemDetails) Unwrap() error {
+	if p.statusError == nil {
+		return nil
+	}
+	return p.statusError
+}
+
+// ParseProblemDetails decodes an RFC 7807 problem document.
+func ParseProblemDetails(body []byte) (*ProblemDetails, error) {
+	problem := &ProblemDetails{}
+	if err := json.Unmarshal(body, problem); err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+	for _, member := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, member)
+	}
+	if len(members) > 0 {
+		problem.Extensions = members
+	}
+	return problem, nil
+}
+
+// Problem returns the item's body as a problem document, if it failed with one.
+func (item ItemResult) Problem() (*ProblemDetails, bool) {
+	if item.OK() {
+		return nil, false
+	}
+	problem, err := ParseProblemDetails(item.Body)
+	if err != nil || (problem.Type == "" && problem.Title == "" && problem.Detail == "") {
+		return nil, false
+	}
+	if problem.Status == 0 {
+		problem.Status = item.StatusCode
+	}
+	return problem, true
+}
+
+// statusError returns the error for a non-2xx response to req with the given body: a
+// *ProblemDetails if the body is a problem document, otherwise a *StatusError.
+func statusError(req *http.Request, resp *http.Response, body []byte) error {
+	err := &StatusError{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode, Body: body}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != ProblemContentType {
+		return err
+	}
+	utf8Body, convErr := toUTF8(resp.Header.Get("Content-Type"), body)
+	if convErr != nil {
+		return err
+	}
+	problem, parseErr := ParseProblemDetails(utf8Body)
+	if parseErr != nil {
+		return err
+	}
+	if problem.Status == 0 {
+		problem.Status = resp.StatusCode
+	}
+	problem.statusError = err
+	return problem
+}
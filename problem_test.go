@@ -0,0 +1,80 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientProblemDetails(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"type":"https://ons.gov.uk/problems/state","title":"Invalid state","detail":"dataset is already published","instance":"/datasets/cpih","state":"published"}`))
+		default:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"conflict"}`))
+		}
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client", t, func() {
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient, MaxRetries: 0}
+
+		Convey("When a non-2xx response has a problem document", func() {
+			_, err := httpClient.GetJSON(context.Background(), ts.URL+"/problem", nil)
+
+			Convey("Then a ProblemDetails error is returned", func() {
+				var problem *ProblemDetails
+				So(errors.As(err, &problem), ShouldBeTrue)
+				So(problem.Type, ShouldEqual, "https://ons.gov.uk/problems/state")
+				So(problem.Status, ShouldEqual, http.StatusConflict)
+				So(problem.Instance, ShouldEqual, "/datasets/cpih")
+				So(string(problem.Extensions["state"]), ShouldEqual, `"published"`)
+				So(err.Error(), ShouldEqual, "rchttp: Invalid state: dataset is already published (status 409)")
+
+				Convey("And it unwraps to the StatusError", func() {
+					var statusErr *StatusError
+					So(errors.As(err, &statusErr), ShouldBeTrue)
+					So(statusErr.StatusCode, ShouldEqual, http.StatusConflict)
+				})
+			})
+		})
+
+		Convey("When a non-2xx response has another body", func() {
+			_, err := httpClient.GetJSON(context.Background(), ts.URL+"/other", nil)
+
+			Convey("Then a StatusError is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &StatusError{})
+			})
+		})
+	})
+}
+
+func TestItemResultProblem(t *testing.T) {
+	Convey("Given a failed multi-status item with a problem document", t, func() {
+		item := ItemResult{StatusCode: 422, Body: []byte(`{"title":"Invalid code","detail":"unknown geography"}`)}
+
+		Convey("Then the problem is parsed with the item status", func() {
+			problem, ok := item.Problem()
+			So(ok, ShouldBeTrue)
+			So(problem.Status, ShouldEqual, 422)
+			So(problem.Detail, ShouldEqual, "unknown geography")
+		})
+	})
+
+	Convey("Given a successful item", t, func() {
+		item := ItemResult{StatusCode: 201, Body: []byte(`{"id":"a"}`)}
+
+		Convey("Then there is no problem", func() {
+			_, ok := item.Problem()
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,69 @@
+package rchttp
+
+import (
+	"strings"
+	"time"
+)
+
+// Profile is a set of agreed timeout, retry and metrics defaults for clients in an
+// environment, so that services do not each choose slightly different numbers. Select one
+// with NewClientWithProfile.
+type Profile struct {
+	Name string
+	// Config holds the timeout and retry settings.
+	Config Config
+	// MaxElapsedTime limits the time spent on a request including its retries.
+	MaxElapsedTime time.Duration
+	// ConnectionGauge enables counting of open connections (see OpenConnections).
+	ConnectionGauge bool
+}
+
+// The agreed profiles. Sandbox allows for slow, shared developer infrastructure and fails
+// quickly rather than retrying; staging and production retry transient failures, counting
+// connections for metrics, with production bounding each request more tightly.
+var (
+	ProfileSandbox = Profile{
+		Name:           "sandbox",
+		Config:         Config{Timeout: 30 * time.Second, MaxRetries: 1, RetryTime: 100 * time.Millisecond},
+		MaxElapsedTime: time.Minute,
+	}
+	ProfileStaging = Profile{
+		Name:            "staging",
+		Config:          Config{Timeout: 10 * time.Second, MaxRetries: 5, RetryTime: 20 * time.Millisecond},
+		MaxElapsedTime:  30 * time.Second,
+		ConnectionGauge: true,
+	}
+	ProfileProduction = Profile{
+		Name:            "production",
+		Config:          Config{Timeout: 5 * time.Second, MaxRetries: 3, RetryTime: 50 * time.Millisecond},
+		MaxElapsedTime:  15 * time.Second,
+		ConnectionGauge: true,
+	}
+)
+
+// ProfileByName returns the profile with the given name (case insensitive), e.g. from a
+// service's ENVIRONMENT setting.
+func ProfileByName(name string) (Profile, bool) {
+	for _, profile := range []Profile{ProfileSandbox, ProfileStaging, ProfileProduction} {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// NewClientWithProfile returns a copy of DefaultClient with the settings of profile, or a
+// *ConfigError if they are dangerous (see Validate).
+func NewClientWithProfile(profile Profile) (*Client, error) {
+	c, err := NewClientWithConfig(profile.Config)
+	if err != nil {
+		return nil, err
+	}
+	c.MaxElapsedTime = profile.MaxElapsedTime
+	if profile.ConnectionGauge {
+		if err := c.EnableConnectionGauge(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
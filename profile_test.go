@@ -0,0 +1,60 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewClientWithProfile(t *testing.T) {
+	Convey("Given the built-in profiles", t, func() {
+		Convey("Then each makes a valid client", func() {
+			for _, profile := range []Profile{ProfileSandbox, ProfileStaging, ProfileProduction} {
+				httpClient, err := NewClientWithProfile(profile)
+				So(err, ShouldBeNil)
+				So(httpClient.Validate(), ShouldBeNil)
+			}
+		})
+
+		Convey("Then they can be selected by name", func() {
+			profile, ok := ProfileByName("Production")
+			So(ok, ShouldBeTrue)
+			So(profile.Name, ShouldEqual, "production")
+			_, ok = ProfileByName("preprod")
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given a client with the production profile", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusOK)
+		defer ts.Close()
+		httpClient, err := NewClientWithProfile(ProfileProduction)
+		So(err, ShouldBeNil)
+
+		Convey("Then it has the profile's settings", func() {
+			So(httpClient.HTTPClient.Timeout, ShouldEqual, 5*time.Second)
+			So(httpClient.GetMaxRetries(), ShouldEqual, 3)
+			So(httpClient.MaxElapsedTime, ShouldEqual, 15*time.Second)
+		})
+
+		Convey("Then its connections are counted", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(httpClient.OpenConnections(), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given a profile with no timeout", t, func() {
+		profile := Profile{Name: "unsafe", Config: Config{MaxRetries: 3, RetryTime: time.Millisecond}}
+
+		Convey("Then it is rejected", func() {
+			_, err := NewClientWithProfile(profile)
+			So(err, ShouldHaveSameTypeAs, &ConfigError{})
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package rchttp
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultProgressInterval is the least time between calls to a progress callback, when
+// the client's ProgressInterval is zero.
+const DefaultProgressInterval = 250 * time.Millisecond
+
+const progressKey = contextKey("rchttp-progress")
+
+// WithProgress returns a context which makes requests made with it report the progress of
+// their transfer to fn: the bytes of the request body sent while uploading, then the bytes
+// of the response body read while downloading. total is -1 if the length is unknown. fn is
+// called at most once per the client's ProgressInterval, and always once the transfer ends.
+func WithProgress(ctx context.Context, fn func(bytesDone, total int64)) context.Context {
+	return context.WithValue(ctx, progressKey, fn)
+}
+
+func progressCallback(ctx context.Context) func(bytesDone, total int64) {
+	fn, _ := ctx.Value(progressKey).(func(bytesDone, total int64))
+	return fn
+}
+
+func (c *Client) progressInterval() time.Duration {
+	if c.ProgressInterval > 0 {
+		return c.ProgressInterval
+	}
+	return DefaultProgressInterval
+}
+
+// reportProgress wraps body, of length total, to report how much of it has been read to
+// the progress callback in ctx, if any.
+func (c *Client) reportProgress(ctx context.Context, body io.ReadCloser, total int64) io.ReadCloser {
+	fn := progressCallback(ctx)
+	if fn == nil || body == nil || body == http.NoBody {
+		return body
+	}
+	if total <= 0 {
+		total = -1
+	}
+	return &progressReader{ReadCloser: body, total: total, fn: fn, interval: c.progressInterval()}
+}
+
+// progressReader reports the bytes read from it to fn, throttled to once per interval.
+type progressReader struct {
+	io.ReadCloser
+	done     int64
+	total    int64
+	fn       func(bytesDone, total int64)
+	interval time.Duration
+	last     time.Time
+	finished bool
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.done += int64(n)
+	if r.finished {
+		return n, err
+	}
+	if err == io.EOF {
+		r.finished = true
+		r.fn(r.done, r.total)
+	} else if n > 0 && time.Since(r.last) >= r.interval {
+		r.last = time.Now()
+		r.fn(r.done, r.total)
+	}
+	return n, err
+}
@@ -0,0 +1,84 @@
+package rchttp
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientWithProgress(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 256*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		w.Write(payload)
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client reporting progress on every read", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, ProgressInterval: time.Nanosecond}
+		var mutex sync.Mutex
+		var reports [][2]int64
+		ctx := WithProgress(context.Background(), func(bytesDone, total int64) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			reports = append(reports, [2]int64{bytesDone, total})
+		})
+
+		Convey("When a body is downloaded", func() {
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+			b, err := ioutil.ReadAll(resp.Body)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then increasing progress is reported, ending with the whole body", func() {
+				So(len(b), ShouldEqual, len(payload))
+				So(len(reports), ShouldBeGreaterThan, 1)
+				for i := 1; i < len(reports); i++ {
+					So(reports[i][0], ShouldBeGreaterThanOrEqualTo, reports[i-1][0])
+				}
+				So(reports[len(reports)-1], ShouldResemble, [2]int64{int64(len(payload)), int64(len(payload))})
+			})
+		})
+
+		Convey("When a body is uploaded", func() {
+			resp, err := httpClient.Post(ctx, ts.URL, "text/plain", bytes.NewReader(payload[:1000]))
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the upload is reported as complete", func() {
+				mutex.Lock()
+				defer mutex.Unlock()
+				So(reports, ShouldContain, [2]int64{1000, 1000})
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with the default progress interval", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		calls := 0
+		ctx := WithProgress(context.Background(), func(bytesDone, total int64) {
+			calls++
+		})
+
+		Convey("When a body is downloaded quickly", func() {
+			resp, err := httpClient.Get(ctx, ts.URL)
+			So(err, ShouldBeNil)
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then progress is throttled", func() {
+				So(calls, ShouldBeBetweenOrEqual, 1, 2)
+			})
+		})
+	})
+}
@@ -0,0 +1,257 @@
+package rchttp
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// epochThreshold distinguishes reset values given as Unix times (as some X-RateLimit-Reset
+// headers are) from those given as seconds until the reset.
+const epochThreshold = 1000000000
+
+// RateLimit holds the rate limit state advertised by a server in its RateLimit-* (or
+// X-RateLimit-*) response headers.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	// Reset is when the limit resets, or the zero time if not given.
+	Reset time.Time
+}
+
+// ParseRateLimit parses the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+// (or their X-RateLimit-* equivalents) in h, reporting whether any were present. Reset may
+// be either seconds until the reset or a Unix time.
+func ParseRateLimit(h http.Header) (*RateLimit, bool) {
+	limit, hasLimit := rateLimitHeader(h, "Limit")
+	remaining, hasRemaining := rateLimitHeader(h, "Remaining")
+	reset, hasReset := rateLimitHeader(h, "Reset")
+	if !hasLimit && !hasRemaining && !hasReset {
+		return nil, false
+	}
+
+	rl := &RateLimit{Limit: limit, Remaining: remaining}
+	if !hasRemaining {
+		rl.Remaining = -1
+	}
+	if hasReset {
+		if reset >= epochThreshold {
+			rl.Reset = time.Unix(int64(reset), 0)
+		} else {
+			rl.Reset = time.Now().Add(time.Duration(reset) * time.Second)
+		}
+	}
+	return rl, true
+}
+
+func rateLimitHeader(h http.Header, name string) (int, bool) {
+	value := h.Get("RateLimit-" + name)
+	if value == "" {
+		value = h.Get("X-RateLimit-" + name)
+	}
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// rateLimitPacer spreads requests to hosts which have reported their rate limit over the
+// time until the limit resets, holding them back entirely once it is exhausted.
+type rateLimitPacer struct {
+	mutex sync.Mutex
+	hosts map[string]*pacedHost
+}
+
+// pacedHost is the pacing of requests to a host until its rate limit resets.
+type pacedHost struct {
+	reset time.Time
+	// next is the earliest time the next request may be made, and interval the time
+	// between requests which spreads the remaining ones until the reset.
+	next     time.Time
+	interval time.Duration
+}
+
+// EnableRateLimitPacing makes the client pace requests to a host which reports its rate
+// limit (via RateLimit-* headers), spreading the requests remaining evenly over the time
+// until the limit resets, and holding requests back until the reset once none remain,
+// rather than making requests which will be rejected with a 429.
+func (c *Client) EnableRateLimitPacing() {
+	c.rateLimitPacer = &rateLimitPacer{hosts: make(map[string]*pacedHost)}
+}
+
+// wait blocks until a request to host may be made, or ctx is done.
+func (p *rateLimitPacer) wait(ctx context.Context, host string) error {
+	p.mutex.Lock()
+	paced, ok := p.hosts[host]
+	if !ok {
+		p.mutex.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if !now.Before(paced.reset) {
+		delete(p.hosts, host)
+		p.mutex.Unlock()
+		return nil
+	}
+	slot := paced.next
+	if slot.Before(now) {
+		slot = now
+	}
+	if slot.After(paced.reset) {
+		slot = paced.reset
+	}
+	paced.next = slot.Add(paced.interval)
+	p.mutex.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// update records the rate limit state of host from the headers of resp.
+func (p *rateLimitPacer) update(host string, resp *http.Response) {
+	rl, ok := ParseRateLimit(resp.Header)
+	if !ok {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	until := time.Until(rl.Reset)
+	if rl.Remaining < 0 || rl.Reset.IsZero() || until <= 0 {
+		delete(p.hosts, host)
+		return
+	}
+
+	paced, ok := p.hosts[host]
+	if !ok {
+		paced = &pacedHost{}
+		p.hosts[host] = paced
+	}
+	paced.reset = rl.Reset
+	if rl.Remaining == 0 {
+		paced.next, paced.interval = rl.Reset, 0
+		return
+	}
+	// keep the slots already given to requests in flight
+	paced.interval = until / time.Duration(rl.Remaining)
+	if paced.next.After(rl.Reset) {
+		paced.next = rl.Reset
+	}
+}
+
+// hostRateLimiter limits the rate of requests to hosts with token buckets.
+type hostRateLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// SetHostRateLimit limits requests (including retries) to host to rps requests per second,
+// allowing bursts of up to burst requests, holding back requests over the limit rather than
+// tripping the host's own rate limit. host is either "host:port" or "host", which matches
+// any port. A non-positive rps removes the limit. Limits can also be replaced while the
+// client is in use with ApplyConfig.
+func (c *Client) SetHostRateLimit(host string, rps float64, burst int) {
+	limiter := c.rateLimiter()
+	if limiter == nil {
+		limiter = newHostRateLimiter()
+		c.hostRateLimits = limiter
+	}
+	limiter.set(host, rps, burst)
+}
+
+func newHostRateLimiter() *hostRateLimiter {
+	return &hostRateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+// set sets the rate limit of host, removing it if rps is not positive.
+func (l *hostRateLimiter) set(host string, rps float64, burst int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if rps <= 0 {
+		delete(l.buckets, host)
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l.buckets[host] = &tokenBucket{rate: rps, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// limits returns the rate limits of l by host.
+func (l *hostRateLimiter) limits() map[string]HostRateLimit {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	limits := make(map[string]HostRateLimit, len(l.buckets))
+	for host, bucket := range l.buckets {
+		limits[host] = HostRateLimit{RPS: bucket.rate, Burst: int(bucket.burst)}
+	}
+	return limits
+}
+
+// wait blocks until a request may be made to host ("host:port") under its rate limit, if
+// any, or ctx is done.
+func (l *hostRateLimiter) wait(ctx context.Context, host string) error {
+	l.mutex.Lock()
+	bucket, ok := l.buckets[host]
+	if !ok {
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			bucket, ok = l.buckets[hostname]
+		}
+	}
+	if !ok {
+		l.mutex.Unlock()
+		return nil
+	}
+	delay := bucket.reserve(time.Now())
+	l.mutex.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mutex.Lock()
+		bucket.tokens++
+		l.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket, returning how long to wait until it is due.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
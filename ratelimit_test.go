@@ -0,0 +1,169 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	Convey("Given RateLimit headers with a reset in seconds", t, func() {
+		h := http.Header{"Ratelimit-Limit": {"100"}, "Ratelimit-Remaining": {"7"}, "Ratelimit-Reset": {"30"}}
+
+		Convey("Then they are parsed", func() {
+			rl, ok := ParseRateLimit(h)
+			So(ok, ShouldBeTrue)
+			So(rl.Limit, ShouldEqual, 100)
+			So(rl.Remaining, ShouldEqual, 7)
+			So(time.Until(rl.Reset), ShouldBeBetweenOrEqual, 29*time.Second, 30*time.Second)
+		})
+	})
+
+	Convey("Given X-RateLimit headers with a reset as a Unix time", t, func() {
+		h := http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1700000000"}}
+
+		Convey("Then they are parsed", func() {
+			rl, ok := ParseRateLimit(h)
+			So(ok, ShouldBeTrue)
+			So(rl.Remaining, ShouldEqual, 0)
+			So(rl.Reset.Equal(time.Unix(1700000000, 0)), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given no rate limit headers", t, func() {
+		Convey("Then nothing is parsed", func() {
+			_, ok := ParseRateLimit(http.Header{})
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestClientRateLimitPacing(t *testing.T) {
+	var calls []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, time.Now())
+		w.Header().Set("RateLimit-Limit", "1")
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(1))
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with rate limit pacing", t, func() {
+		calls = nil
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+		httpClient.EnableRateLimitPacing()
+
+		Convey("When a server reports its limit is exhausted", func() {
+			info, err := httpClient.GetJSON(context.Background(), ts.URL, nil)
+			So(err, ShouldBeNil)
+			So(info.RateLimit.Remaining, ShouldEqual, 0)
+
+			Convey("Then the next request waits for the reset", func() {
+				_, err := httpClient.GetJSON(context.Background(), ts.URL, nil)
+				So(err, ShouldBeNil)
+				So(calls, ShouldHaveLength, 2)
+				So(calls[1].Sub(calls[0]), ShouldBeGreaterThanOrEqualTo, 900*time.Millisecond)
+			})
+
+			Convey("And a request whose context ends first is not made", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				_, err := httpClient.Get(ctx, ts.URL)
+				So(errors.Is(err, ErrCallerDeadline), ShouldBeTrue)
+				So(calls, ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with rate limit pacing of a server with requests remaining", t, func() {
+		var paced []time.Time
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paced = append(paced, time.Now())
+			w.Header().Set("RateLimit-Limit", "4")
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(4-len(paced)))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(1))
+		}))
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+		httpClient.EnableRateLimitPacing()
+
+		Convey("When requests are made in a burst", func() {
+			for i := 0; i < 4; i++ {
+				resp, err := httpClient.Get(context.Background(), ts.URL)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			}
+
+			Convey("Then they are spread over the time until the reset rather than sent at once", func() {
+				So(paced, ShouldHaveLength, 4)
+				So(paced[3].Sub(paced[0]), ShouldBeGreaterThanOrEqualTo, 600*time.Millisecond)
+				for i := 1; i < len(paced); i++ {
+					So(paced[i].Sub(paced[i-1]), ShouldBeLessThan, 900*time.Millisecond)
+				}
+			})
+		})
+	})
+}
+
+func TestClientSetHostRateLimit(t *testing.T) {
+	ts := rchttptest.NewTestServer(http.StatusOK)
+	defer ts.Close()
+	tsURL, _ := url.Parse(ts.URL)
+
+	get := func(httpClient *Client) {
+		resp, err := httpClient.Get(context.Background(), ts.URL)
+		So(err, ShouldBeNil)
+		resp.Body.Close()
+	}
+
+	Convey("Given an rchttp client with a rate limit on a host", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		httpClient.SetHostRateLimit(tsURL.Hostname(), 20, 2)
+
+		Convey("When requests are made in a burst", func() {
+			start := time.Now()
+			for i := 0; i < 4; i++ {
+				get(httpClient)
+			}
+
+			Convey("Then requests over the burst are held back to the rate", func() {
+				elapsed := time.Since(start)
+				So(elapsed, ShouldBeGreaterThanOrEqualTo, 90*time.Millisecond)
+				So(elapsed, ShouldBeLessThan, time.Second)
+			})
+		})
+
+		Convey("When a held back request's context is cancelled", func() {
+			get(httpClient)
+			get(httpClient)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			_, err := httpClient.Get(ctx, ts.URL)
+
+			Convey("Then it fails with the context's error", func() {
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the limit is removed", func() {
+			httpClient.SetHostRateLimit(tsURL.Hostname(), 0, 0)
+			start := time.Now()
+			for i := 0; i < 4; i++ {
+				get(httpClient)
+			}
+
+			Convey("Then requests are not held back", func() {
+				So(time.Since(start), ShouldBeLessThan, 90*time.Millisecond)
+			})
+		})
+	})
+}
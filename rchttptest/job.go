@@ -0,0 +1,96 @@
+package rchttptest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JobServer is a test server scripting the lifecycle of long-running jobs: each request
+// submitting a job is answered with 202 Accepted and a Location to poll, which answers
+// Pending polls with 202 Accepted and then redirects (303 See Other) to the job's result.
+type JobServer struct {
+	Server *httptest.Server
+	URL    string
+	// Pending is the number of polls answered with 202 Accepted before a job completes.
+	Pending int
+	// RetryAfter, if set, is sent as the Retry-After header of pending responses.
+	RetryAfter string
+	// Result is the body of a completed job's result, served with ResultStatus (200 if zero).
+	Result       string
+	ResultStatus int
+	Mutex        sync.Mutex
+
+	jobs  int
+	polls map[int]int
+}
+
+// NewJobServer returns a JobServer whose jobs complete with result after pending polls.
+func NewJobServer(pending int, result string) *JobServer {
+	js := &JobServer{Pending: pending, Result: result, polls: make(map[int]int)}
+	js.Server = httptest.NewServer(http.HandlerFunc(js.handle))
+	js.URL = js.Server.URL
+	return js
+}
+
+func (js *JobServer) handle(w http.ResponseWriter, r *http.Request) {
+	js.Mutex.Lock()
+	defer js.Mutex.Unlock()
+
+	if !strings.HasPrefix(r.URL.Path, "/jobs/") {
+		GetBody(r.Body)
+		js.jobs++
+		w.Header().Set("Location", fmt.Sprintf("/jobs/%d", js.jobs))
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id < 1 || id > js.jobs {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) > 1 && parts[1] == "result" {
+		status := js.ResultStatus
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.Header().Set(ContentTypeHeader, JsonContentType)
+		w.WriteHeader(status)
+		fmt.Fprint(w, js.Result)
+		return
+	}
+
+	js.polls[id]++
+	if js.polls[id] <= js.Pending {
+		if js.RetryAfter != "" {
+			w.Header().Set("Retry-After", js.RetryAfter)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/jobs/%d/result", id), http.StatusSeeOther)
+}
+
+// Close shuts down the server.
+func (js *JobServer) Close() {
+	js.Server.Close()
+}
+
+// GetJobs returns the number of jobs submitted.
+func (js *JobServer) GetJobs() int {
+	js.Mutex.Lock()
+	defer js.Mutex.Unlock()
+	return js.jobs
+}
+
+// GetPolls returns the number of times the status of job id (numbered from 1) was polled.
+func (js *JobServer) GetPolls(id int) int {
+	js.Mutex.Lock()
+	defer js.Mutex.Unlock()
+	return js.polls[id]
+}
@@ -1,6 +1,8 @@
 package rchttptest
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,9 +15,10 @@ import (
 )
 
 const (
-	JsonContentType   = "application/json"
-	FormEncodedType   = "application/x-www-form-urlencoded"
-	ContentTypeHeader = "Content-Type"
+	JsonContentType      = "application/json"
+	FormEncodedType      = "application/x-www-form-urlencoded"
+	ContentTypeHeader    = "Content-Type"
+	IdempotencyKeyHeader = "Idempotency-Key"
 )
 
 type TestServer struct {
@@ -23,6 +26,17 @@ type TestServer struct {
 	URL       string
 	CallCount int
 	Mutex     sync.Mutex
+
+	calls []RecordedCall
+}
+
+// RecordedCall describes a request received by a TestServer.
+type RecordedCall struct {
+	Method         string
+	Path           string
+	BodyLength     int
+	BodyHash       string
+	IdempotencyKey string
 }
 
 type Responder struct {
@@ -52,6 +66,7 @@ func NewTestServer(statusCode int) *TestServer {
 		w.WriteHeader(statusCode)
 		contentType := r.Header.Get(ContentTypeHeader)
 		b := GetBody(r.Body)
+		ts.record(r, b)
 		headers := make(map[string][]string)
 		for h, v := range r.Header {
 			headers[h] = v
@@ -108,6 +123,45 @@ func (ts *TestServer) GetCalls(delta int) int {
 	return ts.CallCount
 }
 
+func (ts *TestServer) record(r *http.Request, body []byte) {
+	hash := sha256.Sum256(body)
+	ts.Mutex.Lock()
+	defer ts.Mutex.Unlock()
+	ts.calls = append(ts.calls, RecordedCall{
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		BodyLength:     len(body),
+		BodyHash:       hex.EncodeToString(hash[:]),
+		IdempotencyKey: r.Header.Get(IdempotencyKeyHeader),
+	})
+}
+
+// GetRecordedCalls returns the requests received by the server, in order.
+func (ts *TestServer) GetRecordedCalls() []RecordedCall {
+	ts.Mutex.Lock()
+	defer ts.Mutex.Unlock()
+	return append([]RecordedCall(nil), ts.calls...)
+}
+
+// CheckReplays returns an error if any request received by the server differs from the
+// first in its method, path, body or Idempotency-Key header, e.g. to check that a request
+// was replayed identically on each retry.
+func (ts *TestServer) CheckReplays() error {
+	calls := ts.GetRecordedCalls()
+	for i := 1; i < len(calls); i++ {
+		first, call := calls[0], calls[i]
+		switch {
+		case call.Method != first.Method || call.Path != first.Path:
+			return fmt.Errorf("call %d was %s %s, not %s %s", i+1, call.Method, call.Path, first.Method, first.Path)
+		case call.BodyHash != first.BodyHash:
+			return fmt.Errorf("call %d had a different body (%d bytes, sha256 %s) to call 1 (%d bytes, sha256 %s)", i+1, call.BodyLength, call.BodyHash, first.BodyLength, first.BodyHash)
+		case call.IdempotencyKey != first.IdempotencyKey:
+			return fmt.Errorf("call %d had idempotency key %q, not %q", i+1, call.IdempotencyKey, first.IdempotencyKey)
+		}
+	}
+	return nil
+}
+
 func convertErrorToOutput(w io.Writer, contentType string, err error) {
 	if contentType != JsonContentType {
 		fmt.Fprint(w, err)
@@ -0,0 +1,114 @@
+package rchttptest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Doer is the subset of an HTTP client used by Soak, satisfied by *rchttp.Client.
+type Doer interface {
+	Do(ctx context.Context, req *http.Request) (*http.Response, error)
+}
+
+// SoakResult summarises the requests made by Soak.
+type SoakResult struct {
+	Requests int
+	// Errors counts requests which returned an error, and StatusCodes counts the status
+	// of those which returned a response.
+	Errors      int
+	StatusCodes map[int]int
+	Elapsed     time.Duration
+
+	// Latencies of all requests, including those which failed.
+	Min, Mean, P50, P95, P99, Max time.Duration
+}
+
+func (r *SoakResult) String() string {
+	return fmt.Sprintf("%d requests in %s (%d errors, statuses %v): min %s mean %s p50 %s p95 %s p99 %s max %s",
+		r.Requests, r.Elapsed, r.Errors, r.StatusCodes, r.Min, r.Mean, r.P50, r.P95, r.P99, r.Max)
+}
+
+// Soak makes GET requests to target with client at rps requests per second for duration,
+// without waiting for each to finish before starting the next, and summarises their
+// latencies and outcomes once all have finished. It is intended for checking changes to
+// retries, pooling and buffering for performance regressions.
+func Soak(client Doer, target string, rps int, duration time.Duration) *SoakResult {
+	if rps < 1 {
+		rps = 1
+	}
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var latencies []time.Duration
+	result := &SoakResult{StatusCodes: make(map[int]int)}
+
+	start := time.Now()
+	for time.Since(start) < duration {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			began := time.Now()
+			status, err := soakRequest(client, target)
+			latency := time.Since(began)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			latencies = append(latencies, latency)
+			if err != nil {
+				result.Errors++
+			} else {
+				result.StatusCodes[status]++
+			}
+		}()
+		<-ticker.C
+	}
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+	result.summarise(latencies)
+	return result
+}
+
+func soakRequest(client Doer, target string) (int, error) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(context.Background(), req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(ioutil.Discard, resp.Body); err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+func (r *SoakResult) summarise(latencies []time.Duration) {
+	r.Requests = len(latencies)
+	if r.Requests == 0 {
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	percentile := func(p int) time.Duration {
+		return latencies[(len(latencies)-1)*p/100]
+	}
+	r.Min, r.Max = latencies[0], latencies[len(latencies)-1]
+	r.Mean = total / time.Duration(len(latencies))
+	r.P50, r.P95, r.P99 = percentile(50), percentile(95), percentile(99)
+}
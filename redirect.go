@@ -0,0 +1,38 @@
+package rchttp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Redirect is one redirect followed when making a request.
+type Redirect struct {
+	From       string
+	To         string
+	StatusCode int
+}
+
+// Downgrade reports whether the redirect was from https to http.
+func (r Redirect) Downgrade() bool {
+	return strings.HasPrefix(r.From, "https:") && strings.HasPrefix(r.To, "http:")
+}
+
+// RedirectHistory returns the redirects followed to get resp, in the order they were
+// followed, or nil if there were none.
+func RedirectHistory(resp *http.Response) []Redirect {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	var history []Redirect
+	for req := resp.Request; req.Response != nil && req.Response.Request != nil; req = req.Response.Request {
+		history = append(history, Redirect{
+			From:       req.Response.Request.URL.String(),
+			To:         req.URL.String(),
+			StatusCode: req.Response.StatusCode,
+		})
+	}
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history
+}
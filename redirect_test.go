@@ -0,0 +1,58 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientRedirectHistory(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+		case "/moved":
+			http.Redirect(w, r, "/current", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+
+		Convey("When a request is redirected twice", func() {
+			_, info, err := httpClient.GetBytes(context.Background(), ts.URL+"/old")
+			So(err, ShouldBeNil)
+
+			Convey("Then the redirect chain is reported in order", func() {
+				So(info.Redirects, ShouldResemble, []Redirect{
+					{From: ts.URL + "/old", To: ts.URL + "/moved", StatusCode: 301},
+					{From: ts.URL + "/moved", To: ts.URL + "/current", StatusCode: 302},
+				})
+			})
+		})
+
+		Convey("When a request is not redirected", func() {
+			_, info, err := httpClient.GetBytes(context.Background(), ts.URL+"/current")
+			So(err, ShouldBeNil)
+
+			Convey("Then there is no redirect history", func() {
+				So(info.Redirects, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given redirects between schemes", t, func() {
+		Convey("Then only https to http is a downgrade", func() {
+			So(Redirect{From: "https://a/x", To: "http://a/x"}.Downgrade(), ShouldBeTrue)
+			So(Redirect{From: "http://a/x", To: "https://a/x"}.Downgrade(), ShouldBeFalse)
+			So(Redirect{From: "https://a/x", To: "https://b/x"}.Downgrade(), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package rchttp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// cachingResolver resolves the hosts of new connections within a timeout, separate from
+// the dial timeout, falling back to the addresses last resolved for a host if a lookup
+// fails or times out.
+type cachingResolver struct {
+	timeout time.Duration
+	lookup  func(ctx context.Context, host string) ([]string, error)
+
+	mutex sync.Mutex
+	addrs map[string][]string
+}
+
+// SetResolverTimeout limits how long the DNS lookup for a new connection may take to
+// timeout, separately from the dial timeout. If a lookup fails or times out, the addresses
+// last resolved for the host are used, so that slow DNS does not hold up failing over.
+// A timeout of zero or less sets no limit separate from the dial timeout, but still falls
+// back to the last resolved addresses.
+func (c *Client) SetResolverTimeout(timeout time.Duration) error {
+	transport, err := c.cloneTransport()
+	if err != nil {
+		return err
+	}
+	c.resolver = &cachingResolver{
+		timeout: timeout,
+		lookup:  net.DefaultResolver.LookupHost,
+		addrs:   make(map[string][]string),
+	}
+	c.setDialContext(transport, c.baseDial(transport))
+	c.setTransport(transport)
+	return nil
+}
+
+// dialContext wraps dial to dial the resolved addresses of a host in turn.
+func (r *cachingResolver) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		addrs, err := r.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range addrs {
+			var conn net.Conn
+			if conn, err = dial(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, err
+	}
+}
+
+// resolve returns the addresses of host, or those last resolved if the lookup fails.
+func (r *cachingResolver) resolve(ctx context.Context, host string) ([]string, error) {
+	lookupCtx := ctx
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	addrs, err := r.lookup(lookupCtx, host)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err == nil && len(addrs) > 0 {
+		r.addrs[host] = addrs
+		return addrs, nil
+	}
+	if cached, ok := r.addrs[host]; ok && ctx.Err() == nil {
+		return cached, nil
+	}
+	if err == nil {
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		err = &net.DNSError{Err: "lookup timed out", Name: host, IsTimeout: true}
+	}
+	return nil, err
+}
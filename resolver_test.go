@@ -0,0 +1,95 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientResolverTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	tsURL, _ := url.Parse(ts.URL)
+
+	Convey("Given an rchttp client with a resolver timeout and a DNS server which becomes slow", t, func() {
+		var slow int32
+		httpClient := &Client{HTTPClient: &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}}
+		So(httpClient.SetResolverTimeout(50*time.Millisecond), ShouldBeNil)
+		httpClient.resolver.lookup = func(ctx context.Context, host string) ([]string, error) {
+			if atomic.LoadInt32(&slow) == 1 {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			if host != "dataset-api" {
+				return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			}
+			return []string{tsURL.Hostname()}, nil
+		}
+		target := "http://dataset-api:" + tsURL.Port()
+
+		Convey("When a host is resolved", func() {
+			resp, err := httpClient.Get(context.Background(), target)
+
+			Convey("Then the request is made to its address", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			})
+
+			Convey("And when a later lookup times out, the last addresses are used", func() {
+				atomic.StoreInt32(&slow, 1)
+				start := time.Now()
+				resp, err := httpClient.Get(context.Background(), target)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+			})
+		})
+
+		Convey("When a lookup times out for a host which has not been resolved", func() {
+			atomic.StoreInt32(&slow, 1)
+			start := time.Now()
+			_, err := httpClient.Get(context.Background(), "http://filter-api:"+tsURL.Port())
+
+			Convey("Then the request fails once the resolver timeout is up", func() {
+				var dnsErr *net.DNSError
+				So(errors.As(err, &dnsErr), ShouldBeTrue)
+				So(dnsErr.IsTimeout, ShouldBeTrue)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+			})
+		})
+
+		Convey("When the resolver timeout is zero", func() {
+			So(httpClient.SetResolverTimeout(0), ShouldBeNil)
+			httpClient.resolver.lookup = func(ctx context.Context, host string) ([]string, error) {
+				if _, ok := ctx.Deadline(); ok {
+					return nil, errors.New("unexpected lookup deadline")
+				}
+				return []string{tsURL.Hostname()}, nil
+			}
+			resp, err := httpClient.Get(context.Background(), target)
+
+			Convey("Then lookups are not given a deadline of their own", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When a host does not exist", func() {
+			_, err := httpClient.Get(context.Background(), "http://filter-api:"+tsURL.Port())
+
+			Convey("Then the lookup error is returned", func() {
+				var dnsErr *net.DNSError
+				So(errors.As(err, &dnsErr), ShouldBeTrue)
+				So(dnsErr.IsNotFound, ShouldBeTrue)
+			})
+		})
+	})
+}
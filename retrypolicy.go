@@ -0,0 +1,118 @@
+package rchttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// RetryPolicy decides whether a request is retried after an attempt, given its response
+// or error. attempt is the number of attempts made so far, starting at 1.
+type RetryPolicy interface {
+	ShouldRetry(resp *http.Response, err error, attempt int) bool
+}
+
+// RetryPolicyFunc adapts a function to a RetryPolicy.
+type RetryPolicyFunc func(resp *http.Response, err error, attempt int) bool
+
+// ShouldRetry calls f.
+func (f RetryPolicyFunc) ShouldRetry(resp *http.Response, err error, attempt int) bool {
+	return f(resp, err, attempt)
+}
+
+// DefaultRetryPolicy retries errors, 5xx responses and 409 Conflict. It is used when the
+// client's RetryPolicy is nil.
+var DefaultRetryPolicy RetryPolicy = RetryPolicyFunc(func(resp *http.Response, err error, attempt int) bool {
+	return wantRetry(err, resp)
+})
+
+// ErrMaxRetriesExceeded is returned by Do, wrapping the error of the last attempt, when
+// a request still fails with an error after all of its retries, or, if the client's
+// ErrorOnRetriesExhausted is set, wrapping a *StatusError when it still gets a retryable
+// response. The last response, if there was one, is returned with it.
+type ErrMaxRetriesExceeded struct {
+	Method   string
+	URL      string
+	Attempts int
+	Elapsed  time.Duration
+	// LastStatusCode is the status of the last response received, if any attempt got one.
+	LastStatusCode int
+	Err            error
+}
+
+func (e *ErrMaxRetriesExceeded) Error() string {
+	return fmt.Sprintf("rchttp: %s %s failed after %d attempts in %s: %v", e.Method, e.URL, e.Attempts, e.Elapsed, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *ErrMaxRetriesExceeded) Unwrap() error {
+	return e.Err
+}
+
+// shouldRetry reports whether to retry after attempt, according to the RetryPolicy of the
+// call's Options or the client. Requests which failed because local resources are exhausted, which
+// possibly created a resource, or whose host's circuit is open, are never retried.
+func (c *Client) shouldRetry(ctx context.Context, resp *http.Response, err error, attempt int) bool {
+	var exhausted *ResourceExhaustedError
+	var created *PossiblyCreatedError
+	if errors.As(err, &exhausted) || errors.As(err, &created) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if opts := callOptions(ctx); opts != nil && opts.RetryPolicy != nil {
+		return opts.RetryPolicy.ShouldRetry(resp, err, attempt)
+	}
+	if c.RetryPolicy == nil {
+		if _, ok := c.retryAfterDelay(resp); ok && resp.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		if c.RetryableStatusCodes != nil && err == nil {
+			return c.isRetryableStatus(resp.StatusCode)
+		}
+		return DefaultRetryPolicy.ShouldRetry(resp, err, attempt)
+	}
+	return c.RetryPolicy.ShouldRetry(resp, err, attempt)
+}
+
+func (c *Client) isRetryableStatus(statusCode int) bool {
+	for _, code := range c.RetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfterDelay returns the delay requested by the Retry-After header of a 429 or 503
+// response, if the client honours it.
+func (c *Client) retryAfterDelay(resp *http.Response) (time.Duration, bool) {
+	if !c.HonorRetryAfter || resp == nil {
+		return 0, false
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	return retryAfter(resp.Header)
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an HTTP date.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,348 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientRetryPolicy(t *testing.T) {
+	Convey("Given a server which always returns 404", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusNotFound)
+		defer ts.Close()
+
+		Convey("When the client has the default retry policy", func() {
+			httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 3, RetryTime: time.Millisecond}
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the request is not retried", func() {
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the client has a policy retrying 404s for up to 2 attempts", func() {
+			var attempts []int
+			httpClient := &Client{
+				HTTPClient: &http.Client{},
+				MaxRetries: 3,
+				RetryTime:  time.Millisecond,
+				RetryPolicy: RetryPolicyFunc(func(resp *http.Response, err error, attempt int) bool {
+					attempts = append(attempts, attempt)
+					return err == nil && resp.StatusCode == http.StatusNotFound && attempt < 2
+				}),
+			}
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the policy decides the retries", func() {
+				So(ts.GetCalls(0), ShouldEqual, 2)
+				So(attempts, ShouldResemble, []int{1, 2})
+			})
+		})
+	})
+
+	Convey("Given a server which always returns 500 and a policy never retrying", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusInternalServerError)
+		defer ts.Close()
+		httpClient := &Client{
+			HTTPClient: &http.Client{},
+			MaxRetries: 3,
+			RetryTime:  time.Millisecond,
+			RetryPolicy: RetryPolicyFunc(func(resp *http.Response, err error, attempt int) bool {
+				return false
+			}),
+		}
+
+		Convey("When a request is made", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then it is not retried", func() {
+				So(ts.GetCalls(0), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestClientHonorRetryAfter(t *testing.T) {
+	Convey("Given a server which first returns a status with Retry-After: 0", t, func() {
+		status := http.StatusTooManyRequests
+		calls := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(status)
+			}
+		}))
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 3, RetryTime: time.Hour}
+
+		Convey("When the client honours Retry-After and gets a 429", func() {
+			httpClient.HonorRetryAfter = true
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then it is retried straight away instead of after the backoff", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(calls, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the client honours Retry-After and gets a 503", func() {
+			httpClient.HonorRetryAfter = true
+			status = http.StatusServiceUnavailable
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then it is retried straight away instead of after the backoff", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(calls, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the client does not honour Retry-After and gets a 429", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then it is not retried", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusTooManyRequests)
+				So(calls, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given Retry-After headers", t, func() {
+		Convey("Then both seconds and HTTP dates are parsed", func() {
+			delay, ok := retryAfter(http.Header{"Retry-After": {"120"}})
+			So(ok, ShouldBeTrue)
+			So(delay, ShouldEqual, 2*time.Minute)
+
+			date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+			delay, ok = retryAfter(http.Header{"Retry-After": {date}})
+			So(ok, ShouldBeTrue)
+			So(delay, ShouldBeGreaterThan, 58*time.Second)
+			So(delay, ShouldBeLessThanOrEqualTo, time.Minute)
+
+			_, ok = retryAfter(http.Header{"Retry-After": {"soon"}})
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestClientRetryableStatusCodes(t *testing.T) {
+	Convey("Given an rchttp client retrying 429 and 460 but not 501", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 2, RetryTime: time.Millisecond, RetryableStatusCodes: []int{429, 460}}
+
+		for _, tc := range []struct {
+			status int
+			calls  int
+		}{{429, 3}, {460, 3}, {501, 1}, {409, 1}} {
+			Convey(fmt.Sprintf("When the server returns %d", tc.status), func() {
+				ts := rchttptest.NewTestServer(tc.status)
+				defer ts.Close()
+				resp, err := httpClient.Get(context.Background(), ts.URL)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+
+				Convey("Then it is retried only if it is in the set", func() {
+					So(ts.GetCalls(0), ShouldEqual, tc.calls)
+				})
+			})
+		}
+	})
+}
+
+func TestClientMaxElapsedTime(t *testing.T) {
+	Convey("Given a server which always fails and a client with many retries", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusInternalServerError)
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 10, RetryTime: 10 * time.Millisecond}
+
+		Convey("When the retries are limited to a total elapsed time", func() {
+			httpClient.MaxElapsedTime = 100 * time.Millisecond
+			start := time.Now()
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then retrying stops within the budget, returning the last response", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusInternalServerError)
+				resp.Body.Close()
+				So(time.Since(start), ShouldBeLessThan, 100*time.Millisecond+50*time.Millisecond)
+				// sleeps of 20, 40 and 80ms: only the first two fit in the budget
+				So(ts.GetCalls(0), ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func TestClientMaxRetryTime(t *testing.T) {
+	Convey("Given a server which always fails", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusInternalServerError)
+		defer ts.Close()
+
+		Convey("When the sleep between retries is capped", func() {
+			httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 5, RetryTime: 10 * time.Millisecond, MaxRetryTime: 15 * time.Millisecond}
+			start := time.Now()
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then no sleep exceeds the cap", func() {
+				So(ts.GetCalls(0), ShouldEqual, 6)
+				// uncapped, the sleeps would total 620ms
+				So(time.Since(start), ShouldBeLessThan, 300*time.Millisecond)
+			})
+		})
+
+		Convey("When there are enough retries for the backoff to overflow", func() {
+			httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 70, RetryTime: time.Millisecond, MaxRetryTime: time.Millisecond}
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the sleeps are still capped", func() {
+				So(ts.GetCalls(0), ShouldEqual, 71)
+			})
+		})
+	})
+}
+
+func TestClientMaxRetriesExceeded(t *testing.T) {
+	Convey("Given an rchttp client retrying a downstream which fails", t, func() {
+		attempts := 0
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+			}
+			return nil, errors.New("connection reset")
+		})
+		httpClient := &Client{HTTPClient: &http.Client{Transport: transport}, MaxRetries: 2, RetryTime: time.Millisecond}
+
+		Convey("When every attempt fails", func() {
+			_, err := httpClient.Get(context.Background(), "http://localhost/datasets")
+
+			Convey("Then an *ErrMaxRetriesExceeded wrapping the last error is returned", func() {
+				var exceeded *ErrMaxRetriesExceeded
+				So(errors.As(err, &exceeded), ShouldBeTrue)
+				So(exceeded.Attempts, ShouldEqual, 3)
+				So(exceeded.LastStatusCode, ShouldEqual, http.StatusServiceUnavailable)
+				So(exceeded.Elapsed, ShouldBeGreaterThan, 0)
+				So(exceeded.Err.Error(), ShouldContainSubstring, "connection reset")
+				So(err.Error(), ShouldContainSubstring, "failed after 3 attempts")
+			})
+		})
+
+		Convey("When an attempt fails with an error which is not retried", func() {
+			httpClient.RetryPolicy = RetryPolicyFunc(func(resp *http.Response, err error, attempt int) bool { return err == nil })
+			_, err := httpClient.Get(context.Background(), "http://localhost/datasets")
+
+			Convey("Then the error is returned as is", func() {
+				var exceeded *ErrMaxRetriesExceeded
+				So(errors.As(err, &exceeded), ShouldBeFalse)
+				So(attempts, ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestClientErrorOnRetriesExhausted(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"database unavailable"}`))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client retrying a server which always returns 503", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 2, RetryTime: time.Millisecond}
+
+		Convey("When retries run out by default", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the last response is returned without an error", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When retries run out with ErrorOnRetriesExhausted set", func() {
+			httpClient.ErrorOnRetriesExhausted = true
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then an *ErrMaxRetriesExceeded is returned with the last response and its body", func() {
+				var exceeded *ErrMaxRetriesExceeded
+				So(errors.As(err, &exceeded), ShouldBeTrue)
+				So(exceeded.Attempts, ShouldEqual, 3)
+				So(exceeded.LastStatusCode, ShouldEqual, http.StatusServiceUnavailable)
+				var statusErr *StatusError
+				So(errors.As(err, &statusErr), ShouldBeTrue)
+				So(statusErr.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+
+				So(resp, ShouldNotBeNil)
+				b, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				So(string(b), ShouldEqual, `{"error":"database unavailable"}`)
+			})
+		})
+	})
+}
+
+func TestClientOnRetry(t *testing.T) {
+	Convey("Given an rchttp client with an OnRetry hook", t, func() {
+		ts := rchttptest.NewTestServer(http.StatusServiceUnavailable)
+		defer ts.Close()
+
+		var attempts, statuses []int
+		var requests []*http.Request
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 2, RetryTime: time.Millisecond}
+		httpClient.OnRetry = func(ctx context.Context, req *http.Request, attempt int, resp *http.Response, err error) {
+			So(err, ShouldBeNil)
+			attempts = append(attempts, attempt)
+			statuses = append(statuses, resp.StatusCode)
+			requests = append(requests, req)
+		}
+
+		Convey("When a request is retried", func() {
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			resp, err := httpClient.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the hook is called before each retry", func() {
+				So(attempts, ShouldResemble, []int{1, 2})
+				So(statuses, ShouldResemble, []int{503, 503})
+				So(requests[0], ShouldEqual, req)
+				So(ts.GetCalls(0), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When a request succeeds first time", func() {
+			ok := rchttptest.NewTestServer(http.StatusOK)
+			defer ok.Close()
+			resp, err := httpClient.Get(context.Background(), ok.URL)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the hook is not called", func() {
+				So(attempts, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,138 @@
+package rchttp
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+const priorityKey = contextKey("rchttp-priority")
+
+// ErrClientShutdown is returned for requests made after Shutdown has been called.
+var ErrClientShutdown = errors.New("rchttp: client is shut down")
+
+// Priority is the class of a request, used by Shutdown to decide which in-flight requests
+// to cancel immediately and which to let drain.
+type Priority int
+
+// Request priorities. Requests are PriorityInteractive unless made with WithPriority.
+const (
+	PriorityInteractive Priority = iota
+	PriorityBatch
+)
+
+// WithPriority returns a context which makes requests made with it of the given priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey, priority)
+}
+
+func priority(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey).(Priority)
+	return p
+}
+
+// shutdownTracker tracks in-flight requests, from when they are made until their response
+// body is closed, so that Shutdown can cancel or wait for them.
+type shutdownTracker struct {
+	mutex    sync.Mutex
+	closed   bool
+	seq      uint64
+	requests map[uint64]trackedRequest
+	drained  chan struct{}
+	once     sync.Once
+}
+
+type trackedRequest struct {
+	priority Priority
+	cancel   context.CancelFunc
+}
+
+// EnableShutdown makes the client track its in-flight requests so that Shutdown can
+// cancel or drain them.
+func (c *Client) EnableShutdown() {
+	c.shutdown = &shutdownTracker{requests: make(map[uint64]trackedRequest), drained: make(chan struct{})}
+}
+
+// Shutdown stops the client making new requests, immediately cancels in-flight requests
+// of the given priorities (e.g. PriorityBatch), and waits for the others to complete
+// (including reading their response bodies) until ctx is done, when they are cancelled
+// too and ctx's error is returned. EnableShutdown must have been called first.
+func (c *Client) Shutdown(ctx context.Context, cancel ...Priority) error {
+	t := c.shutdown
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	t.closed = true
+	for _, req := range t.requests {
+		for _, p := range cancel {
+			if req.priority == p {
+				req.cancel()
+			}
+		}
+	}
+	t.checkDrained()
+	t.mutex.Unlock()
+
+	select {
+	case <-t.drained:
+		return nil
+	case <-ctx.Done():
+		t.mutex.Lock()
+		for _, req := range t.requests {
+			req.cancel()
+		}
+		t.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// track registers a request made with ctx, returning the context to make it with and a
+// function to call once it has completed.
+func (t *shutdownTracker) track(ctx context.Context) (context.Context, func(), error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.closed {
+		return nil, nil, ErrClientShutdown
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t.seq++
+	id := t.seq
+	t.requests[id] = trackedRequest{priority: priority(ctx), cancel: cancel}
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			delete(t.requests, id)
+			t.checkDrained()
+		})
+	}, nil
+}
+
+// checkDrained signals that the tracker has drained. It must be called with the mutex held.
+func (t *shutdownTracker) checkDrained() {
+	if t.closed && len(t.requests) == 0 {
+		t.once.Do(func() {
+			close(t.drained)
+		})
+	}
+}
+
+// finishOnClose calls finish when the response body it wraps is closed.
+type finishOnClose struct {
+	io.ReadCloser
+	finish func()
+}
+
+func (body *finishOnClose) Close() error {
+	err := body.ReadCloser.Close()
+	body.finish()
+	return err
+}
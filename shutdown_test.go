@@ -0,0 +1,112 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientShutdown(t *testing.T) {
+	Convey("Given an rchttp client with batch and interactive requests in flight", t, func() {
+		var arrived int32
+		release := make(chan struct{})
+		var releaseOnce sync.Once
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&arrived, 1)
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer ts.Close()
+		defer releaseOnce.Do(func() { close(release) })
+
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		httpClient.EnableShutdown()
+
+		batchErr := make(chan error, 1)
+		interactiveErr := make(chan error, 1)
+		do := func(ctx context.Context, errs chan error) {
+			resp, err := httpClient.Get(ctx, ts.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			errs <- err
+		}
+		go do(WithPriority(context.Background(), PriorityBatch), batchErr)
+		go do(context.Background(), interactiveErr)
+		So(waitFor(func() bool { return atomic.LoadInt32(&arrived) == 2 }), ShouldBeTrue)
+
+		Convey("When the client is shut down cancelling batch requests", func() {
+			shutdownErr := make(chan error, 1)
+			go func() {
+				shutdownErr <- httpClient.Shutdown(context.Background(), PriorityBatch)
+			}()
+
+			Convey("Then the batch request is cancelled immediately", func() {
+				So(errors.Is(<-batchErr, context.Canceled), ShouldBeTrue)
+			})
+
+			Convey("And new requests are refused", func() {
+				<-batchErr
+				_, err := httpClient.Get(context.Background(), ts.URL)
+				So(err, ShouldEqual, ErrClientShutdown)
+			})
+
+			Convey("And the interactive request drains before Shutdown returns", func() {
+				select {
+				case <-shutdownErr:
+					t.Fatal("shutdown returned before the interactive request completed")
+				case <-time.After(50 * time.Millisecond):
+				}
+				releaseOnce.Do(func() { close(release) })
+				So(<-interactiveErr, ShouldBeNil)
+				So(<-shutdownErr, ShouldBeNil)
+			})
+		})
+
+		Convey("When the shutdown deadline passes before requests drain", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			err := httpClient.Shutdown(ctx)
+
+			Convey("Then the remaining requests are cancelled and the deadline error returned", func() {
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+				So(errors.Is(<-batchErr, context.Canceled), ShouldBeTrue)
+				So(errors.Is(<-interactiveErr, context.Canceled), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestClientShutdownAfterErrorResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"errors":["internal error"]}`))
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client which returns failed responses with an error", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, ErrorBodySnapshotSize: 100}
+		httpClient.EnableShutdown()
+
+		Convey("When a request fails with a body which the caller does not close", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+			So(err, ShouldNotBeNil)
+			So(resp, ShouldNotBeNil)
+
+			Convey("Then Shutdown does not wait for it", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				So(httpClient.Shutdown(ctx), ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package rchttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+)
+
+var (
+	sensitiveJSONFields = regexp.MustCompile(`("(?i:password|passwd|secret|token|access_token|refresh_token|authorization|api_key|apikey)"\s*:\s*)"(?:[^"\\]|\\.)*("|$)`)
+	sensitiveFormFields = regexp.MustCompile(`((?i:password|passwd|secret|token|access_token|refresh_token|authorization|api_key|apikey)=)[^&]*`)
+)
+
+// RequestError is returned by Do, when ErrorBodySnapshotSize is set, for requests which
+// failed permanently (i.e. still failed after any retries). It holds the start of the
+// request and response bodies, redacted, to aid diagnosis.
+type RequestError struct {
+	Method       string
+	URL          string
+	StatusCode   int
+	RequestBody  string
+	ResponseBody string
+	Err          error
+}
+
+func (e *RequestError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("rchttp: %s %s failed: %s", e.Method, e.URL, e.Err)
+	}
+	return fmt.Sprintf("rchttp: %s %s failed with status %d", e.Method, e.URL, e.StatusCode)
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// RedactBody replaces the values of commonly sensitive JSON and form-encoded fields
+// (passwords, secrets, tokens and API keys) in b.
+func RedactBody(b []byte) []byte {
+	b = sensitiveJSONFields.ReplaceAll(b, []byte(`$1"[REDACTED]"`))
+	return sensitiveFormFields.ReplaceAll(b, []byte(`${1}[REDACTED]`))
+}
+
+// snapshotFailure wraps the outcome of a permanently failed request in a RequestError. The
+// response (if any) is returned with its body intact.
+func (c *Client) snapshotFailure(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+	redact := c.ErrorBodyRedactor
+	if redact == nil {
+		redact = RedactBody
+	}
+	size := int64(c.ErrorBodySnapshotSize)
+
+	reqErr := &RequestError{Method: req.Method, URL: req.URL.String(), Err: err}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(io.LimitReader(body, size))
+			body.Close()
+			reqErr.RequestBody = string(redact(b))
+		}
+	}
+	if resp != nil {
+		reqErr.StatusCode = resp.StatusCode
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, size))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+		reqErr.ResponseBody = string(redact(b))
+	}
+	return resp, reqErr
+}
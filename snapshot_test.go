@@ -0,0 +1,50 @@
+package rchttp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedactBody(t *testing.T) {
+	Convey("Sensitive JSON and form fields are redacted", t, func() {
+		So(string(RedactBody([]byte(`{"user":"bob","password":"hunter\"2","Token": "abc"}`))), ShouldEqual, `{"user":"bob","password":"[REDACTED]","Token": "[REDACTED]"}`)
+		So(string(RedactBody([]byte(`{"secret":"trunc`))), ShouldEqual, `{"secret":"[REDACTED]"`)
+		So(string(RedactBody([]byte(`user=bob&password=hunter2&x=y`))), ShouldEqual, `user=bob&password=[REDACTED]&x=y`)
+	})
+}
+
+func TestClientSnapshotsBodiesOnFailure(t *testing.T) {
+	Convey("Given an rchttp client with error body snapshots and a server which always fails", t, func() {
+		ts := rchttptest.NewTestServer(500)
+		defer ts.Close()
+
+		httpClient := ClientWithTimeout(nil, 5*time.Second).(*Client)
+		httpClient.MaxRetries = 0
+		httpClient.ErrorBodySnapshotSize = 30
+
+		Convey("When Post() is called", func() {
+			resp, err := httpClient.Post(context.Background(), ts.URL, "text/plain", strings.NewReader(`{"password":"hunter2","padding":"xxxxxxxx"}`))
+
+			Convey("Then a RequestError with redacted, truncated snapshots is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &RequestError{})
+				reqErr := err.(*RequestError)
+				So(reqErr.Method, ShouldEqual, "POST")
+				So(reqErr.StatusCode, ShouldEqual, 500)
+				So(reqErr.RequestBody, ShouldEqual, `{"password":"[REDACTED]","padding`)
+				So(reqErr.ResponseBody, ShouldHaveLength, 30)
+				So(err.Error(), ShouldContainSubstring, "failed with status 500")
+
+				Convey("And the response body is still complete", func() {
+					call, err := unmarshallResp(resp)
+					So(err, ShouldBeNil)
+					So(call.Method, ShouldEqual, "POST")
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package rchttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSoak(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1)%4 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client without retries", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+
+		Convey("When it is soaked against a server", func() {
+			result := rchttptest.Soak(httpClient, ts.URL, 100, 200*time.Millisecond)
+
+			Convey("Then the requests are made at the given rate", func() {
+				So(result.Requests, ShouldBeBetweenOrEqual, 10, 21)
+				So(result.Errors, ShouldEqual, 0)
+				So(result.StatusCodes[http.StatusOK]+result.StatusCodes[http.StatusServiceUnavailable], ShouldEqual, result.Requests)
+				So(result.StatusCodes[http.StatusServiceUnavailable], ShouldBeGreaterThan, 0)
+			})
+
+			Convey("And their latencies are summarised", func() {
+				So(result.Min, ShouldBeLessThanOrEqualTo, result.P50)
+				So(result.P50, ShouldBeLessThanOrEqualTo, result.P99)
+				So(result.P99, ShouldBeLessThanOrEqualTo, result.Max)
+				So(result.Max, ShouldBeGreaterThanOrEqualTo, 5*time.Millisecond)
+				So(result.String(), ShouldContainSubstring, "requests in")
+			})
+		})
+
+		Convey("When it is soaked against a server which is down", func() {
+			result := rchttptest.Soak(httpClient, "http://127.0.0.1:1", 50, 100*time.Millisecond)
+
+			Convey("Then the errors are counted", func() {
+				So(result.Requests, ShouldBeGreaterThan, 0)
+				So(result.Errors, ShouldEqual, result.Requests)
+			})
+		})
+	})
+}
@@ -0,0 +1,19 @@
+package rchttp
+
+// Stats is a snapshot of what the client has observed, for debug endpoints and health checks.
+type Stats struct {
+	// OpenConnections is the number of connections open, if EnableConnectionGauge was called.
+	OpenConnections int64
+	// CertExpiries are the leaf certificate expiries of the hosts called over TLS (soonest
+	// first), if EnableCertExpiryMonitoring was called.
+	CertExpiries []CertExpiry
+}
+
+// Stats returns a snapshot of the client's statistics.
+func (c *Client) Stats() Stats {
+	stats := Stats{OpenConnections: c.OpenConnections()}
+	if c.certMonitor != nil {
+		stats.CertExpiries = c.certMonitor.list()
+	}
+	return stats
+}
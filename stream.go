@@ -0,0 +1,60 @@
+package rchttp
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// streamChunkSize is the most of a response body passed to a GetStream callback at once.
+const streamChunkSize = 32 * 1024
+
+// GetStream calls Get and calls fn with each chunk of the response body as soon as it
+// arrives (e.g. as each flush by the server is received), rather than buffering the whole
+// body. The body is not read further until fn returns, so a slow fn applies backpressure
+// to the server. It stops and returns the error if fn returns an error or ctx is done.
+// The chunk slice is reused between calls, so fn must copy it to keep it.
+func (c *Client) GetStream(ctx context.Context, url string, fn func(chunk []byte) error) (*CallInfo, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	info := c.newCallInfo(resp, start)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxStatusErrorBody))
+		return info, statusError(req, resp, b)
+	}
+
+	chunk := make([]byte, streamChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return info, err
+		}
+		n, err := resp.Body.Read(chunk)
+		if n > 0 {
+			if err := fn(chunk[:n]); err != nil {
+				return info, err
+			}
+		}
+		if err == io.EOF {
+			return info, nil
+		}
+		if err != nil {
+			return info, err
+		}
+	}
+}
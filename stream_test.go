@@ -0,0 +1,75 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientGetStream(t *testing.T) {
+	next := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		for _, part := range []string{"one", "two", "three"} {
+			w.Write([]byte(part))
+			w.(http.Flusher).Flush()
+			select {
+			case <-next:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client and a server flushing its response in parts", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}}
+
+		Convey("When the response is streamed", func() {
+			var chunks []string
+			info, err := httpClient.GetStream(context.Background(), ts.URL, func(chunk []byte) error {
+				chunks = append(chunks, string(chunk))
+				// the server only sends the next part once this one has been received
+				next <- struct{}{}
+				return nil
+			})
+
+			Convey("Then each part is passed to the callback as it arrives", func() {
+				So(err, ShouldBeNil)
+				So(info.StatusCode, ShouldEqual, http.StatusOK)
+				So(chunks, ShouldResemble, []string{"one", "two", "three"})
+			})
+		})
+
+		Convey("When the callback returns an error", func() {
+			stop := errors.New("stop")
+			calls := 0
+			_, err := httpClient.GetStream(context.Background(), ts.URL, func(chunk []byte) error {
+				calls++
+				return stop
+			})
+
+			Convey("Then streaming stops with that error", func() {
+				So(err, ShouldEqual, stop)
+				So(calls, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the response is not successful", func() {
+			_, err := httpClient.GetStream(context.Background(), ts.URL+"/missing", func(chunk []byte) error {
+				return nil
+			})
+
+			Convey("Then a status error is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &StatusError{})
+			})
+		})
+	})
+}
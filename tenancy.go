@@ -0,0 +1,107 @@
+package rchttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultTenantHeader is the header carrying the tenant of a request when the
+// TenancyGuard does not name one.
+const DefaultTenantHeader = "Collection-Id"
+
+const tenantKey = contextKey("rchttp-tenant")
+
+var (
+	// ErrMissingTenant is wrapped in a *TenancyError for a request to a guarded host made
+	// with no tenant in its context or header.
+	ErrMissingTenant = errors.New("rchttp: request has no tenant")
+	// ErrTenantMismatch is wrapped in a *TenancyError for a request to a guarded host whose
+	// tenant header differs from the tenant in its context.
+	ErrTenantMismatch = errors.New("rchttp: request tenant header does not match its context")
+)
+
+// TenancyGuard requires every request to its hosts to carry a tenant (e.g. a collection
+// ID), so that a forgotten header cannot leak data between tenants.
+type TenancyGuard struct {
+	// Hosts are the guarded hosts, as "host" (any port) or "host:port".
+	Hosts []string
+	// Header carries the tenant, DefaultTenantHeader if empty.
+	Header string
+	// RequireContext, if set, requires the tenant to come from the context (see WithTenant)
+	// rather than accepting a header set on the request.
+	RequireContext bool
+}
+
+// TenancyError is returned by Do for a request to a host guarded by the client's
+// TenancyGuard which does not carry the right tenant. The request is not sent.
+type TenancyError struct {
+	Method string
+	URL    string
+	Header string
+	Err    error
+}
+
+func (e *TenancyError) Error() string {
+	return fmt.Sprintf("%s: %s %s (%s)", e.Err, e.Method, e.URL, e.Header)
+}
+
+// Unwrap returns ErrMissingTenant or ErrTenantMismatch.
+func (e *TenancyError) Unwrap() error {
+	return e.Err
+}
+
+// WithTenant returns a context carrying the tenant of requests made with it, which is set
+// in the TenancyGuard's header of requests to guarded hosts.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+func tenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey).(string)
+	return tenant
+}
+
+// enforceTenancy sets the tenant from ctx on req if it is to a guarded host, returning a
+// *TenancyError if it has none or a different one.
+func (c *Client) enforceTenancy(ctx context.Context, req *http.Request) error {
+	guard := c.TenancyGuard
+	if guard == nil || !guard.guards(req) {
+		return nil
+	}
+	header := guard.header()
+	tenant, set := tenantFromContext(ctx), req.Header.Get(header)
+
+	var err error
+	switch {
+	case tenant == "" && (set == "" || guard.RequireContext):
+		err = ErrMissingTenant
+	case tenant != "" && set != "" && set != tenant:
+		err = ErrTenantMismatch
+	case tenant != "":
+		req.Header.Set(header, tenant)
+	}
+	if err != nil {
+		return &TenancyError{Method: req.Method, URL: req.URL.String(), Header: header, Err: err}
+	}
+	return nil
+}
+
+func (guard *TenancyGuard) guards(req *http.Request) bool {
+	for _, host := range guard.Hosts {
+		if strings.EqualFold(host, req.URL.Host) || strings.EqualFold(host, req.URL.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (guard *TenancyGuard) header() string {
+	if guard.Header != "" {
+		return guard.Header
+	}
+	return DefaultTenantHeader
+}
@@ -0,0 +1,74 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientTenancyGuard(t *testing.T) {
+	ts := rchttptest.NewTestServer(200)
+	defer ts.Close()
+	tsURL, _ := url.Parse(ts.URL)
+
+	Convey("Given an rchttp client guarding the test server's host", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, TenancyGuard: &TenancyGuard{Hosts: []string{tsURL.Hostname()}}}
+		calls := ts.GetCalls(0)
+
+		Convey("When a request is made with a tenant in its context", func() {
+			resp, err := httpClient.Get(WithTenant(context.Background(), "collection-1"), ts.URL)
+
+			Convey("Then the tenant header is set", func() {
+				So(err, ShouldBeNil)
+				call, _ := unmarshallResp(resp)
+				So(call.Headers[DefaultTenantHeader], ShouldResemble, []string{"collection-1"})
+			})
+		})
+
+		Convey("When a request is made with no tenant", func() {
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then it is not sent and a *TenancyError is returned", func() {
+				So(resp, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &TenancyError{})
+				So(errors.Is(err, ErrMissingTenant), ShouldBeTrue)
+				So(ts.GetCalls(0), ShouldEqual, calls)
+			})
+		})
+
+		Convey("When a request sets the tenant header itself", func() {
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			req.Header.Set(DefaultTenantHeader, "collection-2")
+
+			Convey("Then it is sent", func() {
+				_, err := httpClient.Do(context.Background(), req)
+				So(err, ShouldBeNil)
+			})
+
+			Convey("And with a different tenant in its context, ErrTenantMismatch is returned", func() {
+				_, err := httpClient.Do(WithTenant(context.Background(), "collection-1"), req)
+				So(errors.Is(err, ErrTenantMismatch), ShouldBeTrue)
+			})
+
+			Convey("And if the guard requires the tenant from the context, ErrMissingTenant is returned", func() {
+				httpClient.TenancyGuard.RequireContext = true
+				_, err := httpClient.Do(context.Background(), req)
+				So(errors.Is(err, ErrMissingTenant), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a request is made to a host which is not guarded", func() {
+			httpClient.TenancyGuard.Hosts = []string{"dataset-api"}
+			_, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then no tenant is required", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package rchttp
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Sentinel errors matched (with errors.Is) by the errors Do returns for timeouts, so that
+// downstream slowness can be told apart from the caller running out of time.
+var (
+	// ErrAttemptTimeout matches a request whose (last) attempt exceeded the HTTP client's
+	// timeout, after any retries.
+	ErrAttemptTimeout = errors.New("rchttp: request attempt timed out")
+	// ErrCallerDeadline matches a request abandoned because the caller's context deadline
+	// was exceeded.
+	ErrCallerDeadline = errors.New("rchttp: caller's context deadline exceeded")
+)
+
+// DefaultMinAttemptTime is the least time an attempt is expected to take, when the
+// client's MinAttemptTime is zero.
+const DefaultMinAttemptTime = 10 * time.Millisecond
+
+// TimeoutError is returned by Do when a request times out. It matches Reason (one of
+// ErrAttemptTimeout or ErrCallerDeadline) with errors.Is, and otherwise reads and unwraps
+// as the underlying error.
+type TimeoutError struct {
+	Reason error
+	Err    error
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the reason for the timeout.
+func (e *TimeoutError) Is(target error) bool {
+	return target == e.Reason
+}
+
+// Timeout reports true, as for a net.Error.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary reports false, as for a net.Error.
+func (e *TimeoutError) Temporary() bool {
+	return false
+}
+
+func (c *Client) minAttemptTime() time.Duration {
+	if c.MinAttemptTime > 0 {
+		return c.MinAttemptTime
+	}
+	return DefaultMinAttemptTime
+}
+
+// classifyTimeout wraps err in a *TimeoutError if it is due to the deadline of ctx or to
+// an attempt timing out.
+func classifyTimeout(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*TimeoutError); ok {
+		return err
+	}
+	if ctx.Err() == context.DeadlineExceeded && errors.Is(err, context.DeadlineExceeded) {
+		return &TimeoutError{Reason: ErrCallerDeadline, Err: err}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &TimeoutError{Reason: ErrAttemptTimeout, Err: err}
+	}
+	return err
+}
+
+// isAttemptTimeout reports whether err is from an attempt exceeding its own timeout,
+// rather than the caller's context ending.
+func isAttemptTimeout(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// escalateTimeout returns client with its timeout escalated for an attempt made after
+// timeouts attempts have timed out.
+func (c *Client) escalateTimeout(client *http.Client, timeouts int) *http.Client {
+	if timeouts == 0 || c.AttemptTimeoutEscalation <= 1 || client.Timeout <= 0 {
+		return client
+	}
+	timeout := float64(client.Timeout) * math.Pow(c.AttemptTimeoutEscalation, float64(timeouts))
+	if c.MaxAttemptTimeout > 0 && timeout > float64(c.MaxAttemptTimeout) {
+		timeout = float64(c.MaxAttemptTimeout)
+	}
+	escalated := *client
+	escalated.Timeout = time.Duration(timeout)
+	return &escalated
+}
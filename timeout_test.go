@@ -0,0 +1,135 @@
+package rchttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientTimeoutErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with a short timeout and a slow server", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 50 * time.Millisecond}, MaxRetries: 1, RetryTime: time.Millisecond}
+
+		Convey("When every attempt times out", func() {
+			_, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the error matches ErrAttemptTimeout", func() {
+				So(errors.Is(err, ErrAttemptTimeout), ShouldBeTrue)
+				So(errors.Is(err, ErrCallerDeadline), ShouldBeFalse)
+				So(err.Error(), ShouldContainSubstring, "Timeout exceeded")
+			})
+		})
+
+		Convey("When the caller's deadline is exceeded first", func() {
+			httpClient.HTTPClient.Timeout = 5 * time.Second
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := httpClient.Get(ctx, ts.URL)
+
+			Convey("Then the error matches ErrCallerDeadline and the context error", func() {
+				So(errors.Is(err, ErrCallerDeadline), ShouldBeTrue)
+				So(errors.Is(err, ErrAttemptTimeout), ShouldBeFalse)
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestClientAttemptTimeoutEscalation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(150 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+
+	Convey("Given a server which is slow to respond and a client with a short attempt timeout", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: 50 * time.Millisecond}, MaxRetries: 1, RetryTime: time.Millisecond}
+
+		Convey("When the attempt timeout is not escalated", func() {
+			_, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then every attempt times out", func() {
+				So(errors.Is(err, ErrAttemptTimeout), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the attempt timeout is escalated after a timeout", func() {
+			httpClient.AttemptTimeoutEscalation = 4
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the retry has long enough to succeed", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				resp.Body.Close()
+			})
+		})
+
+		Convey("When the escalated timeout is capped", func() {
+			httpClient.AttemptTimeoutEscalation = 4
+			httpClient.MaxAttemptTimeout = 100 * time.Millisecond
+			_, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the retry is limited to the cap", func() {
+				So(errors.Is(err, ErrAttemptTimeout), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestClientSkipsRetriesPastDeadline(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client sleeping for a second before its retry", t, func() {
+		atomic.StoreInt32(&calls, 0)
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 1, RetryTime: 500 * time.Millisecond, Jitter: JitterNone}
+
+		Convey("When the context's deadline is before the retry could complete", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			resp, err := httpClient.Get(ctx, ts.URL)
+
+			Convey("Then the call fails at once with a deadline error and the last response, without retrying", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusInternalServerError)
+				resp.Body.Close()
+				So(errors.Is(err, ErrCallerDeadline), ShouldBeTrue)
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+				So(time.Since(start), ShouldBeLessThan, 100*time.Millisecond)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the context's deadline leaves time for the retry", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := httpClient.Get(ctx, ts.URL)
+
+			Convey("Then the request is retried", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+			})
+		})
+	})
+}
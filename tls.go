@@ -0,0 +1,160 @@
+package rchttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// ErrUnsupportedTransport is returned when configuring transport-level behaviour on a
+// client whose HTTPClient does not use an *http.Transport.
+var ErrUnsupportedTransport = errors.New("rchttp: HTTPClient.Transport is not an *http.Transport")
+
+// SetTLSServerName sets the server name used for SNI and certificate verification on all
+// TLS connections, regardless of the host in the URL, e.g. to connect by IP address while
+// validating the certificate for the real hostname.
+func (c *Client) SetTLSServerName(serverName string) error {
+	return c.SetTLSServerNameForHost("", serverName)
+}
+
+// SetTLSServerNameForHost sets the server name used for SNI and certificate verification
+// on TLS connections to host, which may be a "host:port" address or just a host. HTTP/2 is
+// still negotiated if the transport has ForceAttemptHTTP2 set, as http.DefaultTransport does.
+//
+// Requests tunnelled through a proxy (see SetProxy) are secured by the transport itself
+// rather than by the client's TLS dialler, so only the client-wide server name (host "")
+// applies to them; per-host server names are ignored for proxied requests.
+func (c *Client) SetTLSServerNameForHost(host, serverName string) error {
+	names := make(map[string]string, len(c.tlsServerNames)+1)
+	for h, name := range c.tlsServerNames {
+		names[h] = name
+	}
+	names[host] = serverName
+
+	transport, err := c.cloneTransport()
+	if err != nil {
+		return err
+	}
+	transport.DialTLSContext = dialTLSWithServerNames(transport, names)
+	if name, ok := names[""]; ok {
+		// the transport does its own TLS handshake through a proxy's CONNECT tunnel
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.ServerName = name
+	}
+
+	c.tlsServerNames = names
+	c.setTransport(transport)
+	return nil
+}
+
+// cloneTransport returns a copy of the client's transport, and gives the client its own copy
+// of HTTPClient, so that changes do not affect other clients sharing them (e.g. via NewClient).
+func (c *Client) cloneTransport() (*http.Transport, error) {
+	var transport *http.Transport
+	switch t := c.HTTPClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, ErrUnsupportedTransport
+	}
+
+	httpClient := *c.HTTPClient
+	c.HTTPClient = &httpClient
+	return transport, nil
+}
+
+// setTransport makes transport, from cloneTransport, the client's transport, closing the
+// idle connections of the transport it replaces if that was also the client's own, so they
+// are not left open until they time out. Shared transports are left untouched.
+func (c *Client) setTransport(transport *http.Transport) {
+	previous, owned := c.HTTPClient.Transport.(*http.Transport)
+	owned = owned && previous == c.transport
+	c.HTTPClient.Transport = transport
+	c.transport = transport
+	if owned && previous != transport {
+		previous.CloseIdleConnections()
+	}
+}
+
+func dialTLSWithServerNames(transport *http.Transport, names map[string]string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		config := &tls.Config{}
+		if transport.TLSClientConfig != nil {
+			config = transport.TLSClientConfig.Clone()
+		}
+		if name, ok := names[addr]; ok {
+			config.ServerName = name
+		} else if name, ok := names[host]; ok {
+			config.ServerName = name
+		} else if name, ok := names[""]; ok {
+			config.ServerName = name
+		} else if config.ServerName == "" {
+			config.ServerName = host
+		}
+		if len(config.NextProtos) == 0 && transport.ForceAttemptHTTP2 {
+			// the transport only offers h2 itself when it does the TLS dialling
+			config.NextProtos = []string{"h2", "http/1.1"}
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if transport.TLSHandshakeTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, transport.TLSHandshakeTimeout)
+			defer cancel()
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// ConnectionInfo describes the protocol and TLS parameters a response was received over,
+// e.g. for auditing what is actually negotiated in each environment.
+type ConnectionInfo struct {
+	// Protocol is the HTTP protocol of the response, e.g. "HTTP/1.1" or "HTTP/2.0".
+	Protocol string
+	// TLS is false for plain-text connections, in which case the remaining fields are empty.
+	TLS         bool
+	TLSVersion  string
+	CipherSuite string
+	// NegotiatedProtocol is the protocol agreed by ALPN, e.g. "h2", if any.
+	NegotiatedProtocol string
+	ServerName         string
+}
+
+// ResponseConnectionInfo returns the protocol and TLS parameters resp was received over.
+func ResponseConnectionInfo(resp *http.Response) *ConnectionInfo {
+	info := &ConnectionInfo{Protocol: resp.Proto}
+	if resp.TLS != nil {
+		info.TLS = true
+		info.TLSVersion = tls.VersionName(resp.TLS.Version)
+		info.CipherSuite = tls.CipherSuiteName(resp.TLS.CipherSuite)
+		info.NegotiatedProtocol = resp.TLS.NegotiatedProtocol
+		info.ServerName = resp.TLS.ServerName
+	}
+	return info
+}
@@ -0,0 +1,134 @@
+package rchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSetTLSServerName(t *testing.T) {
+	var seenServerName string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenServerName = r.TLS.ServerName
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client which trusts the test server certificate", t, func() {
+		shared := ts.Client()
+		httpClient := &Client{HTTPClient: shared}
+
+		Convey("When the server name for the test server address is set to the certificate's hostname", func() {
+			So(httpClient.SetTLSServerNameForHost(ts.Listener.Addr().String(), "example.com"), ShouldBeNil)
+
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the request succeeds and the server sees the overridden SNI", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(seenServerName, ShouldEqual, "example.com")
+			})
+
+			Convey("And the original HTTP client is not modified", func() {
+				So(httpClient.HTTPClient, ShouldNotEqual, shared)
+				So(shared.Transport.(*http.Transport).DialTLSContext, ShouldBeNil)
+			})
+		})
+
+		Convey("When the client-wide server name is set on a client sending requests through a proxy", func() {
+			proxy, _ := newConnectProxy()
+			defer proxy.Close()
+			So(httpClient.SetProxy(ProxyConfig{URL: proxy.URL, ConnectHeader: http.Header{"X-Proxy-Token": {"secret"}}}), ShouldBeNil)
+			So(httpClient.SetTLSServerName("example.com"), ShouldBeNil)
+
+			resp, err := httpClient.Get(context.Background(), ts.URL)
+
+			Convey("Then the server sees the overridden SNI through the tunnel", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(seenServerName, ShouldEqual, "example.com")
+			})
+		})
+
+		Convey("When the client-wide server name does not match the certificate", func() {
+			So(httpClient.SetTLSServerName("wrong.example.org"), ShouldBeNil)
+
+			_, err := httpClient.Get(WithBackoff(context.Background(), false), ts.URL)
+
+			Convey("Then certificate verification fails", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "certificate")
+			})
+		})
+	})
+
+	Convey("Given an rchttp client of a server supporting HTTP/2", t, func() {
+		h2 := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h2.EnableHTTP2 = true
+		h2.StartTLS()
+		defer h2.Close()
+		httpClient := &Client{HTTPClient: h2.Client()}
+
+		Convey("When a server name is set", func() {
+			So(httpClient.SetTLSServerName("example.com"), ShouldBeNil)
+			resp, err := httpClient.Get(context.Background(), h2.URL)
+
+			Convey("Then HTTP/2 is still negotiated", func() {
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(resp.ProtoMajor, ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given an rchttp client with a custom round tripper", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}}
+
+		Convey("Then setting the server name fails", func() {
+			So(httpClient.SetTLSServerName("example.com"), ShouldEqual, ErrUnsupportedTransport)
+		})
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestResponseConnectionInfo(t *testing.T) {
+	Convey("Given an HTTP/2 TLS test server", t, func() {
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		ts.EnableHTTP2 = true
+		ts.StartTLS()
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: ts.Client()}
+
+		Convey("When a helper is called", func() {
+			_, info, err := httpClient.GetBytes(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+
+			Convey("Then the negotiated protocol and TLS parameters are reported", func() {
+				So(info.Connection.Protocol, ShouldEqual, "HTTP/2.0")
+				So(info.Connection.TLS, ShouldBeTrue)
+				So(info.Connection.TLSVersion, ShouldEqual, "TLS 1.3")
+				So(info.Connection.CipherSuite, ShouldNotBeEmpty)
+				So(info.Connection.NegotiatedProtocol, ShouldEqual, "h2")
+			})
+		})
+	})
+
+	Convey("Given a plain-text test server", t, func() {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer ts.Close()
+		httpClient := &Client{HTTPClient: DefaultClient.HTTPClient}
+
+		Convey("Then the connection is reported as plain HTTP/1.1", func() {
+			_, info, err := httpClient.GetBytes(context.Background(), ts.URL)
+			So(err, ShouldBeNil)
+			So(info.Connection, ShouldResemble, &ConnectionInfo{Protocol: "HTTP/1.1"})
+		})
+	})
+}
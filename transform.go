@@ -0,0 +1,78 @@
+package rchttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// RequestTransformer rewrites the serialized body of an outgoing request, e.g. to redact
+// fields or to downgrade it to the schema version a downstream API still expects.
+type RequestTransformer func(req *http.Request, body []byte) ([]byte, error)
+
+// ResponseTransformer rewrites the body of a successful response before the helper methods
+// (GetBytes, GetJSON, GetAs, etc.) return or decode it, e.g. to upgrade an old payload shape
+// into the one the caller expects. The body is already transcoded to UTF-8 from the charset
+// of the response, whose Content-Type header is left as received.
+type ResponseTransformer func(resp *http.Response, body []byte) ([]byte, error)
+
+// transformRequestBody reads the body of req and passes it through the client's
+// RequestTransformers in order, replacing it with a replayable copy of the result.
+func (c *Client) transformRequestBody(req *http.Request) error {
+	if len(c.RequestTransformers) == 0 || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	for _, transform := range c.RequestTransformers {
+		if b, err = transform(req, b); err != nil {
+			return err
+		}
+	}
+
+	req.ContentLength = int64(len(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// transformResponseBody passes body, read from resp, through the client's
+// ResponseTransformers in order.
+func (c *Client) transformResponseBody(resp *http.Response, body []byte) ([]byte, error) {
+	var err error
+	for _, transform := range c.ResponseTransformers {
+		if body, err = transform(resp, body); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// RedactJSONFields returns a RequestTransformer removing the named top-level fields from
+// JSON object bodies. Other bodies are left unchanged.
+func RedactJSONFields(fields ...string) RequestTransformer {
+	return func(req *http.Request, body []byte) ([]byte, error) {
+		mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+			return body, nil
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(body, &object); err != nil {
+			return body, nil
+		}
+		for _, field := range fields {
+			delete(object, field)
+		}
+		return json.Marshal(object)
+	}
+}
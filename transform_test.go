@@ -0,0 +1,136 @@
+package rchttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-rchttp/rchttptest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientRequestTransformers(t *testing.T) {
+	Convey("Given an rchttp client with a chain of request transformers", t, func() {
+		ts := rchttptest.NewTestServer(200)
+		defer ts.Close()
+
+		downgrade := func(req *http.Request, body []byte) ([]byte, error) {
+			return bytes.Replace(body, []byte(`"title"`), []byte(`"name"`), 1), nil
+		}
+		httpClient := &Client{
+			HTTPClient:          &http.Client{},
+			RequestTransformers: []RequestTransformer{RedactJSONFields("password"), downgrade},
+		}
+
+		Convey("When a JSON body is posted", func() {
+			resp, err := httpClient.Post(context.Background(), ts.URL, "application/json", strings.NewReader(`{"password":"secret","title":"a"}`))
+			So(err, ShouldBeNil)
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then the transformed body is sent", func() {
+				So(call.Body, ShouldEqual, `{"name":"a"}`)
+				So(call.Headers["Content-Length"], ShouldResemble, []string{"12"})
+			})
+		})
+
+		Convey("When a body of another type is posted", func() {
+			resp, err := httpClient.Post(context.Background(), ts.URL, "text/plain", strings.NewReader(`{"password":"secret"}`))
+			So(err, ShouldBeNil)
+			call, err := unmarshallResp(resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is not redacted", func() {
+				So(call.Body, ShouldEqual, `{"password":"secret"}`)
+			})
+		})
+
+		Convey("When a streamed body is sent to a failing server", func() {
+			failing := rchttptest.NewTestServer(500)
+			defer failing.Close()
+			httpClient.MaxRetries = 1
+			req, _ := http.NewRequest("PUT", failing.URL, streamReader{strings.NewReader(`{"title":"a"}`)})
+			req.Header.Set("Content-Type", "text/plain")
+			resp, err := httpClient.Do(context.Background(), req)
+
+			Convey("Then the transformed body can be retried", func() {
+				So(err, ShouldBeNil)
+				call, err := unmarshallResp(resp)
+				So(err, ShouldBeNil)
+				So(call.Body, ShouldEqual, `{"name":"a"}`)
+				So(failing.GetCalls(0), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a transformer fails", func() {
+			failure := errors.New("cannot downgrade")
+			httpClient.RequestTransformers = append(httpClient.RequestTransformers, func(req *http.Request, body []byte) ([]byte, error) {
+				return nil, failure
+			})
+			_, err := httpClient.Post(context.Background(), ts.URL, "application/json", strings.NewReader(`{}`))
+
+			Convey("Then the request is not sent and the error is returned", func() {
+				So(err, ShouldEqual, failure)
+				So(ts.GetCalls(0), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestClientResponseTransformers(t *testing.T) {
+	Convey("Given an rchttp client with a response transformer upgrading an old payload shape", t, func() {
+		status := http.StatusOK
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"name":"a"}`))
+		}))
+		defer ts.Close()
+
+		upgrade := func(resp *http.Response, body []byte) ([]byte, error) {
+			return bytes.Replace(body, []byte(`"name"`), []byte(`"title"`), 1), nil
+		}
+		httpClient := &Client{HTTPClient: &http.Client{}, ResponseTransformers: []ResponseTransformer{upgrade}}
+
+		Convey("When a response is decoded by a helper", func() {
+			var v struct {
+				Title string `json:"title"`
+			}
+			_, err := httpClient.GetJSON(context.Background(), ts.URL, &v)
+
+			Convey("Then the transformed body is decoded", func() {
+				So(err, ShouldBeNil)
+				So(v.Title, ShouldEqual, "a")
+			})
+		})
+
+		Convey("When the response is not successful", func() {
+			status = http.StatusBadRequest
+			_, _, err := httpClient.GetBytes(context.Background(), ts.URL)
+
+			Convey("Then the error holds the body as received", func() {
+				var statusErr *StatusError
+				So(errors.As(err, &statusErr), ShouldBeTrue)
+				So(string(statusErr.Body), ShouldEqual, `{"name":"a"}`)
+			})
+		})
+
+		Convey("When a transformer fails", func() {
+			failure := errors.New("unknown payload version")
+			httpClient.ResponseTransformers = append(httpClient.ResponseTransformers, func(resp *http.Response, body []byte) ([]byte, error) {
+				return nil, failure
+			})
+			b, info, err := httpClient.GetBytes(context.Background(), ts.URL)
+
+			Convey("Then its error is returned with the call info", func() {
+				So(err, ShouldEqual, failure)
+				So(b, ShouldBeNil)
+				So(info.StatusCode, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+}
@@ -0,0 +1,118 @@
+package rchttp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newConnectProxy returns a proxy which tunnels CONNECT requests carrying the
+// X-Proxy-Token "secret", recording the targets it was asked for.
+func newConnectProxy() (*httptest.Server, func() []string) {
+	var mutex sync.Mutex
+	var targets []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		targets = append(targets, r.Host)
+		mutex.Unlock()
+		if r.Method != "CONNECT" || r.Header.Get("X-Proxy-Token") != "secret" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		go func() {
+			io.Copy(upstream, buf)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	return proxy, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), targets...)
+	}
+}
+
+func TestClientProxyTunnel(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("through the tunnel"))
+	}))
+	defer ts.Close()
+	proxy, targets := newConnectProxy()
+	defer proxy.Close()
+	target := ts.Listener.Addr().String()
+
+	Convey("Given an rchttp client sending requests through a CONNECT proxy", t, func() {
+		httpClient := &Client{HTTPClient: ts.Client()}
+		cfg := ProxyConfig{URL: proxy.URL, ConnectHeader: http.Header{"X-Proxy-Token": {"secret"}}}
+		So(httpClient.SetProxy(cfg), ShouldBeNil)
+
+		Convey("When an HTTPS request is made", func() {
+			body, _, err := httpClient.GetBytes(context.Background(), ts.URL)
+
+			Convey("Then it is tunnelled through the proxy with the CONNECT headers", func() {
+				So(err, ShouldBeNil)
+				So(string(body), ShouldEqual, "through the tunnel")
+				So(targets(), ShouldContain, target)
+			})
+		})
+
+		Convey("When the CONNECT headers come from GetConnectHeader", func() {
+			cfg.ConnectHeader = nil
+			cfg.GetConnectHeader = func(ctx context.Context, proxyURL *url.URL, target string) (http.Header, error) {
+				return http.Header{"X-Proxy-Token": {"secret"}}, nil
+			}
+			So(httpClient.SetProxy(cfg), ShouldBeNil)
+			body, _, err := httpClient.GetBytes(context.Background(), ts.URL)
+
+			Convey("Then they are sent on the CONNECT", func() {
+				So(err, ShouldBeNil)
+				So(string(body), ShouldEqual, "through the tunnel")
+			})
+		})
+
+		Convey("When a tunnel is dialled directly", func() {
+			conn, err := httpClient.DialTunnel(context.Background(), cfg, target)
+			So(err, ShouldBeNil)
+			defer conn.Close()
+
+			Convey("Then it connects to the target", func() {
+				tlsConfig := ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+				tlsConfig.ServerName = "example.com"
+				tlsConn := tls.Client(conn, tlsConfig)
+				req, _ := http.NewRequest("GET", ts.URL, nil)
+				So(req.Write(tlsConn), ShouldBeNil)
+				resp, err := http.ReadResponse(bufio.NewReader(tlsConn), req)
+				So(err, ShouldBeNil)
+				b, _ := io.ReadAll(resp.Body)
+				So(string(b), ShouldEqual, "through the tunnel")
+			})
+		})
+
+		Convey("When the proxy refuses the tunnel", func() {
+			cfg.ConnectHeader = nil
+			_, err := httpClient.DialTunnel(context.Background(), cfg, target)
+
+			Convey("Then a *ProxyConnectError is returned", func() {
+				So(err, ShouldHaveSameTypeAs, &ProxyConnectError{})
+				So(err.(*ProxyConnectError).StatusCode, ShouldEqual, http.StatusProxyAuthRequired)
+			})
+		})
+	})
+}
@@ -0,0 +1,48 @@
+package rchttp
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// PayloadTooLargeError is returned by Do when a PUT or POST was rejected with 413 Payload
+// Too Large and the client's ChunkedUploader then failed to send its body in parts.
+// Err holds the error from ChunkedUploader.
+type PayloadTooLargeError struct {
+	Method        string
+	URL           string
+	ContentLength int64
+	Err           error
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("rchttp: %s %s payload of %d bytes too large, and chunked upload failed: %v", e.Method, e.URL, e.ContentLength, e.Err)
+}
+
+// Unwrap returns the error from ChunkedUploader.
+func (e *PayloadTooLargeError) Unwrap() error {
+	return e.Err
+}
+
+// uploadInChunks hands req to the client's ChunkedUploader, with a fresh copy of its body,
+// if resp rejected it with 413 Payload Too Large, returning the uploader's response instead.
+func (c *Client) uploadInChunks(ctx context.Context, req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || resp.StatusCode != http.StatusRequestEntityTooLarge || (req.Method != "PUT" && req.Method != "POST") {
+		return resp, err
+	}
+	c.drainForRetry(resp)
+
+	upload := req.Clone(ctx)
+	if req.GetBody != nil {
+		if upload.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	resp, err = c.ChunkedUploader(ctx, upload)
+	if err != nil {
+		return resp, &PayloadTooLargeError{Method: req.Method, URL: req.URL.String(), ContentLength: req.ContentLength, Err: err}
+	}
+	return resp, nil
+}
@@ -0,0 +1,91 @@
+package rchttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientChunkedUploader(t *testing.T) {
+	var mutex sync.Mutex
+	var parts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if len(body) > 8 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		mutex.Lock()
+		parts = append(parts, string(body))
+		mutex.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	Convey("Given an rchttp client with a chunked uploader", t, func() {
+		parts = nil
+		httpClient := &Client{HTTPClient: &http.Client{}}
+		httpClient.ChunkedUploader = func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			var resp *http.Response
+			for len(body) > 0 {
+				n := 8
+				if len(body) < n {
+					n = len(body)
+				}
+				if resp, err = httpClient.Put(ctx, req.URL.String(), "text/plain", bytes.NewReader(body[:n])); err != nil {
+					return nil, err
+				}
+				resp.Body.Close()
+				body = body[n:]
+			}
+			return resp, nil
+		}
+
+		Convey("When a body too large for the server is sent", func() {
+			resp, err := httpClient.Put(context.Background(), ts.URL, "text/plain", strings.NewReader("observations"))
+
+			Convey("Then it is uploaded in parts instead", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+				So(parts, ShouldResemble, []string{"observat", "ions"})
+			})
+		})
+
+		Convey("When the chunked upload fails", func() {
+			uploadErr := errors.New("upload failed")
+			httpClient.ChunkedUploader = func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				return nil, uploadErr
+			}
+			_, err := httpClient.Post(context.Background(), ts.URL, "text/plain", strings.NewReader("observations"))
+
+			Convey("Then a PayloadTooLargeError is returned", func() {
+				var tooLarge *PayloadTooLargeError
+				So(errors.As(err, &tooLarge), ShouldBeTrue)
+				So(tooLarge.ContentLength, ShouldEqual, 12)
+				So(errors.Is(err, uploadErr), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a body the server accepts is sent", func() {
+			resp, err := httpClient.Put(context.Background(), ts.URL, "text/plain", strings.NewReader("small"))
+
+			Convey("Then it is sent whole", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+				So(parts, ShouldResemble, []string{"small"})
+			})
+		})
+	})
+}
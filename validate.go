@@ -0,0 +1,78 @@
+package rchttp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MaxSafeRetries is the most retries Validate allows, beyond which a failing downstream
+// is likely to be overwhelmed by the retries of its callers.
+const MaxSafeRetries = 20
+
+// ConfigError is returned by Validate, listing the problems found with a client's config.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "rchttp: invalid client config: " + strings.Join(e.Problems, "; ")
+}
+
+// NewClientWithConfig returns a copy of DefaultClient with the timeout and retry settings
+// in cfg, or a *ConfigError if they are dangerous (see Validate).
+func NewClientWithConfig(cfg Config) (*Client, error) {
+	c := *DefaultClient
+	httpClient := *DefaultClient.HTTPClient
+	httpClient.Timeout = cfg.Timeout
+	c.HTTPClient = &httpClient
+	c.MaxRetries = cfg.MaxRetries
+	c.RetryTime = cfg.RetryTime
+	c.PathsWithNoRetries = make(map[string]bool)
+	for _, path := range cfg.PathsWithNoRetries {
+		c.PathsWithNoRetries[path] = true
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Validate returns a *ConfigError if the client's config is likely to cause an incident:
+// retries with no timeout (so a hung attempt is never retried) or no backoff between them,
+// more than MaxSafeRetries retries, or buffering request bodies of any size for retries.
+func (c *Client) Validate() error {
+	if c.HTTPClient == nil {
+		return &ConfigError{Problems: []string{"HTTPClient is nil"}}
+	}
+
+	var problems []string
+	cfg := c.Config()
+	switch {
+	case cfg.MaxRetries < 0:
+		problems = append(problems, fmt.Sprintf("MaxRetries of %d is negative", cfg.MaxRetries))
+	case cfg.MaxRetries > MaxSafeRetries:
+		problems = append(problems, fmt.Sprintf("MaxRetries of %d is over %d", cfg.MaxRetries, MaxSafeRetries))
+	}
+	if cfg.MaxRetries > 0 {
+		if cfg.Timeout <= 0 && c.MaxElapsedTime <= 0 && !hasTimeout(c.HTTPClient.Transport) {
+			problems = append(problems, "retries are enabled with no timeout, so a hung attempt is never retried")
+		}
+		if cfg.RetryTime <= 0 && c.BackoffFunc == nil {
+			problems = append(problems, "retries are enabled with no RetryTime to back off by")
+		}
+		if c.BufferRequestBodies {
+			problems = append(problems, "BufferRequestBodies buffers request bodies of any size to retry them; use MaxBufferedBodySize instead")
+		}
+	}
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// hasTimeout reports whether transport times out waiting for responses.
+func hasTimeout(transport http.RoundTripper) bool {
+	t, ok := transport.(*http.Transport)
+	return ok && t.ResponseHeaderTimeout > 0
+}
@@ -0,0 +1,72 @@
+package rchttp
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientValidate(t *testing.T) {
+	Convey("Given the default client", t, func() {
+		Convey("Then its config is valid", func() {
+			So(DefaultClient.Validate(), ShouldBeNil)
+		})
+	})
+
+	Convey("Given a client with retries but no timeout", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{}, MaxRetries: 3, RetryTime: time.Second}
+
+		Convey("Then it is rejected", func() {
+			err := httpClient.Validate()
+			var configErr *ConfigError
+			So(errors.As(err, &configErr), ShouldBeTrue)
+			So(configErr.Problems, ShouldHaveLength, 1)
+			So(err.Error(), ShouldContainSubstring, "no timeout")
+		})
+
+		Convey("But not once the timeout is set", func() {
+			httpClient.SetTimeout(time.Second)
+			So(httpClient.Validate(), ShouldBeNil)
+		})
+	})
+
+	Convey("Given a client with too many retries and no backoff, buffering every body", t, func() {
+		httpClient := &Client{HTTPClient: &http.Client{Timeout: time.Second}, MaxRetries: 50, BufferRequestBodies: true}
+
+		Convey("Then each problem is reported", func() {
+			err := httpClient.Validate()
+			So(err, ShouldNotBeNil)
+			So(err.(*ConfigError).Problems, ShouldHaveLength, 3)
+			So(err.Error(), ShouldContainSubstring, "MaxRetries of 50 is over 20")
+		})
+	})
+
+	Convey("Given a config", t, func() {
+		cfg := Config{Timeout: time.Second, MaxRetries: 3, RetryTime: 10 * time.Millisecond, PathsWithNoRetries: []string{"/healthcheck"}}
+
+		Convey("When a client is made with it", func() {
+			httpClient, err := NewClientWithConfig(cfg)
+
+			Convey("Then the client has its settings", func() {
+				So(err, ShouldBeNil)
+				So(httpClient.HTTPClient.Timeout, ShouldEqual, time.Second)
+				So(httpClient.GetMaxRetries(), ShouldEqual, 3)
+				So(httpClient.GetPathsWithNoRetries(), ShouldResemble, []string{"/healthcheck"})
+				So(httpClient.HTTPClient != DefaultClient.HTTPClient, ShouldBeTrue)
+			})
+		})
+
+		Convey("When a client is made with it but no timeout", func() {
+			cfg.Timeout = 0
+			httpClient, err := NewClientWithConfig(cfg)
+
+			Convey("Then it is rejected", func() {
+				So(httpClient, ShouldBeNil)
+				So(err, ShouldHaveSameTypeAs, &ConfigError{})
+			})
+		})
+	})
+}